@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/hmaier-dev/checklist-tool/internal/backup"
+)
+
+const commandUsage = `commands:
+  backup <file>                                    write all templates and entries into <file>
+  restore [-mode=skip|overwrite|rename] <file>     restore <file> written by 'backup'`
+
+// Runs the command given after the flags, e.g. '-db=db.sqlite backup out.json.gz'
+func runCommand(ctx context.Context, db *sql.DB, args []string) error {
+	switch args[0] {
+	case "backup":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: backup <file>")
+		}
+		return backupCommand(ctx, db, args[1])
+	case "restore":
+		return restoreCommand(ctx, db, args[1:])
+	}
+	return fmt.Errorf("unknown command '%s'\n%s", args[0], commandUsage)
+}
+
+func backupCommand(ctx context.Context, db *sql.DB, name string) error {
+	archive, err := backup.Export(ctx, db)
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if err := backup.Write(f, archive); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %d templates to %s\n", len(archive.Templates), name)
+	return nil
+}
+
+func restoreCommand(ctx context.Context, db *sql.DB, args []string) error {
+	fs := flag.NewFlagSet("restore", flag.ExitOnError)
+	modeArg := fs.String("mode", string(backup.ModeSkip), "What happens with existing templates, entries and views: 'skip', 'overwrite' or 'rename'")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: restore [-mode=skip|overwrite|rename] <file>")
+	}
+	mode, err := backup.ParseMode(*modeArg)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	archive, err := backup.Read(f)
+	if err != nil {
+		return err
+	}
+	report, err := backup.Restore(ctx, db, archive, mode)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("templates: %s\nentries:   %s\n", counts(report.Templates), counts(report.Entries))
+	if len(report.Notes) > 0 {
+		fmt.Printf("notes:\n  %s\n", strings.Join(report.Notes, "\n  "))
+	}
+	return nil
+}
+
+func counts(c backup.Counts) string {
+	return fmt.Sprintf("%d created, %d skipped, %d overwritten, %d renamed", c.Created, c.Skipped, c.Overwritten, c.Renamed)
+}
@@ -0,0 +1,7 @@
+//go:build !sqlite_fts5
+
+package main
+
+// The search index needs sqlite with FTS5, which go-sqlite3 only builds with '-tags sqlite_fts5'.
+// Without the tag, the build stops here instead of producing a binary, which can't migrate its database.
+var _ = requires_build_tag_sqlite_fts5
@@ -0,0 +1,115 @@
+// Package archive keeps a copy of every generated pdf.
+// Each copy is recorded in the table pdf_exports with its SHA-256, the revision
+// of the entry it was built from, a hash of its content and who requested it,
+// so it can be proven later what was handed out.
+package archive
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hmaier-dev/checklist-tool/internal/database"
+)
+
+// Returned by Store.Get, when there is no file with the key
+var ErrNotFound = errors.New("file not found in the archive")
+
+// Returned by Archive.Load, when the stored file doesn't match its recorded checksum
+var ErrChecksum = errors.New("file in the archive doesn't match its checksum")
+
+// Holds the archived files. Keys look like '<path of the entry>/<date>_<checksum>.pdf'.
+type Store interface {
+	Put(ctx context.Context, key string, data []byte) error
+	Get(ctx context.Context, key string) ([]byte, error)
+}
+
+// Backends for the flag -archive-backend
+const (
+	BackendNone = "none"
+	BackendDir  = "dir"
+	BackendS3   = "s3"
+)
+
+// Returns the store for 'backend'. 'dir' and 's3' are the configured backends,
+// only the chosen one is used. BackendNone returns nil, which turns archiving off.
+func NewStore(backend string, dir *Dir, s3 *S3) (Store, error) {
+	switch backend {
+	case BackendNone:
+		return nil, nil
+	case BackendDir:
+		return dir, nil
+	case BackendS3:
+		if s3.Bucket == "" {
+			return nil, fmt.Errorf("the s3 archive needs a bucket")
+		}
+		return s3, nil
+	}
+	return nil, fmt.Errorf("unknown archive backend '%s', use '%s', '%s' or '%s'", backend, BackendNone, BackendDir, BackendS3)
+}
+
+// Records the pdfs in the database and keeps the files in Store
+type Archive struct {
+	Store Store
+	// Header with the name of the logged in user, set by an authenticating proxy.
+	// When empty or missing, the name sent by the page as ?user= is used.
+	UserHeader string
+}
+
+// Name of the user requesting a pdf
+func (a *Archive) User(r *http.Request) string {
+	if a.UserHeader != "" {
+		if user := strings.TrimSpace(r.Header.Get(a.UserHeader)); user != "" {
+			return user
+		}
+	}
+	return strings.TrimSpace(r.FormValue("user"))
+}
+
+// Stores the pdf of 'entry' and records it.
+// 'entry' has to be loaded before the pdf is rendered, so its revision matches the content.
+// 'content' is the hash of the rendered document (pdf.Document.ContentHash), it also changes
+// with comments and attachments, which don't bump the revision.
+func (a *Archive) Save(ctx context.Context, q *database.Queries, entry database.Entry, content, user, filename string, data []byte) (database.PdfExport, error) {
+	sum := sha256.Sum256(data)
+	checksum := hex.EncodeToString(sum[:])
+	now := time.Now()
+	key := fmt.Sprintf("%s/%s_%s.pdf", entry.Path, now.Format("20060102T150405"), checksum[:16])
+	if err := a.Store.Put(ctx, key, data); err != nil {
+		return database.PdfExport{}, fmt.Errorf("couldn't store '%s': %w", key, err)
+	}
+	export, err := q.InsertPdfExport(ctx, database.InsertPdfExportParams{
+		EntryID:       entry.ID,
+		EntryPath:     entry.Path,
+		Revision:      entry.Revision,
+		ContentSha256: content,
+		Filename:      filename,
+		StorageKey:    key,
+		Sha256:        checksum,
+		Size:          int64(len(data)),
+		RequestedBy:   user,
+		Date:          now.Unix(),
+	})
+	if err != nil {
+		return export, fmt.Errorf("couldn't record '%s': %w", key, err)
+	}
+	return export, nil
+}
+
+// Returns an archived pdf after checking it against its checksum
+func (a *Archive) Load(ctx context.Context, export database.PdfExport) ([]byte, error) {
+	data, err := a.Store.Get(ctx, export.StorageKey)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	if hex.EncodeToString(sum[:]) != export.Sha256 {
+		return nil, fmt.Errorf("%w: '%s'", ErrChecksum, export.StorageKey)
+	}
+	return data, nil
+}
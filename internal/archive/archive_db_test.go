@@ -0,0 +1,75 @@
+//go:build sqlite_fts5
+
+package archive
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hmaier-dev/checklist-tool/internal/database"
+	"github.com/hmaier-dev/checklist-tool/internal/database/dbtest"
+)
+
+func TestSaveAndLoad(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.Open(t)
+	_, err := db.Exec(`
+INSERT INTO templates (id, name) VALUES (1, 'geräte');
+INSERT INTO entries (id, template_id, path, revision) VALUES (1, 1, 'abc', 7);`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := database.New(db)
+	dir := &Dir{Path: t.TempDir()}
+	a := &Archive{Store: dir}
+
+	entry := database.Entry{ID: 1, Path: "abc", Revision: 7}
+	export, err := a.Save(ctx, q, entry, "c0ffee", "Anna", "Max.pdf", []byte("%PDF-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if export.Revision != 7 || export.ContentSha256 != "c0ffee" || export.RequestedBy != "Anna" || export.Size != 6 ||
+		export.Sha256 != "21af8e71c8703196df7fe1ff901869a88fe64c07bbaa83d838efb45a52b4f303" {
+		t.Errorf("unexpected record %+v", export)
+	}
+	exports, err := q.GetPdfExportsByEntryPath(ctx, "abc")
+	if err != nil || len(exports) != 1 || exports[0] != export {
+		t.Fatalf("expected the record in the database, got %+v (%v)", exports, err)
+	}
+	data, err := a.Load(ctx, export)
+	if err != nil || string(data) != "%PDF-1" {
+		t.Fatalf("expected the stored pdf, got %q (%v)", data, err)
+	}
+
+	// The record stays, when the entry is purged from the trash
+	if _, err := db.Exec("UPDATE entries SET deleted_at = 1 WHERE id = 1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.PurgeEntryByPath(ctx, "abc"); err != nil {
+		t.Fatal(err)
+	}
+	exports, err = q.GetPdfExportsByEntryPath(ctx, "abc")
+	if err != nil || len(exports) != 1 || exports[0] != export {
+		t.Fatalf("expected the record of the purged entry, got %+v (%v)", exports, err)
+	}
+	if _, err := q.GetPdfExportByID(ctx, export.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	// A changed file is noticed
+	if err := os.WriteFile(filepath.Join(dir.Path, export.StorageKey), []byte("%PDF-2"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.Load(ctx, export); !errors.Is(err, ErrChecksum) {
+		t.Errorf("expected ErrChecksum, got %v", err)
+	}
+	if _, err := dir.Get(ctx, "abc/missing.pdf"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if err := dir.Put(ctx, "../outside.pdf", nil); err == nil {
+		t.Errorf("expected keys outside of the directory to be rejected")
+	}
+}
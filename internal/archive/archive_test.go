@@ -0,0 +1,18 @@
+package archive
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestUser(t *testing.T) {
+	a := &Archive{UserHeader: "X-Forwarded-User"}
+	r := httptest.NewRequest("GET", "/checklist/print/abc?user=Anna", nil)
+	if got := a.User(r); got != "Anna" {
+		t.Errorf("expected the name sent by the page, got %q", got)
+	}
+	r.Header.Set("X-Forwarded-User", "anna.m")
+	if got := a.User(r); got != "anna.m" {
+		t.Errorf("expected the name from the proxy, got %q", got)
+	}
+}
@@ -0,0 +1,424 @@
+// Backup of the whole instance as one portable file.
+// The archive doesn't contain any ids of the database, templates are referenced by name,
+// fields by key, people by name and entries by path. That way it can be restored
+// into another database, which already contains data.
+package backup
+
+import (
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/hmaier-dev/checklist-tool/internal/database"
+	"github.com/hmaier-dev/checklist-tool/internal/handlers/checklist"
+)
+
+// Marks the file as backup of this tool
+const Format = "checklist-tool-backup"
+
+// Increased whenever the archive changes in a way older versions can't read
+const Version = 2
+
+// Written as gzip compressed json
+type Archive struct {
+	Format    string     `json:"format"`
+	Version   int        `json:"version"`
+	Created   int64      `json:"created"`
+	Templates []Template `json:"templates"`
+	People    []string   `json:"people"`
+	Views     []View     `json:"views"`
+	// Since version 2
+	PdfExports []PdfExport `json:"pdf_exports,omitempty"`
+}
+
+type Template struct {
+	Name      string `json:"name"`
+	EmptyYaml string `json:"empty_yaml"`
+	// The uploaded file including the frontmatter
+	File      string            `json:"file"`
+	DueIn     string            `json:"due_in,omitempty"`
+	DueField  string            `json:"due_field,omitempty"`
+	CaseField string            `json:"case_field,omitempty"`
+	Fields    []Field           `json:"fields"`
+	TabDesc   []string          `json:"tab_desc_schema"`
+	PdfName   []string          `json:"pdf_name_schema"`
+	Versions  []TemplateVersion `json:"versions,omitempty"`
+	Entries   []Entry           `json:"entries"`
+}
+
+type Field struct {
+	Key      string `json:"key"`
+	Desc     string `json:"desc"`
+	Position int64  `json:"position"`
+	// Set for fields removed from the frontmatter, which still have values
+	RemovedAt int64 `json:"removed_at,omitempty"`
+}
+
+// Earlier checklist of a template, kept for some entries
+type TemplateVersion struct {
+	// Only valid inside the archive, see Entry.Version
+	ID        int64  `json:"id"`
+	EmptyYaml string `json:"empty_yaml"`
+	Date      int64  `json:"date"`
+}
+
+type Entry struct {
+	Path      string `json:"path"`
+	Date      int64  `json:"date"`
+	DeletedAt int64  `json:"deleted_at,omitempty"`
+	Status    string `json:"status"`
+	Due       int64  `json:"due,omitempty"`
+	Assignee  string `json:"assignee,omitempty"`
+	// ID of one of Template.Versions, 0 follows the current checklist
+	Version         int64  `json:"version,omitempty"`
+	Revision        int64  `json:"revision"`
+	ProgressChecked int64  `json:"progress_checked"`
+	ProgressTotal   int64  `json:"progress_total"`
+	RequiredChecked int64  `json:"required_checked"`
+	RequiredTotal   int64  `json:"required_total"`
+	Answers         string `json:"answers,omitempty"`
+	// Values by the key of their field
+	Values      map[string]string `json:"values"`
+	States      []State           `json:"states,omitempty"`
+	Comments    []Comment         `json:"comments,omitempty"`
+	Attachments []Attachment      `json:"attachments,omitempty"`
+	Events      []Event           `json:"events,omitempty"`
+}
+
+type State struct {
+	Task            string  `json:"task"`
+	Checked         *bool   `json:"checked,omitempty"`
+	Text            *string `json:"text,omitempty"`
+	CheckedRevision int64   `json:"checked_revision,omitempty"`
+	TextRevision    int64   `json:"text_revision,omitempty"`
+}
+
+type Comment struct {
+	Author string `json:"author"`
+	Body   string `json:"body"`
+	Date   int64  `json:"date"`
+}
+
+type Attachment struct {
+	// Empty, when the file belongs to the entry itself
+	Task        string `json:"task,omitempty"`
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	Data        []byte `json:"data"`
+	Date        int64  `json:"date"`
+}
+
+type Event struct {
+	Kind    string `json:"kind"`
+	Message string `json:"message"`
+	Date    int64  `json:"date"`
+}
+
+type View struct {
+	Name  string `json:"name"`
+	Query string `json:"query"`
+}
+
+// Record of an archived pdf. The file itself stays in the archive storage,
+// only the record is part of the backup.
+type PdfExport struct {
+	// Path of the entry, which may have been purged since
+	Path          string `json:"path"`
+	Revision      int64  `json:"revision"`
+	ContentSha256 string `json:"content_sha256"`
+	Filename      string `json:"filename"`
+	StorageKey    string `json:"storage_key"`
+	Sha256        string `json:"sha256"`
+	Size          int64  `json:"size"`
+	RequestedBy   string `json:"requested_by"`
+	Date          int64  `json:"date"`
+}
+
+// Reads everything from the database, including the entries in the trash
+func Export(ctx context.Context, db *sql.DB) (*Archive, error) {
+	// A read transaction sees one state, even when someone works meanwhile
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	q := database.New(db).WithTx(tx)
+
+	archive := &Archive{Format: Format, Version: Version, Created: time.Now().Unix()}
+	people, err := q.GetAllPeople(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't load the people: %w", err)
+	}
+	names := make(map[int64]string)
+	for _, p := range people {
+		names[p.ID] = p.Name
+		archive.People = append(archive.People, p.Name)
+	}
+	views, err := q.GetAllSavedViews(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't load the saved views: %w", err)
+	}
+	for _, v := range views {
+		archive.Views = append(archive.Views, View{Name: v.Name, Query: v.Query})
+	}
+
+	templates, err := q.GetAllTemplates(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't load the templates: %w", err)
+	}
+	for _, t := range templates {
+		template, err := exportTemplate(ctx, q, t, names)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't export '%s': %w", t.Name, err)
+		}
+		archive.Templates = append(archive.Templates, template)
+	}
+	exports, err := q.GetAllPdfExports(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't load the archived pdfs: %w", err)
+	}
+	for _, e := range exports {
+		archive.PdfExports = append(archive.PdfExports, PdfExport{
+			Path:          e.EntryPath,
+			Revision:      e.Revision,
+			ContentSha256: e.ContentSha256,
+			Filename:      e.Filename,
+			StorageKey:    e.StorageKey,
+			Sha256:        e.Sha256,
+			Size:          e.Size,
+			RequestedBy:   e.RequestedBy,
+			Date:          e.Date,
+		})
+	}
+	return archive, nil
+}
+
+func exportTemplate(ctx context.Context, q *database.Queries, t database.Template, people map[int64]string) (Template, error) {
+	template := Template{
+		Name:      t.Name,
+		EmptyYaml: t.EmptyYaml.String,
+		File:      t.File.String,
+		DueIn:     t.DueIn.String,
+		DueField:  t.DueField.String,
+		CaseField: t.CaseField.String,
+		Fields:    []Field{},
+		TabDesc:   []string{},
+		PdfName:   []string{},
+		Entries:   []Entry{},
+	}
+	fields, err := q.GetCustomFieldsByTemplateID(ctx, t.ID)
+	if err != nil {
+		return template, err
+	}
+	for _, f := range fields {
+		template.Fields = append(template.Fields, Field{Key: f.Key, Desc: f.Desc, Position: f.Position, RemovedAt: f.RemovedAt.Int64})
+	}
+	tabDesc, err := q.GetTabDescriptionsByTemplateID(ctx, t.ID)
+	if err != nil {
+		return template, err
+	}
+	for _, d := range tabDesc {
+		template.TabDesc = append(template.TabDesc, d.Value)
+	}
+	pdfName, err := q.GetPdfNamingByTemplateID(ctx, t.ID)
+	if err != nil {
+		return template, err
+	}
+	for _, p := range pdfName {
+		template.PdfName = append(template.PdfName, p.Value)
+	}
+	versions, err := q.GetTemplateVersionsByTemplateID(ctx, t.ID)
+	if err != nil {
+		return template, err
+	}
+	for _, v := range versions {
+		template.Versions = append(template.Versions, TemplateVersion{ID: v.ID, EmptyYaml: v.EmptyYaml, Date: v.Date})
+	}
+
+	entries, err := q.GetEntriesByTemplateIDWithDeleted(ctx, t.ID)
+	if err != nil {
+		return template, err
+	}
+	for _, e := range entries {
+		entry, err := exportEntry(ctx, q, e, people)
+		if err != nil {
+			return template, fmt.Errorf("entry '%s': %w", e.Path, err)
+		}
+		template.Entries = append(template.Entries, entry)
+	}
+	return template, nil
+}
+
+func exportEntry(ctx context.Context, q *database.Queries, e database.Entry, people map[int64]string) (Entry, error) {
+	entry := Entry{
+		Path:            e.Path,
+		Date:            e.Date.Int64,
+		DeletedAt:       e.DeletedAt.Int64,
+		Status:          e.Status,
+		Due:             e.Due.Int64,
+		Assignee:        people[e.AssigneeID.Int64],
+		Version:         e.TemplateVersionID.Int64,
+		Revision:        e.Revision,
+		ProgressChecked: e.ProgressChecked,
+		ProgressTotal:   e.ProgressTotal,
+		RequiredChecked: e.RequiredChecked,
+		RequiredTotal:   e.RequiredTotal,
+		Answers:         e.Answers.String,
+		Values:          make(map[string]string),
+	}
+	values, err := q.GetAllEntryValuesByEntryID(ctx, e.ID)
+	if err != nil {
+		return entry, err
+	}
+	for _, v := range values {
+		entry.Values[v.Key] = v.Value
+	}
+	states, err := q.GetItemStatesByEntryID(ctx, e.ID)
+	if err != nil {
+		return entry, err
+	}
+	for _, s := range states {
+		state := State{Task: s.Task, CheckedRevision: s.CheckedRevision, TextRevision: s.TextRevision}
+		if s.Checked.Valid {
+			state.Checked = &s.Checked.Bool
+		}
+		if s.Text.Valid {
+			state.Text = &s.Text.String
+		}
+		entry.States = append(entry.States, state)
+	}
+	comments, err := q.GetCommentsByEntryID(ctx, e.ID)
+	if err != nil {
+		return entry, err
+	}
+	for _, c := range comments {
+		entry.Comments = append(entry.Comments, Comment{Author: c.Author, Body: c.Body, Date: c.Date})
+	}
+	attachments, err := q.GetAttachmentsWithDataByEntryID(ctx, e.ID)
+	if err != nil {
+		return entry, err
+	}
+	for _, a := range attachments {
+		entry.Attachments = append(entry.Attachments, Attachment{
+			Task:        a.Task.String,
+			Filename:    a.Filename,
+			ContentType: a.ContentType,
+			Data:        a.Data,
+			Date:        a.Date,
+		})
+	}
+	events, err := q.GetEntryEventsByEntryID(ctx, e.ID)
+	if err != nil {
+		return entry, err
+	}
+	for _, ev := range events {
+		entry.Events = append(entry.Events, Event{Kind: ev.Kind, Message: ev.Message, Date: ev.Date})
+	}
+	return entry, nil
+}
+
+func Write(w io.Writer, archive *Archive) error {
+	zw := gzip.NewWriter(w)
+	if err := json.NewEncoder(zw).Encode(archive); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// Reads and validates an archive written by Write()
+func Read(r io.Reader) (*Archive, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: not a backup of the checklist-tool: %w", ErrInvalid, err)
+	}
+	defer zr.Close()
+	var archive Archive
+	if err := json.NewDecoder(zr).Decode(&archive); err != nil {
+		return nil, fmt.Errorf("%w: not a backup of the checklist-tool: %w", ErrInvalid, err)
+	}
+	if err := archive.Validate(); err != nil {
+		return nil, err
+	}
+	return &archive, nil
+}
+
+// Returned by Validate for archives, which can't be restored
+var ErrInvalid = errors.New("invalid backup")
+
+// Checks that everything referenced inside the archive exists,
+// so a restore doesn't fail halfway because of the archive itself.
+func (a *Archive) Validate() error {
+	if a.Format != Format {
+		return fmt.Errorf("%w: not a backup of the checklist-tool", ErrInvalid)
+	}
+	if a.Version < 1 || a.Version > Version {
+		return fmt.Errorf("%w: version %d can't be read, only up to %d", ErrInvalid, a.Version, Version)
+	}
+	people := make(map[string]bool)
+	for _, p := range a.People {
+		people[p] = true
+	}
+	templates := make(map[string]bool)
+	paths := make(map[string]bool)
+	for _, t := range a.Templates {
+		if t.Name == "" {
+			return fmt.Errorf("%w: template without name", ErrInvalid)
+		}
+		if templates[t.Name] {
+			return fmt.Errorf("%w: template '%s' appears twice", ErrInvalid, t.Name)
+		}
+		templates[t.Name] = true
+		if err := validYaml(t.EmptyYaml); err != nil {
+			return fmt.Errorf("%w: checklist of '%s': %v", ErrInvalid, t.Name, err)
+		}
+		keys := make(map[string]bool)
+		for _, f := range t.Fields {
+			if f.Key == "" || keys[f.Key] {
+				return fmt.Errorf("%w: template '%s' has an empty or duplicate field '%s'", ErrInvalid, t.Name, f.Key)
+			}
+			keys[f.Key] = true
+		}
+		versions := make(map[int64]bool)
+		for _, v := range t.Versions {
+			if err := validYaml(v.EmptyYaml); err != nil {
+				return fmt.Errorf("%w: version %d of '%s': %v", ErrInvalid, v.ID, t.Name, err)
+			}
+			versions[v.ID] = true
+		}
+		for _, e := range t.Entries {
+			if e.Path == "" || paths[e.Path] {
+				return fmt.Errorf("%w: entry of '%s' with an empty or duplicate path '%s'", ErrInvalid, t.Name, e.Path)
+			}
+			paths[e.Path] = true
+			if e.Version != 0 && !versions[e.Version] {
+				return fmt.Errorf("%w: entry '%s' refers to the unknown version %d", ErrInvalid, e.Path, e.Version)
+			}
+			if e.Assignee != "" && !people[e.Assignee] {
+				return fmt.Errorf("%w: entry '%s' is assigned to the unknown person '%s'", ErrInvalid, e.Path, e.Assignee)
+			}
+			for key := range e.Values {
+				if !keys[key] {
+					return fmt.Errorf("%w: entry '%s' has a value for the unknown field '%s'", ErrInvalid, e.Path, key)
+				}
+			}
+		}
+	}
+	for _, e := range a.PdfExports {
+		if e.Path == "" || e.StorageKey == "" {
+			return fmt.Errorf("%w: archived pdf '%s' without path or storage key", ErrInvalid, e.Filename)
+		}
+	}
+	return nil
+}
+
+func validYaml(s string) error {
+	var items []*checklist.Item
+	return yaml.Unmarshal([]byte(s), &items)
+}
@@ -0,0 +1,161 @@
+//go:build sqlite_fts5
+
+package backup
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/hmaier-dev/checklist-tool/internal/database/dbtest"
+)
+
+func testArchive() *Archive {
+	checked := true
+	text := "IMEI 123"
+	return &Archive{
+		Format:  Format,
+		Version: Version,
+		People:  []string{"Anna"},
+		Views:   []View{{Name: "Offen", Query: "open=true"}},
+		Templates: []Template{{
+			Name:      "Gerät",
+			EmptyYaml: "- task: \"A\"\n  checked: false\n- task: \"B\"\n  checked: false\n  text: \"\"\n",
+			File:      "---\nname: Gerät\n---\n",
+			DueIn:     "3d",
+			Fields: []Field{
+				{Key: "name", Desc: "Name", Position: 0},
+				{Key: "old", Desc: "Alt", Position: 1, RemovedAt: 1700000000},
+			},
+			TabDesc:  []string{"name"},
+			PdfName:  []string{"date", "name"},
+			Versions: []TemplateVersion{{ID: 7, EmptyYaml: "- task: \"A\"\n  checked: false\n", Date: 1600000000}},
+			Entries: []Entry{
+				{
+					Path: "p1", Date: 1700000000, Status: "open", Assignee: "Anna", Revision: 3,
+					ProgressChecked: 1, ProgressTotal: 2, Answers: "IMEI 123",
+					Values:      map[string]string{"name": "Max", "old": "x"},
+					States:      []State{{Task: "A", Checked: &checked, CheckedRevision: 1}, {Task: "B", Text: &text, TextRevision: 3}},
+					Comments:    []Comment{{Author: "Anna", Body: "**fertig**", Date: 1700000100}},
+					Attachments: []Attachment{{Task: "A", Filename: "a.pdf", ContentType: "application/pdf", Data: []byte("%PDF"), Date: 1700000200}},
+					Events:      []Event{{Kind: "assignee", Message: "Anna", Date: 1700000300}},
+				},
+				{Path: "p2", Date: 1700000000, DeletedAt: 1700000500, Status: "done", Version: 7, Values: map[string]string{}},
+			},
+		}},
+		PdfExports: []PdfExport{
+			{
+				Path: "p1", Revision: 3, ContentSha256: "c0ffee", Filename: "Max.pdf", StorageKey: "p1/20231114T221320_21af8e71c8703196.pdf",
+				Sha256: "21af8e71c8703196df7fe1ff901869a88fe64c07bbaa83d838efb45a52b4f303", Size: 6, RequestedBy: "Anna", Date: 1700000000,
+			},
+			// Of an entry purged before the backup
+			{Path: "p0", Revision: 1, ContentSha256: "beef", Filename: "Moritz.pdf", StorageKey: "p0/20231114T221320_0123456789abcdef.pdf", Sha256: "0123", Size: 4, Date: 1700000000},
+		},
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.Open(t)
+	archive := testArchive()
+	report, err := Restore(ctx, db, archive, ModeSkip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Templates.Created != 1 || report.Entries.Created != 2 {
+		t.Errorf("unexpected report %+v", report)
+	}
+
+	exported, err := Export(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := Write(&buf, exported); err != nil {
+		t.Fatal(err)
+	}
+	read, err := Read(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Ids of versions are only valid inside an archive
+	archive.Templates[0].Versions[0].ID = 1
+	archive.Templates[0].Entries[1].Version = 1
+	read.Created = 0
+	if !reflect.DeepEqual(read, archive) {
+		t.Errorf("expected the restored archive\n%+v\ngot\n%+v", archive, read)
+	}
+}
+
+func TestRestoreModes(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.Open(t)
+	if _, err := Restore(ctx, db, testArchive(), ModeSkip); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := Restore(ctx, db, testArchive(), ModeSkip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Templates.Skipped != 1 || report.Entries.Skipped != 2 {
+		t.Errorf("expected everything to be skipped, got %+v", report)
+	}
+
+	changed := testArchive()
+	changed.Templates[0].Entries[0].Values["name"] = "Moritz"
+	report, err = Restore(ctx, db, changed, ModeOverwrite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Templates.Overwritten != 1 || report.Entries.Overwritten != 2 {
+		t.Errorf("expected everything to be overwritten, got %+v", report)
+	}
+	var value string
+	db.QueryRow("SELECT value FROM entry_values ev JOIN entries e ON e.id = ev.entry_id JOIN custom_fields cf ON cf.id = ev.field_id WHERE e.path = 'p1' AND cf.key = 'name'").Scan(&value)
+	if value != "Moritz" {
+		t.Errorf("expected the overwritten value, got %q", value)
+	}
+
+	report, err = Restore(ctx, db, testArchive(), ModeRename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Templates.Renamed != 1 || report.Entries.Renamed != 2 {
+		t.Errorf("expected everything to be renamed, got %+v", report)
+	}
+	var templates, entries, people int
+	db.QueryRow("SELECT COUNT(*) FROM templates WHERE name = 'Gerät (2)'").Scan(&templates)
+	db.QueryRow("SELECT COUNT(*) FROM entries").Scan(&entries)
+	db.QueryRow("SELECT COUNT(*) FROM people").Scan(&people)
+	if templates != 1 || entries != 4 || people != 1 {
+		t.Errorf("expected a renamed template, 4 entries and 1 person, got %d, %d, %d", templates, entries, people)
+	}
+	// The records of archived pdfs are kept through all modes and added only once
+	var exports int
+	db.QueryRow("SELECT COUNT(*) FROM pdf_exports WHERE entry_path IN ('p0', 'p1')").Scan(&exports)
+	if exports != 2 {
+		t.Errorf("expected the 2 archived pdfs once, got %d", exports)
+	}
+}
+
+func TestRestoreInvalid(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.Open(t)
+	archive := testArchive()
+	archive.Templates[0].Entries[1].Version = 99
+	if _, err := Restore(ctx, db, archive, ModeSkip); !errors.Is(err, ErrInvalid) {
+		t.Errorf("expected ErrInvalid, got %v", err)
+	}
+	// The archive is checked before anything is written
+	var n int
+	db.QueryRow("SELECT COUNT(*) FROM people").Scan(&n)
+	if n != 0 {
+		t.Errorf("expected nothing to be restored")
+	}
+	if _, err := Read(bytes.NewReader([]byte("not gzip"))); err == nil {
+		t.Errorf("expected an error for a file, which isn't a backup")
+	}
+}
@@ -0,0 +1,574 @@
+package backup
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/btcsuite/btcutil/base58"
+
+	"github.com/hmaier-dev/checklist-tool/internal/database"
+)
+
+// What happens with templates, entries and views, which already exist
+type Mode string
+
+const (
+	// Keeps the existing one, entries of an existing template are still added
+	ModeSkip Mode = "skip"
+	// Replaces the existing one with the one from the archive
+	ModeOverwrite Mode = "overwrite"
+	// Adds the one from the archive under a new name or path
+	ModeRename Mode = "rename"
+)
+
+var Modes = []Mode{ModeSkip, ModeOverwrite, ModeRename}
+
+func ParseMode(s string) (Mode, error) {
+	for _, m := range Modes {
+		if string(m) == s {
+			return m, nil
+		}
+	}
+	return "", fmt.Errorf("unknown mode '%s', use 'skip', 'overwrite' or 'rename'", s)
+}
+
+// Outcome of Restore, counted per kind
+type Report struct {
+	Templates Counts
+	Entries   Counts
+	// Everything, which didn't end up as in the archive, e.g. renamed templates
+	Notes []string
+}
+
+type Counts struct {
+	Created     int
+	Skipped     int
+	Overwritten int
+	Renamed     int
+}
+
+type restorer struct {
+	ctx    context.Context
+	q      *database.Queries
+	mode   Mode
+	now    int64
+	people map[string]int64
+	// New paths of renamed entries by their path in the archive
+	paths  map[string]string
+	report Report
+}
+
+// Writes the archive into the database in one transaction.
+// Nothing is changed, when an error is returned.
+func Restore(ctx context.Context, db *sql.DB, archive *Archive, mode Mode) (Report, error) {
+	if err := archive.Validate(); err != nil {
+		return Report{}, err
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return Report{}, err
+	}
+	defer tx.Rollback()
+	r := &restorer{
+		ctx:    ctx,
+		q:      database.New(db).WithTx(tx),
+		mode:   mode,
+		now:    time.Now().Unix(),
+		people: make(map[string]int64),
+		paths:  make(map[string]string),
+	}
+	if err := r.restorePeople(archive.People); err != nil {
+		return Report{}, fmt.Errorf("couldn't restore the people: %w", err)
+	}
+	if err := r.restoreViews(archive.Views); err != nil {
+		return Report{}, fmt.Errorf("couldn't restore the saved views: %w", err)
+	}
+	for _, t := range archive.Templates {
+		if err := r.restoreTemplate(t); err != nil {
+			return Report{}, fmt.Errorf("couldn't restore '%s': %w", t.Name, err)
+		}
+	}
+	if err := r.restorePdfExports(archive.PdfExports); err != nil {
+		return Report{}, fmt.Errorf("couldn't restore the archived pdfs: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return Report{}, err
+	}
+	return r.report, nil
+}
+
+// People are matched by name, missing ones are added
+func (r *restorer) restorePeople(names []string) error {
+	existing, err := r.q.GetAllPeople(r.ctx)
+	if err != nil {
+		return err
+	}
+	for _, p := range existing {
+		r.people[p.Name] = p.ID
+	}
+	for _, name := range names {
+		if _, ok := r.people[name]; ok {
+			continue
+		}
+		if err := r.q.InsertPerson(r.ctx, name); err != nil {
+			return err
+		}
+	}
+	all, err := r.q.GetAllPeople(r.ctx)
+	if err != nil {
+		return err
+	}
+	for _, p := range all {
+		r.people[p.Name] = p.ID
+	}
+	return nil
+}
+
+func (r *restorer) restoreViews(views []View) error {
+	saved, err := r.q.GetAllSavedViews(r.ctx)
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]string)
+	for _, v := range saved {
+		existing[v.Name] = v.Query
+	}
+	for _, v := range views {
+		name := v.Name
+		if query, ok := existing[name]; ok && query != v.Query {
+			switch r.mode {
+			case ModeSkip:
+				continue
+			case ModeRename:
+				name = freeName(name, func(n string) bool { _, ok := existing[n]; return ok })
+			}
+		}
+		if err := r.q.SaveView(r.ctx, database.SaveViewParams{Name: name, Query: v.Query}); err != nil {
+			return err
+		}
+		existing[name] = v.Query
+	}
+	return nil
+}
+
+func (r *restorer) restoreTemplate(t Template) error {
+	existing, err := r.q.GetTemplateByName(r.ctx, t.Name)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	exists := err == nil
+	var templateID int64
+	// Checklist the entries of the archive were made for, when it differs from the one in the database
+	frozen := false
+	switch {
+	case !exists:
+		templateID, err = r.insertTemplate(t, t.Name)
+		r.report.Templates.Created++
+	case r.mode == ModeOverwrite:
+		templateID = existing.ID
+		err = r.overwriteTemplate(existing.ID, t)
+		r.report.Templates.Overwritten++
+	case r.mode == ModeRename:
+		name, nameErr := r.freeTemplateName(t.Name)
+		if nameErr != nil {
+			return nameErr
+		}
+		templateID, err = r.insertTemplate(t, name)
+		r.report.Templates.Renamed++
+		r.report.Notes = append(r.report.Notes, fmt.Sprintf("Vorlage '%s' wurde als '%s' wiederhergestellt.", t.Name, name))
+	default:
+		templateID = existing.ID
+		frozen = existing.EmptyYaml.String != t.EmptyYaml
+		err = r.addMissingFields(existing.ID, t)
+		r.report.Templates.Skipped++
+	}
+	if err != nil {
+		return err
+	}
+
+	fields, err := r.q.GetCustomFieldsByTemplateID(r.ctx, templateID)
+	if err != nil {
+		return err
+	}
+	fieldIDs := make(map[string]int64)
+	for _, f := range fields {
+		fieldIDs[f.Key] = f.ID
+	}
+	// Versions are only created for the entries, which use them
+	versions := make(map[int64]int64)
+	version := func(archiveID int64, emptyYaml string, date int64) (sql.NullInt64, error) {
+		if id, ok := versions[archiveID]; ok {
+			return sql.NullInt64{Valid: true, Int64: id}, nil
+		}
+		id, err := r.q.InsertTemplateVersion(r.ctx, database.InsertTemplateVersionParams{
+			TemplateID: templateID,
+			EmptyYaml:  emptyYaml,
+			Date:       date,
+		})
+		versions[archiveID] = id
+		return sql.NullInt64{Valid: true, Int64: id}, err
+	}
+	byID := make(map[int64]TemplateVersion)
+	for _, v := range t.Versions {
+		byID[v.ID] = v
+	}
+	for _, e := range t.Entries {
+		var versionID sql.NullInt64
+		switch {
+		case e.Version != 0:
+			v := byID[e.Version]
+			versionID, err = version(v.ID, v.EmptyYaml, v.Date)
+		case frozen:
+			// The existing template was kept, the entry keeps the checklist from the archive.
+			// 0 is never the id of a version in the archive.
+			versionID, err = version(0, t.EmptyYaml, r.now)
+		}
+		if err != nil {
+			return err
+		}
+		if err := r.restoreEntry(templateID, versionID, fieldIDs, e); err != nil {
+			return fmt.Errorf("entry '%s': %w", e.Path, err)
+		}
+	}
+	return nil
+}
+
+func (r *restorer) insertTemplate(t Template, name string) (int64, error) {
+	id, err := r.q.InsertNewChecklistTemplate(r.ctx, database.InsertNewChecklistTemplateParams{
+		Name:      name,
+		EmptyYaml: sql.NullString{Valid: true, String: t.EmptyYaml},
+		File:      sql.NullString{Valid: true, String: t.File},
+		DueIn:     nullString(t.DueIn),
+		DueField:  nullString(t.DueField),
+		CaseField: nullString(t.CaseField),
+	})
+	if err != nil {
+		return 0, err
+	}
+	for _, f := range t.Fields {
+		_, err := r.q.RestoreCustomField(r.ctx, database.RestoreCustomFieldParams{
+			TemplateID: id,
+			Key:        f.Key,
+			Desc:       f.Desc,
+			Position:   f.Position,
+			RemovedAt:  nullInt64(f.RemovedAt),
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+	return id, r.insertSchemas(id, t)
+}
+
+func (r *restorer) insertSchemas(id int64, t Template) error {
+	for _, v := range t.TabDesc {
+		if err := r.q.InsertTabDescSchema(r.ctx, database.InsertTabDescSchemaParams{TemplateID: id, Value: v}); err != nil {
+			return err
+		}
+	}
+	for _, v := range t.PdfName {
+		if err := r.q.InsertPdfNameSchema(r.ctx, database.InsertPdfNameSchemaParams{TemplateID: id, Value: v}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Same as updating the template with the file of the archive.
+// Fields missing in the archive are hidden, but their values are kept.
+func (r *restorer) overwriteTemplate(id int64, t Template) error {
+	err := r.q.UpdateTemplateById(r.ctx, database.UpdateTemplateByIdParams{
+		EmptyYaml: sql.NullString{Valid: true, String: t.EmptyYaml},
+		File:      sql.NullString{Valid: true, String: t.File},
+		DueIn:     nullString(t.DueIn),
+		DueField:  nullString(t.DueField),
+		CaseField: nullString(t.CaseField),
+		ID:        id,
+	})
+	if err != nil {
+		return err
+	}
+	if err := r.q.DeleteTabDescSchemaByTemplateID(r.ctx, id); err != nil {
+		return err
+	}
+	if err := r.q.DeletePdfNameSchemaByTemplateID(r.ctx, id); err != nil {
+		return err
+	}
+	if err := r.insertSchemas(id, t); err != nil {
+		return err
+	}
+	fields, err := r.q.GetCustomFieldsByTemplateID(r.ctx, id)
+	if err != nil {
+		return err
+	}
+	archived := make(map[string]Field)
+	for _, f := range t.Fields {
+		archived[f.Key] = f
+	}
+	for _, f := range fields {
+		a, ok := archived[f.Key]
+		if !ok {
+			err = r.q.RemoveCustomFieldByID(r.ctx, database.RemoveCustomFieldByIDParams{
+				RemovedAt: sql.NullInt64{Valid: true, Int64: r.now},
+				ID:        f.ID,
+			})
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		delete(archived, f.Key)
+		err = r.q.UpdateCustomFieldByID(r.ctx, database.UpdateCustomFieldByIDParams{
+			Key:      a.Key,
+			Desc:     a.Desc,
+			Position: a.Position,
+			ID:       f.ID,
+		})
+		if err == nil && a.RemovedAt != 0 {
+			err = r.q.RemoveCustomFieldByID(r.ctx, database.RemoveCustomFieldByIDParams{
+				RemovedAt: sql.NullInt64{Valid: true, Int64: a.RemovedAt},
+				ID:        f.ID,
+			})
+		}
+		if err != nil {
+			return err
+		}
+	}
+	var missing []Field
+	for _, f := range t.Fields {
+		if _, ok := archived[f.Key]; ok {
+			missing = append(missing, f)
+		}
+	}
+	return r.insertFields(id, missing, false)
+}
+
+// The existing template is kept. Fields only known to the archive are added hidden,
+// so the values of the restored entries aren't lost.
+func (r *restorer) addMissingFields(id int64, t Template) error {
+	fields, err := r.q.GetCustomFieldsByTemplateID(r.ctx, id)
+	if err != nil {
+		return err
+	}
+	known := make(map[string]bool)
+	for _, f := range fields {
+		known[f.Key] = true
+	}
+	var missing []Field
+	for _, f := range t.Fields {
+		if !known[f.Key] {
+			missing = append(missing, f)
+		}
+	}
+	if len(missing) > 0 {
+		r.report.Notes = append(r.report.Notes, fmt.Sprintf("Vorlage '%s' hat %d ausgeblendete Felder aus der Sicherung erhalten.", t.Name, len(missing)))
+	}
+	return r.insertFields(id, missing, true)
+}
+
+func (r *restorer) insertFields(id int64, fields []Field, hidden bool) error {
+	for _, f := range fields {
+		removed := nullInt64(f.RemovedAt)
+		if hidden && !removed.Valid {
+			removed = sql.NullInt64{Valid: true, Int64: r.now}
+		}
+		_, err := r.q.RestoreCustomField(r.ctx, database.RestoreCustomFieldParams{
+			TemplateID: id,
+			Key:        f.Key,
+			Desc:       f.Desc,
+			Position:   f.Position,
+			RemovedAt:  removed,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *restorer) restoreEntry(templateID int64, versionID sql.NullInt64, fieldIDs map[string]int64, e Entry) error {
+	path := e.Path
+	n, err := r.q.CountEntriesByPath(r.ctx, path)
+	if err != nil {
+		return err
+	}
+	switch {
+	case n == 0:
+		r.report.Entries.Created++
+	case r.mode == ModeSkip:
+		r.report.Entries.Skipped++
+		return nil
+	case r.mode == ModeOverwrite:
+		if err := r.q.DeleteEntryByPath(r.ctx, path); err != nil {
+			return err
+		}
+		r.report.Entries.Overwritten++
+	case r.mode == ModeRename:
+		path, err = r.freePath(path)
+		if err != nil {
+			return err
+		}
+		r.paths[e.Path] = path
+		r.report.Entries.Renamed++
+		r.report.Notes = append(r.report.Notes, fmt.Sprintf("Eintrag '%s' wurde unter '/checklist/%s' wiederhergestellt.", e.Path, path))
+	}
+
+	var assignee sql.NullInt64
+	if e.Assignee != "" {
+		assignee = sql.NullInt64{Valid: true, Int64: r.people[e.Assignee]}
+	}
+	id, err := r.q.RestoreEntry(r.ctx, database.RestoreEntryParams{
+		TemplateID:        templateID,
+		Path:              path,
+		Date:              nullInt64(e.Date),
+		DeletedAt:         nullInt64(e.DeletedAt),
+		Status:            e.Status,
+		Due:               nullInt64(e.Due),
+		AssigneeID:        assignee,
+		ProgressChecked:   e.ProgressChecked,
+		ProgressTotal:     e.ProgressTotal,
+		RequiredChecked:   e.RequiredChecked,
+		RequiredTotal:     e.RequiredTotal,
+		Answers:           sql.NullString{Valid: true, String: e.Answers},
+		Revision:          e.Revision,
+		TemplateVersionID: versionID,
+	})
+	if err != nil {
+		return err
+	}
+	for key, value := range e.Values {
+		err := r.q.SetEntryValue(r.ctx, database.SetEntryValueParams{EntryID: id, FieldID: fieldIDs[key], Value: value})
+		if err != nil {
+			return err
+		}
+	}
+	for _, s := range e.States {
+		arg := database.RestoreItemStateParams{
+			EntryID:         id,
+			Task:            s.Task,
+			CheckedRevision: s.CheckedRevision,
+			TextRevision:    s.TextRevision,
+		}
+		if s.Checked != nil {
+			arg.Checked = sql.NullBool{Valid: true, Bool: *s.Checked}
+		}
+		if s.Text != nil {
+			arg.Text = sql.NullString{Valid: true, String: *s.Text}
+		}
+		if err := r.q.RestoreItemState(r.ctx, arg); err != nil {
+			return err
+		}
+	}
+	for _, c := range e.Comments {
+		err := r.q.InsertComment(r.ctx, database.InsertCommentParams{EntryID: id, Author: c.Author, Body: c.Body, Date: c.Date})
+		if err != nil {
+			return err
+		}
+	}
+	for _, a := range e.Attachments {
+		err := r.q.InsertAttachment(r.ctx, database.InsertAttachmentParams{
+			EntryID:     id,
+			Task:        nullString(a.Task),
+			Filename:    a.Filename,
+			ContentType: a.ContentType,
+			Size:        int64(len(a.Data)),
+			Data:        a.Data,
+			Date:        a.Date,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	for _, ev := range e.Events {
+		err := r.q.InsertEntryEvent(r.ctx, database.InsertEntryEventParams{EntryID: id, Kind: ev.Kind, Message: ev.Message, Date: ev.Date})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Records of archived pdfs are never overwritten, they are matched by their file
+// in the archive storage and only missing ones are added.
+// They follow a renamed entry and keep the path of a purged one.
+func (r *restorer) restorePdfExports(exports []PdfExport) error {
+	for _, e := range exports {
+		n, err := r.q.CountPdfExportsByStorageKey(r.ctx, e.StorageKey)
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			continue
+		}
+		path := e.Path
+		if renamed, ok := r.paths[path]; ok {
+			path = renamed
+		}
+		// 0, when the entry was purged before the backup
+		entryID, err := r.q.GetEntryIDByPath(r.ctx, path)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+		_, err = r.q.InsertPdfExport(r.ctx, database.InsertPdfExportParams{
+			EntryID:       entryID,
+			EntryPath:     path,
+			Revision:      e.Revision,
+			ContentSha256: e.ContentSha256,
+			Filename:      e.Filename,
+			StorageKey:    e.StorageKey,
+			Sha256:        e.Sha256,
+			Size:          e.Size,
+			RequestedBy:   e.RequestedBy,
+			Date:          e.Date,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *restorer) freeTemplateName(name string) (string, error) {
+	var err error
+	free := freeName(name, func(n string) bool {
+		_, e := r.q.GetTemplateByName(r.ctx, n)
+		if e != nil && e != sql.ErrNoRows {
+			err = e
+		}
+		return e == nil
+	})
+	return free, err
+}
+
+// New path derived from the old one, like the paths of new entries
+func (r *restorer) freePath(path string) (string, error) {
+	for i := 1; ; i++ {
+		sum := sha256.Sum256([]byte(fmt.Sprintf("%s-%d", path, i)))
+		candidate := base58.Encode(sum[:])
+		n, err := r.q.CountEntriesByPath(r.ctx, candidate)
+		if err != nil || n == 0 {
+			return candidate, err
+		}
+	}
+}
+
+// Appends " (2)", " (3)", … until 'taken' returns false
+func freeName(name string, taken func(string) bool) string {
+	for i := 2; ; i++ {
+		candidate := fmt.Sprintf("%s (%d)", name, i)
+		if !taken(candidate) {
+			return candidate
+		}
+	}
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{Valid: s != "", String: s}
+}
+
+func nullInt64(i int64) sql.NullInt64 {
+	return sql.NullInt64{Valid: i != 0, Int64: i}
+}
@@ -0,0 +1,64 @@
+// Test databases created from the real schema.sql and migrations/,
+// so the tests run against the same tables as the tool.
+// The search index of the migrations needs sqlite built with FTS5,
+// tests using this package are built with '-tags sqlite_fts5'.
+package dbtest
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/hmaier-dev/checklist-tool/internal/database"
+)
+
+// Root of the repository, schema.sql and migrations/ are embedded by package main only
+func root(tb testing.TB) string {
+	tb.Helper()
+	_, file, _, ok := runtime.Caller(0)
+	if !ok {
+		tb.Fatal("couldn't find the root of the repository")
+	}
+	return filepath.Join(filepath.Dir(file), "..", "..", "..")
+}
+
+// Opens an empty database with the tables of the first version, before any migration.
+// Fill in data in the old shape and call Migrate to test the conversion.
+func Baseline(tb testing.TB) *sql.DB {
+	tb.Helper()
+	db, err := sql.Open("sqlite3", filepath.Join(tb.TempDir(), "test.sqlite"))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { db.Close() })
+	ddl, err := os.ReadFile(filepath.Join(root(tb), "schema.sql"))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	if _, err := db.Exec(string(ddl)); err != nil {
+		tb.Fatal(err)
+	}
+	return db
+}
+
+// Applies migrations/ like the startup of the tool
+func Migrate(tb testing.TB, db *sql.DB) {
+	tb.Helper()
+	err := database.Migrate(context.Background(), db, os.DirFS(filepath.Join(root(tb), "migrations")))
+	if err != nil {
+		tb.Fatal(err)
+	}
+}
+
+// Opens an empty database at the current version
+func Open(tb testing.TB) *sql.DB {
+	tb.Helper()
+	db := Baseline(tb)
+	Migrate(tb, db)
+	return db
+}
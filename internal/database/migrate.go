@@ -0,0 +1,62 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"log"
+	"sort"
+)
+
+// Applies all migrations from the passed filesystem, which haven't been applied yet.
+// The files are sorted by their name, so they should be prefixed with a number (e.g. 0001_name.sql).
+// The amount of applied migrations is stored in 'PRAGMA user_version'.
+// This file is not generated by sqlc.
+func Migrate(ctx context.Context, db *sql.DB, migrations fs.FS) error {
+	names, err := fs.Glob(migrations, "*.sql")
+	if err != nil {
+		return err
+	}
+	sort.Strings(names)
+
+	var version int
+	err = db.QueryRowContext(ctx, "PRAGMA user_version").Scan(&version)
+	if err != nil {
+		return fmt.Errorf("couldn't read user_version: %w", err)
+	}
+	for i := version; i < len(names); i++ {
+		stmt, err := fs.ReadFile(migrations, names[i])
+		if err != nil {
+			return err
+		}
+		// Every migration runs in its own transaction.
+		// If it fails, the database stays at the last working version.
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, string(stmt)); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration '%s' failed: %w", names[i], err)
+		}
+		// PRAGMA does not support placeholders
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("PRAGMA user_version = %d", i+1)); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+		log.Printf("Applied migration '%s'\n", names[i])
+	}
+	return nil
+}
+
+// Reports whether sqlite was compiled with the FTS5 extension,
+// which is needed by the search index.
+func HasFTS5(ctx context.Context, db *sql.DB) (bool, error) {
+	var used bool
+	err := db.QueryRowContext(ctx, "SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&used)
+	return used, err
+}
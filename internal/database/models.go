@@ -8,20 +8,94 @@ import (
 	"database/sql"
 )
 
+type Attachment struct {
+	ID          int64
+	EntryID     int64
+	Task        sql.NullString
+	Filename    string
+	ContentType string
+	Size        int64
+	Data        []byte
+	Date        int64
+}
+
+type Comment struct {
+	ID      int64
+	EntryID int64
+	Author  string
+	Body    string
+	Date    int64
+}
+
 type CustomField struct {
 	ID         int64
 	TemplateID int64
 	Key        string
 	Desc       string
+	Position   int64
+	RemovedAt  sql.NullInt64
+}
+
+type EntriesSearch struct {
+	Data     string
+	Template string
+	Answers  string
 }
 
 type Entry struct {
-	ID         int64
-	TemplateID int64
-	Data       string
-	Path       string
-	Yaml       sql.NullString
-	Date       sql.NullInt64
+	ID                int64
+	TemplateID        int64
+	Path              string
+	Yaml              sql.NullString
+	Date              sql.NullInt64
+	DeletedAt         sql.NullInt64
+	Status            string
+	Due               sql.NullInt64
+	AssigneeID        sql.NullInt64
+	ProgressChecked   int64
+	ProgressTotal     int64
+	RequiredChecked   int64
+	RequiredTotal     int64
+	Answers           sql.NullString
+	Revision          int64
+	TemplateVersionID sql.NullInt64
+}
+
+type EntryEvent struct {
+	ID      int64
+	EntryID int64
+	Kind    string
+	Message string
+	Date    int64
+}
+
+type EntryValue struct {
+	EntryID int64
+	FieldID int64
+	Value   string
+}
+
+type ItemState struct {
+	EntryID         int64
+	Task            string
+	Checked         sql.NullBool
+	Text            sql.NullString
+	CheckedRevision int64
+	TextRevision    int64
+}
+
+type PdfExport struct {
+	ID            int64
+	EntryID       int64
+	EntryPath     string
+	Revision      int64
+	ContentSha256 string
+	Filename      string
+	StorageKey    string
+	Sha256        string
+	Size          int64
+	RequestedBy   string
+	Date          int64
 }
 
 type PdfNameSchema struct {
@@ -30,6 +104,17 @@ type PdfNameSchema struct {
 	Value      string
 }
 
+type Person struct {
+	ID   int64
+	Name string
+}
+
+type SavedView struct {
+	ID    int64
+	Name  string
+	Query string
+}
+
 type TabDescSchema struct {
 	ID         int64
 	TemplateID int64
@@ -41,4 +126,14 @@ type Template struct {
 	Name      string
 	EmptyYaml sql.NullString
 	File      sql.NullString
+	DueIn     sql.NullString
+	DueField  sql.NullString
+	CaseField sql.NullString
+}
+
+type TemplateVersion struct {
+	ID         int64
+	TemplateID int64
+	EmptyYaml  string
+	Date       int64
 }
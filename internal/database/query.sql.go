@@ -8,8 +8,307 @@ package database
 import (
 	"context"
 	"database/sql"
+	"strings"
 )
 
+const browseEntries = `-- name: BrowseEntries :many
+SELECT
+    entries.id,
+    entries.path,
+    entries.yaml,
+    entries.date,
+    entries.status,
+    entries.due,
+    entries.assignee_id,
+    entries.progress_checked,
+    entries.progress_total,
+    entries.required_checked,
+    entries.required_total,
+    entries.answers,
+    templates.name AS template_name
+FROM entries
+JOIN templates ON entries.template_id = templates.id
+JOIN (
+  SELECT
+    CAST(?1 AS TEXT) AS sort,
+    CAST(?2 AS TEXT) AS field,
+    CAST(?3 AS INTEGER) AS descending
+) AS params
+WHERE entries.deleted_at IS NULL
+  AND (CAST(?4 AS TEXT) = '' OR templates.name = ?4)
+  AND (CAST(?5 AS INTEGER) = 0 OR entries.date >= ?5)
+  AND (CAST(?6 AS INTEGER) = 0 OR entries.date <= ?6)
+  AND (CAST(?7 AS INTEGER) = 0 OR entries.status != 'done')
+  AND (CAST(?8 AS TEXT) = ''
+    OR (?8 = 'none' AND entries.assignee_id IS NULL)
+    OR CAST(entries.assignee_id AS TEXT) = ?8)
+  AND (CAST(?9 AS TEXT) = ''
+    OR (?9 = 'none' AND entries.due IS NULL)
+    OR (?9 = 'overdue' AND entries.status != 'done' AND entries.due < CAST(?10 AS INTEGER))
+    OR (?9 = 'soon' AND entries.status != 'done' AND entries.due < CAST(?11 AS INTEGER)))
+  AND (CAST(?12 AS TEXT) = '' OR CAST(?13 AS TEXT) = '' OR EXISTS (
+    SELECT 1 FROM entry_values
+    JOIN custom_fields ON custom_fields.id = entry_values.field_id
+    WHERE entry_values.entry_id = entries.id
+      AND custom_fields.key = ?12
+      AND (entry_values.value LIKE '%' || ?13 || '%' ESCAPE '\')))
+  AND (CAST(?14 AS TEXT) = '' OR entries.status = ?14)
+ORDER BY
+  -- entries without due date come last in both directions
+  CASE WHEN params.sort = 'due' THEN entries.due IS NULL END,
+  CASE WHEN params.descending = 0 THEN CASE params.sort
+      WHEN 'template' THEN templates.name
+      WHEN 'field' THEN (SELECT entry_values.value FROM entry_values
+        JOIN custom_fields ON custom_fields.id = entry_values.field_id
+        WHERE entry_values.entry_id = entries.id AND custom_fields.key = params.field)
+      WHEN 'due' THEN entries.due
+      WHEN 'progress' THEN entries.progress_checked * 1.0 / MAX(entries.progress_total, 1)
+      ELSE entries.date
+    END END ASC,
+  CASE WHEN params.descending = 1 THEN CASE params.sort
+      WHEN 'template' THEN templates.name
+      WHEN 'field' THEN (SELECT entry_values.value FROM entry_values
+        JOIN custom_fields ON custom_fields.id = entry_values.field_id
+        WHERE entry_values.entry_id = entries.id AND custom_fields.key = params.field)
+      WHEN 'due' THEN entries.due
+      WHEN 'progress' THEN entries.progress_checked * 1.0 / MAX(entries.progress_total, 1)
+      ELSE entries.date
+    END END DESC,
+  entries.id DESC
+LIMIT ?16 OFFSET ?15
+`
+
+type BrowseEntriesParams struct {
+	Sort        string
+	Field       string
+	Descending  int64
+	Template    string
+	DateFrom    int64
+	DateTo      int64
+	Open        int64
+	Assignee    string
+	DueFilter   string
+	Now         int64
+	Soon        int64
+	FilterField string
+	FilterValue string
+	Status      string
+	Offset      int64
+	Limit       int64
+}
+
+type BrowseEntriesRow struct {
+	ID              int64
+	Path            string
+	Yaml            sql.NullString
+	Date            sql.NullInt64
+	Status          string
+	Due             sql.NullInt64
+	AssigneeID      sql.NullInt64
+	ProgressChecked int64
+	ProgressTotal   int64
+	RequiredChecked int64
+	RequiredTotal   int64
+	Answers         sql.NullString
+	TemplateName    string
+}
+
+// Paginated list of the entries-browser, see handlers/browser.go.
+// sqlc can't build dynamic ORDER BY clauses, so the column is chosen by 'sort'.
+// sqlc only replaces the arguments of ORDER BY in a subquery, they are joined as the one row of 'params'.
+// The casts give the arguments their types, the LIKE is in parentheses, otherwise sqlc fails to parse its ESCAPE.
+func (q *Queries) BrowseEntries(ctx context.Context, arg BrowseEntriesParams) ([]BrowseEntriesRow, error) {
+	rows, err := q.db.QueryContext(ctx, browseEntries,
+		arg.Sort,
+		arg.Field,
+		arg.Descending,
+		arg.Template,
+		arg.DateFrom,
+		arg.DateTo,
+		arg.Open,
+		arg.Assignee,
+		arg.DueFilter,
+		arg.Now,
+		arg.Soon,
+		arg.FilterField,
+		arg.FilterValue,
+		arg.Status,
+		arg.Offset,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []BrowseEntriesRow
+	for rows.Next() {
+		var i BrowseEntriesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Path,
+			&i.Yaml,
+			&i.Date,
+			&i.Status,
+			&i.Due,
+			&i.AssigneeID,
+			&i.ProgressChecked,
+			&i.ProgressTotal,
+			&i.RequiredChecked,
+			&i.RequiredTotal,
+			&i.Answers,
+			&i.TemplateName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const clearAssigneeByPersonID = `-- name: ClearAssigneeByPersonID :exec
+UPDATE entries
+SET assignee_id = NULL
+WHERE assignee_id = ?
+`
+
+func (q *Queries) ClearAssigneeByPersonID(ctx context.Context, assigneeID sql.NullInt64) error {
+	_, err := q.db.ExecContext(ctx, clearAssigneeByPersonID, assigneeID)
+	return err
+}
+
+const clearYamlByID = `-- name: ClearYamlByID :exec
+UPDATE entries
+SET yaml = NULL
+WHERE id = ?
+`
+
+func (q *Queries) ClearYamlByID(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, clearYamlByID, id)
+	return err
+}
+
+const countBrowseEntries = `-- name: CountBrowseEntries :one
+SELECT COUNT(*)
+FROM entries
+JOIN templates ON entries.template_id = templates.id
+WHERE entries.deleted_at IS NULL
+  AND (CAST(?1 AS TEXT) = '' OR templates.name = ?1)
+  AND (CAST(?2 AS INTEGER) = 0 OR entries.date >= ?2)
+  AND (CAST(?3 AS INTEGER) = 0 OR entries.date <= ?3)
+  AND (CAST(?4 AS INTEGER) = 0 OR entries.status != 'done')
+  AND (CAST(?5 AS TEXT) = ''
+    OR (?5 = 'none' AND entries.assignee_id IS NULL)
+    OR CAST(entries.assignee_id AS TEXT) = ?5)
+  AND (CAST(?6 AS TEXT) = ''
+    OR (?6 = 'none' AND entries.due IS NULL)
+    OR (?6 = 'overdue' AND entries.status != 'done' AND entries.due < CAST(?7 AS INTEGER))
+    OR (?6 = 'soon' AND entries.status != 'done' AND entries.due < CAST(?8 AS INTEGER)))
+  AND (CAST(?9 AS TEXT) = '' OR CAST(?10 AS TEXT) = '' OR EXISTS (
+    SELECT 1 FROM entry_values
+    JOIN custom_fields ON custom_fields.id = entry_values.field_id
+    WHERE entry_values.entry_id = entries.id
+      AND custom_fields.key = ?9
+      AND (entry_values.value LIKE '%' || ?10 || '%' ESCAPE '\')))
+  AND (CAST(?11 AS TEXT) = '' OR entries.status = ?11)
+`
+
+type CountBrowseEntriesParams struct {
+	Template    string
+	DateFrom    int64
+	DateTo      int64
+	Open        int64
+	Assignee    string
+	DueFilter   string
+	Now         int64
+	Soon        int64
+	FilterField string
+	FilterValue string
+	Status      string
+}
+
+// Has to use the same WHERE as BrowseEntries
+func (q *Queries) CountBrowseEntries(ctx context.Context, arg CountBrowseEntriesParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countBrowseEntries,
+		arg.Template,
+		arg.DateFrom,
+		arg.DateTo,
+		arg.Open,
+		arg.Assignee,
+		arg.DueFilter,
+		arg.Now,
+		arg.Soon,
+		arg.FilterField,
+		arg.FilterValue,
+		arg.Status,
+	)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countEntriesByPath = `-- name: CountEntriesByPath :one
+SELECT COUNT(*)
+FROM entries
+WHERE path = ?
+`
+
+// Also counts the entries in the trash
+func (q *Queries) CountEntriesByPath(ctx context.Context, path string) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countEntriesByPath, path)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countPdfExportsByStorageKey = `-- name: CountPdfExportsByStorageKey :one
+SELECT COUNT(*)
+FROM pdf_exports
+WHERE storage_key = ?
+`
+
+func (q *Queries) CountPdfExportsByStorageKey(ctx context.Context, storageKey string) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countPdfExportsByStorageKey, storageKey)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const deleteAttachmentByIDAndEntryID = `-- name: DeleteAttachmentByIDAndEntryID :execrows
+DELETE FROM attachments
+WHERE id = ? AND entry_id = ?
+`
+
+type DeleteAttachmentByIDAndEntryIDParams struct {
+	ID      int64
+	EntryID int64
+}
+
+// The entry is part of the condition, so attachments can only be deleted from the entry they belong to
+func (q *Queries) DeleteAttachmentByIDAndEntryID(ctx context.Context, arg DeleteAttachmentByIDAndEntryIDParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteAttachmentByIDAndEntryID, arg.ID, arg.EntryID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteCustomFieldByID = `-- name: DeleteCustomFieldByID :exec
+DELETE FROM custom_fields
+WHERE id = ?
+`
+
+// The values of the field are deleted by a trigger
+func (q *Queries) DeleteCustomFieldByID(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, deleteCustomFieldByID, id)
+	return err
+}
+
 const deleteCustomFieldsByTemplateID = `-- name: DeleteCustomFieldsByTemplateID :exec
 DELETE FROM custom_fields
 WHERE template_id = ?
@@ -35,6 +334,7 @@ DELETE FROM entries
 WHERE path = ?
 `
 
+// Also deletes an entry in the trash, e.g. when a backup overwrites it
 func (q *Queries) DeleteEntryByPath(ctx context.Context, path string) error {
 	_, err := q.db.ExecContext(ctx, deleteEntryByPath, path)
 	return err
@@ -50,6 +350,25 @@ func (q *Queries) DeletePdfNameSchemaByTemplateID(ctx context.Context, templateI
 	return err
 }
 
+const deletePersonByID = `-- name: DeletePersonByID :exec
+DELETE FROM people
+WHERE id = ?
+`
+
+func (q *Queries) DeletePersonByID(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, deletePersonByID, id)
+	return err
+}
+
+const deleteSavedViewByID = `-- name: DeleteSavedViewByID :exec
+DELETE FROM saved_views WHERE id = ?
+`
+
+func (q *Queries) DeleteSavedViewByID(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, deleteSavedViewByID, id)
+	return err
+}
+
 const deleteTabDescSchemaByTemplateID = `-- name: DeleteTabDescSchemaByTemplateID :exec
 DELETE FROM tab_desc_schema
 WHERE template_id = ?
@@ -73,7 +392,7 @@ func (q *Queries) DeleteTemplateByID(ctx context.Context, id int64) error {
 const doesPathExist = `-- name: DoesPathExist :one
 SELECT path
 FROM entries
-WHERE path = ?
+WHERE path = ? AND deleted_at IS NULL
 `
 
 func (q *Queries) DoesPathExist(ctx context.Context, path string) (string, error) {
@@ -82,9 +401,47 @@ func (q *Queries) DoesPathExist(ctx context.Context, path string) (string, error
 	return path, err
 }
 
+const getAllCustomFields = `-- name: GetAllCustomFields :many
+SELECT id, template_id, key, desc, position, removed_at
+FROM custom_fields
+WHERE removed_at IS NULL
+ORDER BY template_id, position, id
+`
+
+func (q *Queries) GetAllCustomFields(ctx context.Context) ([]CustomField, error) {
+	rows, err := q.db.QueryContext(ctx, getAllCustomFields)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []CustomField
+	for rows.Next() {
+		var i CustomField
+		if err := rows.Scan(
+			&i.ID,
+			&i.TemplateID,
+			&i.Key,
+			&i.Desc,
+			&i.Position,
+			&i.RemovedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getAllEntries = `-- name: GetAllEntries :many
-SELECT id, template_id, data, path, yaml, date
+SELECT id, template_id, path, yaml, date, deleted_at, status, due, assignee_id, progress_checked, progress_total, required_checked, required_total, answers, revision, template_version_id
 FROM entries
+WHERE deleted_at IS NULL
 `
 
 func (q *Queries) GetAllEntries(ctx context.Context) ([]Entry, error) {
@@ -99,10 +456,20 @@ func (q *Queries) GetAllEntries(ctx context.Context) ([]Entry, error) {
 		if err := rows.Scan(
 			&i.ID,
 			&i.TemplateID,
-			&i.Data,
 			&i.Path,
 			&i.Yaml,
 			&i.Date,
+			&i.DeletedAt,
+			&i.Status,
+			&i.Due,
+			&i.AssigneeID,
+			&i.ProgressChecked,
+			&i.ProgressTotal,
+			&i.RequiredChecked,
+			&i.RequiredTotal,
+			&i.Answers,
+			&i.Revision,
+			&i.TemplateVersionID,
 		); err != nil {
 			return nil, err
 		}
@@ -120,23 +487,38 @@ func (q *Queries) GetAllEntries(ctx context.Context) ([]Entry, error) {
 const getAllEntriesPlusTemplateName = `-- name: GetAllEntriesPlusTemplateName :many
 SELECT
     entries.id,
-    entries.data,
     entries.path,
     entries.yaml,
     entries.date,
+    entries.status,
+    entries.due,
+    entries.assignee_id,
+    entries.progress_checked,
+    entries.progress_total,
+    entries.required_checked,
+    entries.required_total,
+    entries.answers,
     templates.name AS template_name
 FROM entries
 JOIN templates ON entries.template_id = templates.id
+WHERE entries.deleted_at IS NULL
 ORDER BY entries.date DESC
 `
 
 type GetAllEntriesPlusTemplateNameRow struct {
-	ID           int64
-	Data         string
-	Path         string
-	Yaml         sql.NullString
-	Date         sql.NullInt64
-	TemplateName string
+	ID              int64
+	Path            string
+	Yaml            sql.NullString
+	Date            sql.NullInt64
+	Status          string
+	Due             sql.NullInt64
+	AssigneeID      sql.NullInt64
+	ProgressChecked int64
+	ProgressTotal   int64
+	RequiredChecked int64
+	RequiredTotal   int64
+	Answers         sql.NullString
+	TemplateName    string
 }
 
 func (q *Queries) GetAllEntriesPlusTemplateName(ctx context.Context) ([]GetAllEntriesPlusTemplateNameRow, error) {
@@ -150,10 +532,17 @@ func (q *Queries) GetAllEntriesPlusTemplateName(ctx context.Context) ([]GetAllEn
 		var i GetAllEntriesPlusTemplateNameRow
 		if err := rows.Scan(
 			&i.ID,
-			&i.Data,
 			&i.Path,
 			&i.Yaml,
 			&i.Date,
+			&i.Status,
+			&i.Due,
+			&i.AssigneeID,
+			&i.ProgressChecked,
+			&i.ProgressTotal,
+			&i.RequiredChecked,
+			&i.RequiredTotal,
+			&i.Answers,
 			&i.TemplateName,
 		); err != nil {
 			return nil, err
@@ -169,26 +558,30 @@ func (q *Queries) GetAllEntriesPlusTemplateName(ctx context.Context) ([]GetAllEn
 	return items, nil
 }
 
-const getAllTemplates = `-- name: GetAllTemplates :many
-SELECT id, name, empty_yaml, file
-FROM templates
+const getAllEntryValuesByEntryID = `-- name: GetAllEntryValuesByEntryID :many
+SELECT custom_fields.key, entry_values.value
+FROM entry_values
+JOIN custom_fields ON custom_fields.id = entry_values.field_id
+WHERE entry_values.entry_id = ?
+ORDER BY custom_fields.position, custom_fields.id
 `
 
-func (q *Queries) GetAllTemplates(ctx context.Context) ([]Template, error) {
-	rows, err := q.db.QueryContext(ctx, getAllTemplates)
+type GetAllEntryValuesByEntryIDRow struct {
+	Key   string
+	Value string
+}
+
+// Values of all fields of an entry, also of removed ones
+func (q *Queries) GetAllEntryValuesByEntryID(ctx context.Context, entryID int64) ([]GetAllEntryValuesByEntryIDRow, error) {
+	rows, err := q.db.QueryContext(ctx, getAllEntryValuesByEntryID, entryID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Template
+	var items []GetAllEntryValuesByEntryIDRow
 	for rows.Next() {
-		var i Template
-		if err := rows.Scan(
-			&i.ID,
-			&i.Name,
-			&i.EmptyYaml,
-			&i.File,
-		); err != nil {
+		var i GetAllEntryValuesByEntryIDRow
+		if err := rows.Scan(&i.Key, &i.Value); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -202,27 +595,33 @@ func (q *Queries) GetAllTemplates(ctx context.Context) ([]Template, error) {
 	return items, nil
 }
 
-const getCustomFieldsByTemplateName = `-- name: GetCustomFieldsByTemplateName :many
-SELECT cf.id, cf.template_id, cf.key, cf.desc
-FROM custom_fields cf
-JOIN templates t ON cf.template_id = t.id
-WHERE t.name = ?
+const getAllPdfExports = `-- name: GetAllPdfExports :many
+SELECT id, entry_id, entry_path, revision, content_sha256, filename, storage_key, sha256, size, requested_by, date
+FROM pdf_exports
+ORDER BY id
 `
 
-func (q *Queries) GetCustomFieldsByTemplateName(ctx context.Context, name string) ([]CustomField, error) {
-	rows, err := q.db.QueryContext(ctx, getCustomFieldsByTemplateName, name)
+func (q *Queries) GetAllPdfExports(ctx context.Context) ([]PdfExport, error) {
+	rows, err := q.db.QueryContext(ctx, getAllPdfExports)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []CustomField
+	var items []PdfExport
 	for rows.Next() {
-		var i CustomField
+		var i PdfExport
 		if err := rows.Scan(
 			&i.ID,
-			&i.TemplateID,
-			&i.Key,
-			&i.Desc,
+			&i.EntryID,
+			&i.EntryPath,
+			&i.Revision,
+			&i.ContentSha256,
+			&i.Filename,
+			&i.StorageKey,
+			&i.Sha256,
+			&i.Size,
+			&i.RequestedBy,
+			&i.Date,
 		); err != nil {
 			return nil, err
 		}
@@ -237,37 +636,22 @@ func (q *Queries) GetCustomFieldsByTemplateName(ctx context.Context, name string
 	return items, nil
 }
 
-const getEntriesByTemplateName = `-- name: GetEntriesByTemplateName :many
-SELECT
-    entries.id,
-    entries.template_id,
-    entries.data,
-    entries.path,
-    entries.yaml,
-    entries.date
-FROM entries
-JOIN templates ON entries.template_id = templates.id
-WHERE templates.name = ?
-ORDER BY entries.date DESC
+const getAllPeople = `-- name: GetAllPeople :many
+SELECT id, name
+FROM people
+ORDER BY name
 `
 
-func (q *Queries) GetEntriesByTemplateName(ctx context.Context, name string) ([]Entry, error) {
-	rows, err := q.db.QueryContext(ctx, getEntriesByTemplateName, name)
+func (q *Queries) GetAllPeople(ctx context.Context) ([]Person, error) {
+	rows, err := q.db.QueryContext(ctx, getAllPeople)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Entry
+	var items []Person
 	for rows.Next() {
-		var i Entry
-		if err := rows.Scan(
-			&i.ID,
-			&i.TemplateID,
-			&i.Data,
-			&i.Path,
-			&i.Yaml,
-			&i.Date,
-		); err != nil {
+		var i Person
+		if err := rows.Scan(&i.ID, &i.Name); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -281,42 +665,22 @@ func (q *Queries) GetEntriesByTemplateName(ctx context.Context, name string) ([]
 	return items, nil
 }
 
-const getEntryByPath = `-- name: GetEntryByPath :one
-SELECT id, template_id, data, path, yaml, date
-FROM entries
-WHERE path = ?
-`
-
-func (q *Queries) GetEntryByPath(ctx context.Context, path string) (Entry, error) {
-	row := q.db.QueryRowContext(ctx, getEntryByPath, path)
-	var i Entry
-	err := row.Scan(
-		&i.ID,
-		&i.TemplateID,
-		&i.Data,
-		&i.Path,
-		&i.Yaml,
-		&i.Date,
-	)
-	return i, err
-}
-
-const getPdfNamingByTemplateID = `-- name: GetPdfNamingByTemplateID :many
-SELECT id, template_id, value
-FROM pdf_name_schema
-WHERE template_id = ?
+const getAllSavedViews = `-- name: GetAllSavedViews :many
+SELECT id, name, query
+FROM saved_views
+ORDER BY name
 `
 
-func (q *Queries) GetPdfNamingByTemplateID(ctx context.Context, templateID int64) ([]PdfNameSchema, error) {
-	rows, err := q.db.QueryContext(ctx, getPdfNamingByTemplateID, templateID)
+func (q *Queries) GetAllSavedViews(ctx context.Context) ([]SavedView, error) {
+	rows, err := q.db.QueryContext(ctx, getAllSavedViews)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []PdfNameSchema
+	var items []SavedView
 	for rows.Next() {
-		var i PdfNameSchema
-		if err := rows.Scan(&i.ID, &i.TemplateID, &i.Value); err != nil {
+		var i SavedView
+		if err := rows.Scan(&i.ID, &i.Name, &i.Query); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -330,22 +694,29 @@ func (q *Queries) GetPdfNamingByTemplateID(ctx context.Context, templateID int64
 	return items, nil
 }
 
-const getTabDescriptionsByTemplateID = `-- name: GetTabDescriptionsByTemplateID :many
-SELECT id, template_id, value
-FROM tab_desc_schema
-WHERE template_id = ?
+const getAllTemplates = `-- name: GetAllTemplates :many
+SELECT id, name, empty_yaml, file, due_in, due_field, case_field
+FROM templates
 `
 
-func (q *Queries) GetTabDescriptionsByTemplateID(ctx context.Context, templateID int64) ([]TabDescSchema, error) {
-	rows, err := q.db.QueryContext(ctx, getTabDescriptionsByTemplateID, templateID)
+func (q *Queries) GetAllTemplates(ctx context.Context) ([]Template, error) {
+	rows, err := q.db.QueryContext(ctx, getAllTemplates)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []TabDescSchema
+	var items []Template
 	for rows.Next() {
-		var i TabDescSchema
-		if err := rows.Scan(&i.ID, &i.TemplateID, &i.Value); err != nil {
+		var i Template
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.EmptyYaml,
+			&i.File,
+			&i.DueIn,
+			&i.DueField,
+			&i.CaseField,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -359,107 +730,1200 @@ func (q *Queries) GetTabDescriptionsByTemplateID(ctx context.Context, templateID
 	return items, nil
 }
 
-const getTemplateById = `-- name: GetTemplateById :one
-SELECT id, name, empty_yaml, file
-FROM templates
+const getAttachmentByID = `-- name: GetAttachmentByID :one
+SELECT id, entry_id, task, filename, content_type, size, data, date
+FROM attachments
 WHERE id = ?
 `
 
-func (q *Queries) GetTemplateById(ctx context.Context, id int64) (Template, error) {
-	row := q.db.QueryRowContext(ctx, getTemplateById, id)
-	var i Template
+func (q *Queries) GetAttachmentByID(ctx context.Context, id int64) (Attachment, error) {
+	row := q.db.QueryRowContext(ctx, getAttachmentByID, id)
+	var i Attachment
 	err := row.Scan(
 		&i.ID,
-		&i.Name,
-		&i.EmptyYaml,
-		&i.File,
+		&i.EntryID,
+		&i.Task,
+		&i.Filename,
+		&i.ContentType,
+		&i.Size,
+		&i.Data,
+		&i.Date,
 	)
 	return i, err
 }
 
-const getTemplateByName = `-- name: GetTemplateByName :one
-SELECT id, name, empty_yaml, file
-FROM templates
-WHERE name = ?
+const getAttachmentsByEntryID = `-- name: GetAttachmentsByEntryID :many
+SELECT id, entry_id, task, filename, content_type, size, date
+FROM attachments
+WHERE entry_id = ?
+ORDER BY date ASC, id ASC
 `
 
-func (q *Queries) GetTemplateByName(ctx context.Context, name string) (Template, error) {
-	row := q.db.QueryRowContext(ctx, getTemplateByName, name)
-	var i Template
-	err := row.Scan(
-		&i.ID,
-		&i.Name,
-		&i.EmptyYaml,
-		&i.File,
-	)
-	return i, err
+type GetAttachmentsByEntryIDRow struct {
+	ID          int64
+	EntryID     int64
+	Task        sql.NullString
+	Filename    string
+	ContentType string
+	Size        int64
+	Date        int64
 }
 
-const getTemplateIdByName = `-- name: GetTemplateIdByName :one
-SELECT id FROM templates where name = ?
-`
-
-func (q *Queries) GetTemplateIdByName(ctx context.Context, name string) (int64, error) {
-	row := q.db.QueryRowContext(ctx, getTemplateIdByName, name)
-	var id int64
-	err := row.Scan(&id)
-	return id, err
+// Leaves out the file itself, because it is just needed for the listing
+func (q *Queries) GetAttachmentsByEntryID(ctx context.Context, entryID int64) ([]GetAttachmentsByEntryIDRow, error) {
+	rows, err := q.db.QueryContext(ctx, getAttachmentsByEntryID, entryID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetAttachmentsByEntryIDRow
+	for rows.Next() {
+		var i GetAttachmentsByEntryIDRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.EntryID,
+			&i.Task,
+			&i.Filename,
+			&i.ContentType,
+			&i.Size,
+			&i.Date,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getAttachmentsWithDataByEntryID = `-- name: GetAttachmentsWithDataByEntryID :many
+SELECT id, entry_id, task, filename, content_type, size, data, date
+FROM attachments
+WHERE entry_id = ?
+ORDER BY date ASC, id ASC
+`
+
+func (q *Queries) GetAttachmentsWithDataByEntryID(ctx context.Context, entryID int64) ([]Attachment, error) {
+	rows, err := q.db.QueryContext(ctx, getAttachmentsWithDataByEntryID, entryID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Attachment
+	for rows.Next() {
+		var i Attachment
+		if err := rows.Scan(
+			&i.ID,
+			&i.EntryID,
+			&i.Task,
+			&i.Filename,
+			&i.ContentType,
+			&i.Size,
+			&i.Data,
+			&i.Date,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChecklistYamlByEntryID = `-- name: GetChecklistYamlByEntryID :one
+SELECT COALESCE(v.empty_yaml, t.empty_yaml, '') AS empty_yaml
+FROM entries e
+JOIN templates t ON t.id = e.template_id
+LEFT JOIN template_versions v ON v.id = e.template_version_id
+WHERE e.id = ?
+`
+
+// Checklist of an entry: the version it was kept at or the current one of its template
+func (q *Queries) GetChecklistYamlByEntryID(ctx context.Context, id int64) (string, error) {
+	row := q.db.QueryRowContext(ctx, getChecklistYamlByEntryID, id)
+	var empty_yaml string
+	err := row.Scan(&empty_yaml)
+	return empty_yaml, err
+}
+
+const getCommentsByEntryID = `-- name: GetCommentsByEntryID :many
+SELECT id, entry_id, author, body, date
+FROM comments
+WHERE entry_id = ?
+ORDER BY date ASC, id ASC
+`
+
+func (q *Queries) GetCommentsByEntryID(ctx context.Context, entryID int64) ([]Comment, error) {
+	rows, err := q.db.QueryContext(ctx, getCommentsByEntryID, entryID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Comment
+	for rows.Next() {
+		var i Comment
+		if err := rows.Scan(
+			&i.ID,
+			&i.EntryID,
+			&i.Author,
+			&i.Body,
+			&i.Date,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getCustomFieldsByTemplateID = `-- name: GetCustomFieldsByTemplateID :many
+SELECT id, template_id, key, desc, position, removed_at
+FROM custom_fields
+WHERE template_id = ?
+ORDER BY position, id
+`
+
+func (q *Queries) GetCustomFieldsByTemplateID(ctx context.Context, templateID int64) ([]CustomField, error) {
+	rows, err := q.db.QueryContext(ctx, getCustomFieldsByTemplateID, templateID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []CustomField
+	for rows.Next() {
+		var i CustomField
+		if err := rows.Scan(
+			&i.ID,
+			&i.TemplateID,
+			&i.Key,
+			&i.Desc,
+			&i.Position,
+			&i.RemovedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getCustomFieldsByTemplateName = `-- name: GetCustomFieldsByTemplateName :many
+SELECT cf.id, cf.template_id, cf.key, cf.desc, cf.position, cf.removed_at
+FROM custom_fields cf
+JOIN templates t ON cf.template_id = t.id
+WHERE t.name = ? AND cf.removed_at IS NULL
+ORDER BY cf.position, cf.id
+`
+
+func (q *Queries) GetCustomFieldsByTemplateName(ctx context.Context, name string) ([]CustomField, error) {
+	rows, err := q.db.QueryContext(ctx, getCustomFieldsByTemplateName, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []CustomField
+	for rows.Next() {
+		var i CustomField
+		if err := rows.Scan(
+			&i.ID,
+			&i.TemplateID,
+			&i.Key,
+			&i.Desc,
+			&i.Position,
+			&i.RemovedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getDeletedEntriesPlusTemplateName = `-- name: GetDeletedEntriesPlusTemplateName :many
+SELECT
+    entries.id,
+    entries.path,
+    entries.yaml,
+    entries.date,
+    entries.deleted_at,
+    entries.status,
+    entries.due,
+    entries.assignee_id,
+    entries.progress_checked,
+    entries.progress_total,
+    entries.required_checked,
+    entries.required_total,
+    entries.answers,
+    templates.name AS template_name
+FROM entries
+JOIN templates ON entries.template_id = templates.id
+WHERE entries.deleted_at IS NOT NULL
+ORDER BY entries.deleted_at DESC
+`
+
+type GetDeletedEntriesPlusTemplateNameRow struct {
+	ID              int64
+	Path            string
+	Yaml            sql.NullString
+	Date            sql.NullInt64
+	DeletedAt       sql.NullInt64
+	Status          string
+	Due             sql.NullInt64
+	AssigneeID      sql.NullInt64
+	ProgressChecked int64
+	ProgressTotal   int64
+	RequiredChecked int64
+	RequiredTotal   int64
+	Answers         sql.NullString
+	TemplateName    string
+}
+
+func (q *Queries) GetDeletedEntriesPlusTemplateName(ctx context.Context) ([]GetDeletedEntriesPlusTemplateNameRow, error) {
+	rows, err := q.db.QueryContext(ctx, getDeletedEntriesPlusTemplateName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetDeletedEntriesPlusTemplateNameRow
+	for rows.Next() {
+		var i GetDeletedEntriesPlusTemplateNameRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Path,
+			&i.Yaml,
+			&i.Date,
+			&i.DeletedAt,
+			&i.Status,
+			&i.Due,
+			&i.AssigneeID,
+			&i.ProgressChecked,
+			&i.ProgressTotal,
+			&i.RequiredChecked,
+			&i.RequiredTotal,
+			&i.Answers,
+			&i.TemplateName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getDeletedEntryByPath = `-- name: GetDeletedEntryByPath :one
+SELECT id, template_id, path, yaml, date, deleted_at, status, due, assignee_id, progress_checked, progress_total, required_checked, required_total, answers, revision, template_version_id
+FROM entries
+WHERE path = ? AND deleted_at IS NOT NULL
+`
+
+func (q *Queries) GetDeletedEntryByPath(ctx context.Context, path string) (Entry, error) {
+	row := q.db.QueryRowContext(ctx, getDeletedEntryByPath, path)
+	var i Entry
+	err := row.Scan(
+		&i.ID,
+		&i.TemplateID,
+		&i.Path,
+		&i.Yaml,
+		&i.Date,
+		&i.DeletedAt,
+		&i.Status,
+		&i.Due,
+		&i.AssigneeID,
+		&i.ProgressChecked,
+		&i.ProgressTotal,
+		&i.RequiredChecked,
+		&i.RequiredTotal,
+		&i.Answers,
+		&i.Revision,
+		&i.TemplateVersionID,
+	)
+	return i, err
+}
+
+const getEntriesByCaseKey = `-- name: GetEntriesByCaseKey :many
+SELECT
+    entries.id,
+    entries.template_id,
+    entries.path,
+    entries.yaml,
+    entries.date,
+    entries.status,
+    entries.progress_checked,
+    entries.progress_total,
+    entries.required_checked,
+    entries.required_total,
+    entries.answers,
+    templates.name AS template_name
+FROM entries
+JOIN templates ON entries.template_id = templates.id
+WHERE templates.case_field IS NOT NULL
+  AND EXISTS (
+    SELECT 1 FROM entry_values
+    JOIN custom_fields ON custom_fields.id = entry_values.field_id
+    WHERE entry_values.entry_id = entries.id
+      AND custom_fields.key = templates.case_field
+      AND entry_values.value = ?1)
+  AND entries.deleted_at IS NULL
+ORDER BY entries.date
+`
+
+type GetEntriesByCaseKeyRow struct {
+	ID              int64
+	TemplateID      int64
+	Path            string
+	Yaml            sql.NullString
+	Date            sql.NullInt64
+	Status          string
+	ProgressChecked int64
+	ProgressTotal   int64
+	RequiredChecked int64
+	RequiredTotal   int64
+	Answers         sql.NullString
+	TemplateName    string
+}
+
+func (q *Queries) GetEntriesByCaseKey(ctx context.Context, caseKey string) ([]GetEntriesByCaseKeyRow, error) {
+	rows, err := q.db.QueryContext(ctx, getEntriesByCaseKey, caseKey)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetEntriesByCaseKeyRow
+	for rows.Next() {
+		var i GetEntriesByCaseKeyRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.TemplateID,
+			&i.Path,
+			&i.Yaml,
+			&i.Date,
+			&i.Status,
+			&i.ProgressChecked,
+			&i.ProgressTotal,
+			&i.RequiredChecked,
+			&i.RequiredTotal,
+			&i.Answers,
+			&i.TemplateName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getEntriesByTemplateIDWithDeleted = `-- name: GetEntriesByTemplateIDWithDeleted :many
+SELECT id, template_id, path, yaml, date, deleted_at, status, due, assignee_id, progress_checked, progress_total, required_checked, required_total, answers, revision, template_version_id
+FROM entries
+WHERE template_id = ?
+`
+
+// Also returns the entries from the trash,
+// so they stay in sync with their template when restored.
+func (q *Queries) GetEntriesByTemplateIDWithDeleted(ctx context.Context, templateID int64) ([]Entry, error) {
+	rows, err := q.db.QueryContext(ctx, getEntriesByTemplateIDWithDeleted, templateID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Entry
+	for rows.Next() {
+		var i Entry
+		if err := rows.Scan(
+			&i.ID,
+			&i.TemplateID,
+			&i.Path,
+			&i.Yaml,
+			&i.Date,
+			&i.DeletedAt,
+			&i.Status,
+			&i.Due,
+			&i.AssigneeID,
+			&i.ProgressChecked,
+			&i.ProgressTotal,
+			&i.RequiredChecked,
+			&i.RequiredTotal,
+			&i.Answers,
+			&i.Revision,
+			&i.TemplateVersionID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getEntriesByTemplateName = `-- name: GetEntriesByTemplateName :many
+SELECT
+    entries.id,
+    entries.template_id,
+    entries.path,
+    entries.yaml,
+    entries.date,
+    entries.deleted_at,
+    entries.status,
+    entries.due,
+    entries.assignee_id,
+    entries.progress_checked,
+    entries.progress_total,
+    entries.required_checked,
+    entries.required_total,
+    entries.answers,
+    entries.revision,
+    entries.template_version_id
+FROM entries
+JOIN templates ON entries.template_id = templates.id
+WHERE templates.name = ? AND entries.deleted_at IS NULL
+ORDER BY entries.date DESC
+`
+
+func (q *Queries) GetEntriesByTemplateName(ctx context.Context, name string) ([]Entry, error) {
+	rows, err := q.db.QueryContext(ctx, getEntriesByTemplateName, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Entry
+	for rows.Next() {
+		var i Entry
+		if err := rows.Scan(
+			&i.ID,
+			&i.TemplateID,
+			&i.Path,
+			&i.Yaml,
+			&i.Date,
+			&i.DeletedAt,
+			&i.Status,
+			&i.Due,
+			&i.AssigneeID,
+			&i.ProgressChecked,
+			&i.ProgressTotal,
+			&i.RequiredChecked,
+			&i.RequiredTotal,
+			&i.Answers,
+			&i.Revision,
+			&i.TemplateVersionID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getEntriesWithYaml = `-- name: GetEntriesWithYaml :many
+SELECT id, yaml
+FROM entries
+WHERE yaml IS NOT NULL
+`
+
+type GetEntriesWithYamlRow struct {
+	ID   int64
+	Yaml sql.NullString
+}
+
+// Entries created before 'item_states', see migrations/0013_item_states.sql
+func (q *Queries) GetEntriesWithYaml(ctx context.Context) ([]GetEntriesWithYamlRow, error) {
+	rows, err := q.db.QueryContext(ctx, getEntriesWithYaml)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetEntriesWithYamlRow
+	for rows.Next() {
+		var i GetEntriesWithYamlRow
+		if err := rows.Scan(&i.ID, &i.Yaml); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getEntriesWithoutAnswers = `-- name: GetEntriesWithoutAnswers :many
+SELECT id, template_id
+FROM entries
+WHERE answers IS NULL
+`
+
+type GetEntriesWithoutAnswersRow struct {
+	ID         int64
+	TemplateID int64
+}
+
+func (q *Queries) GetEntriesWithoutAnswers(ctx context.Context) ([]GetEntriesWithoutAnswersRow, error) {
+	rows, err := q.db.QueryContext(ctx, getEntriesWithoutAnswers)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetEntriesWithoutAnswersRow
+	for rows.Next() {
+		var i GetEntriesWithoutAnswersRow
+		if err := rows.Scan(&i.ID, &i.TemplateID); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getEntriesWithoutProgress = `-- name: GetEntriesWithoutProgress :many
+SELECT id, template_id
+FROM entries
+WHERE progress_total = 0
+`
+
+type GetEntriesWithoutProgressRow struct {
+	ID         int64
+	TemplateID int64
+}
+
+func (q *Queries) GetEntriesWithoutProgress(ctx context.Context) ([]GetEntriesWithoutProgressRow, error) {
+	rows, err := q.db.QueryContext(ctx, getEntriesWithoutProgress)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetEntriesWithoutProgressRow
+	for rows.Next() {
+		var i GetEntriesWithoutProgressRow
+		if err := rows.Scan(&i.ID, &i.TemplateID); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getEntryByPath = `-- name: GetEntryByPath :one
+SELECT id, template_id, path, yaml, date, deleted_at, status, due, assignee_id, progress_checked, progress_total, required_checked, required_total, answers, revision, template_version_id
+FROM entries
+WHERE path = ? AND deleted_at IS NULL
+`
+
+func (q *Queries) GetEntryByPath(ctx context.Context, path string) (Entry, error) {
+	row := q.db.QueryRowContext(ctx, getEntryByPath, path)
+	var i Entry
+	err := row.Scan(
+		&i.ID,
+		&i.TemplateID,
+		&i.Path,
+		&i.Yaml,
+		&i.Date,
+		&i.DeletedAt,
+		&i.Status,
+		&i.Due,
+		&i.AssigneeID,
+		&i.ProgressChecked,
+		&i.ProgressTotal,
+		&i.RequiredChecked,
+		&i.RequiredTotal,
+		&i.Answers,
+		&i.Revision,
+		&i.TemplateVersionID,
+	)
+	return i, err
+}
+
+const getEntryEventsByEntryID = `-- name: GetEntryEventsByEntryID :many
+SELECT id, entry_id, kind, message, date
+FROM entry_events
+WHERE entry_id = ?
+ORDER BY date, id
+`
+
+func (q *Queries) GetEntryEventsByEntryID(ctx context.Context, entryID int64) ([]EntryEvent, error) {
+	rows, err := q.db.QueryContext(ctx, getEntryEventsByEntryID, entryID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []EntryEvent
+	for rows.Next() {
+		var i EntryEvent
+		if err := rows.Scan(
+			&i.ID,
+			&i.EntryID,
+			&i.Kind,
+			&i.Message,
+			&i.Date,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getEntryIDByPath = `-- name: GetEntryIDByPath :one
+SELECT id
+FROM entries
+WHERE path = ?
+`
+
+// Also finds the entries in the trash
+func (q *Queries) GetEntryIDByPath(ctx context.Context, path string) (int64, error) {
+	row := q.db.QueryRowContext(ctx, getEntryIDByPath, path)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
+const getEntryValuesByEntryIDs = `-- name: GetEntryValuesByEntryIDs :many
+SELECT
+    entries.id AS entry_id,
+    custom_fields.id AS field_id,
+    custom_fields.key,
+    custom_fields.desc,
+    COALESCE(entry_values.value, '') AS value
+FROM entries
+JOIN custom_fields ON custom_fields.template_id = entries.template_id
+  AND custom_fields.removed_at IS NULL
+LEFT JOIN entry_values ON entry_values.entry_id = entries.id
+  AND entry_values.field_id = custom_fields.id
+WHERE entries.id IN (/*SLICE:ids*/?)
+ORDER BY entries.id, custom_fields.position, custom_fields.id
+`
+
+type GetEntryValuesByEntryIDsRow struct {
+	EntryID int64
+	FieldID int64
+	Key     string
+	Desc    string
+	Value   string
+}
+
+// Every field of the template is returned,
+// fields without a stored value have an empty one.
+func (q *Queries) GetEntryValuesByEntryIDs(ctx context.Context, ids []int64) ([]GetEntryValuesByEntryIDsRow, error) {
+	query := getEntryValuesByEntryIDs
+	var queryParams []interface{}
+	if len(ids) > 0 {
+		for _, v := range ids {
+			queryParams = append(queryParams, v)
+		}
+		query = strings.Replace(query, "/*SLICE:ids*/?", strings.Repeat(",?", len(ids))[1:], 1)
+	} else {
+		query = strings.Replace(query, "/*SLICE:ids*/?", "NULL", 1)
+	}
+	rows, err := q.db.QueryContext(ctx, query, queryParams...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetEntryValuesByEntryIDsRow
+	for rows.Next() {
+		var i GetEntryValuesByEntryIDsRow
+		if err := rows.Scan(
+			&i.EntryID,
+			&i.FieldID,
+			&i.Key,
+			&i.Desc,
+			&i.Value,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getItemStatesByEntryID = `-- name: GetItemStatesByEntryID :many
+SELECT entry_id, task, checked, text, checked_revision, text_revision
+FROM item_states
+WHERE entry_id = ?
+`
+
+func (q *Queries) GetItemStatesByEntryID(ctx context.Context, entryID int64) ([]ItemState, error) {
+	rows, err := q.db.QueryContext(ctx, getItemStatesByEntryID, entryID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ItemState
+	for rows.Next() {
+		var i ItemState
+		if err := rows.Scan(
+			&i.EntryID,
+			&i.Task,
+			&i.Checked,
+			&i.Text,
+			&i.CheckedRevision,
+			&i.TextRevision,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPdfExportByID = `-- name: GetPdfExportByID :one
+SELECT id, entry_id, entry_path, revision, content_sha256, filename, storage_key, sha256, size, requested_by, date
+FROM pdf_exports
+WHERE id = ?
+`
+
+func (q *Queries) GetPdfExportByID(ctx context.Context, id int64) (PdfExport, error) {
+	row := q.db.QueryRowContext(ctx, getPdfExportByID, id)
+	var i PdfExport
+	err := row.Scan(
+		&i.ID,
+		&i.EntryID,
+		&i.EntryPath,
+		&i.Revision,
+		&i.ContentSha256,
+		&i.Filename,
+		&i.StorageKey,
+		&i.Sha256,
+		&i.Size,
+		&i.RequestedBy,
+		&i.Date,
+	)
+	return i, err
+}
+
+const getPdfExportsByEntryPath = `-- name: GetPdfExportsByEntryPath :many
+SELECT id, entry_id, entry_path, revision, content_sha256, filename, storage_key, sha256, size, requested_by, date
+FROM pdf_exports
+WHERE entry_path = ?
+ORDER BY date DESC, id DESC
+`
+
+func (q *Queries) GetPdfExportsByEntryPath(ctx context.Context, entryPath string) ([]PdfExport, error) {
+	rows, err := q.db.QueryContext(ctx, getPdfExportsByEntryPath, entryPath)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []PdfExport
+	for rows.Next() {
+		var i PdfExport
+		if err := rows.Scan(
+			&i.ID,
+			&i.EntryID,
+			&i.EntryPath,
+			&i.Revision,
+			&i.ContentSha256,
+			&i.Filename,
+			&i.StorageKey,
+			&i.Sha256,
+			&i.Size,
+			&i.RequestedBy,
+			&i.Date,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPdfNamingByTemplateID = `-- name: GetPdfNamingByTemplateID :many
+SELECT id, template_id, value
+FROM pdf_name_schema
+WHERE template_id = ?
+`
+
+func (q *Queries) GetPdfNamingByTemplateID(ctx context.Context, templateID int64) ([]PdfNameSchema, error) {
+	rows, err := q.db.QueryContext(ctx, getPdfNamingByTemplateID, templateID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []PdfNameSchema
+	for rows.Next() {
+		var i PdfNameSchema
+		if err := rows.Scan(&i.ID, &i.TemplateID, &i.Value); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPersonByID = `-- name: GetPersonByID :one
+SELECT id, name
+FROM people
+WHERE id = ?
+`
+
+func (q *Queries) GetPersonByID(ctx context.Context, id int64) (Person, error) {
+	row := q.db.QueryRowContext(ctx, getPersonByID, id)
+	var i Person
+	err := row.Scan(&i.ID, &i.Name)
+	return i, err
+}
+
+const getTabDescriptionsByTemplateID = `-- name: GetTabDescriptionsByTemplateID :many
+SELECT id, template_id, value
+FROM tab_desc_schema
+WHERE template_id = ?
+`
+
+func (q *Queries) GetTabDescriptionsByTemplateID(ctx context.Context, templateID int64) ([]TabDescSchema, error) {
+	rows, err := q.db.QueryContext(ctx, getTabDescriptionsByTemplateID, templateID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []TabDescSchema
+	for rows.Next() {
+		var i TabDescSchema
+		if err := rows.Scan(&i.ID, &i.TemplateID, &i.Value); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getTemplateById = `-- name: GetTemplateById :one
+SELECT id, name, empty_yaml, file, due_in, due_field, case_field
+FROM templates
+WHERE id = ?
+`
+
+func (q *Queries) GetTemplateById(ctx context.Context, id int64) (Template, error) {
+	row := q.db.QueryRowContext(ctx, getTemplateById, id)
+	var i Template
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.EmptyYaml,
+		&i.File,
+		&i.DueIn,
+		&i.DueField,
+		&i.CaseField,
+	)
+	return i, err
+}
+
+const getTemplateByName = `-- name: GetTemplateByName :one
+SELECT id, name, empty_yaml, file, due_in, due_field, case_field
+FROM templates
+WHERE name = ?
+`
+
+func (q *Queries) GetTemplateByName(ctx context.Context, name string) (Template, error) {
+	row := q.db.QueryRowContext(ctx, getTemplateByName, name)
+	var i Template
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.EmptyYaml,
+		&i.File,
+		&i.DueIn,
+		&i.DueField,
+		&i.CaseField,
+	)
+	return i, err
+}
+
+const getTemplateIdByName = `-- name: GetTemplateIdByName :one
+SELECT id FROM templates where name = ?
+`
+
+func (q *Queries) GetTemplateIdByName(ctx context.Context, name string) (int64, error) {
+	row := q.db.QueryRowContext(ctx, getTemplateIdByName, name)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
+const getTemplateNameById = `-- name: GetTemplateNameById :one
+SELECT name FROM templates WHERE id = ?
+`
+
+func (q *Queries) GetTemplateNameById(ctx context.Context, id int64) (string, error) {
+	row := q.db.QueryRowContext(ctx, getTemplateNameById, id)
+	var name string
+	err := row.Scan(&name)
+	return name, err
+}
+
+const getTemplateVersionsByTemplateID = `-- name: GetTemplateVersionsByTemplateID :many
+SELECT id, template_id, empty_yaml, date
+FROM template_versions
+WHERE template_id = ?
+ORDER BY id
+`
+
+func (q *Queries) GetTemplateVersionsByTemplateID(ctx context.Context, templateID int64) ([]TemplateVersion, error) {
+	rows, err := q.db.QueryContext(ctx, getTemplateVersionsByTemplateID, templateID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []TemplateVersion
+	for rows.Next() {
+		var i TemplateVersion
+		if err := rows.Scan(
+			&i.ID,
+			&i.TemplateID,
+			&i.EmptyYaml,
+			&i.Date,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
-const getTemplateNameById = `-- name: GetTemplateNameById :one
-SELECT name FROM templates WHERE id = ?
+const insertAttachment = `-- name: InsertAttachment :exec
+INSERT INTO attachments (entry_id, task, filename, content_type, size, data, date)
+VALUES (?, ?, ?, ?, ?, ?, ?)
 `
 
-func (q *Queries) GetTemplateNameById(ctx context.Context, id int64) (string, error) {
-	row := q.db.QueryRowContext(ctx, getTemplateNameById, id)
-	var name string
-	err := row.Scan(&name)
-	return name, err
+type InsertAttachmentParams struct {
+	EntryID     int64
+	Task        sql.NullString
+	Filename    string
+	ContentType string
+	Size        int64
+	Data        []byte
+	Date        int64
+}
+
+func (q *Queries) InsertAttachment(ctx context.Context, arg InsertAttachmentParams) error {
+	_, err := q.db.ExecContext(ctx, insertAttachment,
+		arg.EntryID,
+		arg.Task,
+		arg.Filename,
+		arg.ContentType,
+		arg.Size,
+		arg.Data,
+		arg.Date,
+	)
+	return err
+}
+
+const insertComment = `-- name: InsertComment :exec
+INSERT INTO comments (entry_id, author, body, date)
+VALUES (?, ?, ?, ?)
+`
+
+type InsertCommentParams struct {
+	EntryID int64
+	Author  string
+	Body    string
+	Date    int64
+}
+
+func (q *Queries) InsertComment(ctx context.Context, arg InsertCommentParams) error {
+	_, err := q.db.ExecContext(ctx, insertComment,
+		arg.EntryID,
+		arg.Author,
+		arg.Body,
+		arg.Date,
+	)
+	return err
 }
 
 const insertCustomField = `-- name: InsertCustomField :exec
-INSERT INTO custom_fields (template_id, key, desc)
-VALUES (?, ?, ?)
+INSERT INTO custom_fields (template_id, key, desc, position)
+VALUES (?, ?, ?, ?)
 `
 
 type InsertCustomFieldParams struct {
 	TemplateID int64
 	Key        string
 	Desc       string
+	Position   int64
 }
 
 func (q *Queries) InsertCustomField(ctx context.Context, arg InsertCustomFieldParams) error {
-	_, err := q.db.ExecContext(ctx, insertCustomField, arg.TemplateID, arg.Key, arg.Desc)
+	_, err := q.db.ExecContext(ctx, insertCustomField,
+		arg.TemplateID,
+		arg.Key,
+		arg.Desc,
+		arg.Position,
+	)
 	return err
 }
 
 const insertEntry = `-- name: InsertEntry :exec
-INSERT INTO entries (template_id, data, path, yaml, date)
+INSERT INTO entries (template_id, path, date, due, assignee_id)
 VALUES (?, ?, ?, ?, ?)
 `
 
 type InsertEntryParams struct {
 	TemplateID int64
-	Data       string
 	Path       string
-	Yaml       sql.NullString
 	Date       sql.NullInt64
+	Due        sql.NullInt64
+	AssigneeID sql.NullInt64
 }
 
 func (q *Queries) InsertEntry(ctx context.Context, arg InsertEntryParams) error {
 	_, err := q.db.ExecContext(ctx, insertEntry,
 		arg.TemplateID,
-		arg.Data,
 		arg.Path,
-		arg.Yaml,
+		arg.Date,
+		arg.Due,
+		arg.AssigneeID,
+	)
+	return err
+}
+
+const insertEntryEvent = `-- name: InsertEntryEvent :exec
+INSERT INTO entry_events (entry_id, kind, message, date)
+VALUES (?, ?, ?, ?)
+`
+
+type InsertEntryEventParams struct {
+	EntryID int64
+	Kind    string
+	Message string
+	Date    int64
+}
+
+func (q *Queries) InsertEntryEvent(ctx context.Context, arg InsertEntryEventParams) error {
+	_, err := q.db.ExecContext(ctx, insertEntryEvent,
+		arg.EntryID,
+		arg.Kind,
+		arg.Message,
 		arg.Date,
 	)
 	return err
 }
 
+const insertEntryEventsByAssignee = `-- name: InsertEntryEventsByAssignee :exec
+INSERT INTO entry_events (entry_id, kind, message, date)
+SELECT id, 'assignee', ?, ?
+FROM entries
+WHERE assignee_id = ?
+`
+
+type InsertEntryEventsByAssigneeParams struct {
+	Message    string
+	Date       int64
+	AssigneeID sql.NullInt64
+}
+
+func (q *Queries) InsertEntryEventsByAssignee(ctx context.Context, arg InsertEntryEventsByAssigneeParams) error {
+	_, err := q.db.ExecContext(ctx, insertEntryEventsByAssignee, arg.Message, arg.Date, arg.AssigneeID)
+	return err
+}
+
 const insertNewChecklistTemplate = `-- name: InsertNewChecklistTemplate :one
-INSERT INTO templates (name, empty_yaml, file)
-VALUES (?, ?, ?)
+INSERT INTO templates (name, empty_yaml, file, due_in, due_field, case_field)
+VALUES (?, ?, ?, ?, ?, ?)
 RETURNING id
 `
 
@@ -467,15 +1931,74 @@ type InsertNewChecklistTemplateParams struct {
 	Name      string
 	EmptyYaml sql.NullString
 	File      sql.NullString
+	DueIn     sql.NullString
+	DueField  sql.NullString
+	CaseField sql.NullString
 }
 
 func (q *Queries) InsertNewChecklistTemplate(ctx context.Context, arg InsertNewChecklistTemplateParams) (int64, error) {
-	row := q.db.QueryRowContext(ctx, insertNewChecklistTemplate, arg.Name, arg.EmptyYaml, arg.File)
+	row := q.db.QueryRowContext(ctx, insertNewChecklistTemplate,
+		arg.Name,
+		arg.EmptyYaml,
+		arg.File,
+		arg.DueIn,
+		arg.DueField,
+		arg.CaseField,
+	)
 	var id int64
 	err := row.Scan(&id)
 	return id, err
 }
 
+const insertPdfExport = `-- name: InsertPdfExport :one
+INSERT INTO pdf_exports (entry_id, entry_path, revision, content_sha256, filename, storage_key, sha256, size, requested_by, date)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+RETURNING id, entry_id, entry_path, revision, content_sha256, filename, storage_key, sha256, size, requested_by, date
+`
+
+type InsertPdfExportParams struct {
+	EntryID       int64
+	EntryPath     string
+	Revision      int64
+	ContentSha256 string
+	Filename      string
+	StorageKey    string
+	Sha256        string
+	Size          int64
+	RequestedBy   string
+	Date          int64
+}
+
+func (q *Queries) InsertPdfExport(ctx context.Context, arg InsertPdfExportParams) (PdfExport, error) {
+	row := q.db.QueryRowContext(ctx, insertPdfExport,
+		arg.EntryID,
+		arg.EntryPath,
+		arg.Revision,
+		arg.ContentSha256,
+		arg.Filename,
+		arg.StorageKey,
+		arg.Sha256,
+		arg.Size,
+		arg.RequestedBy,
+		arg.Date,
+	)
+	var i PdfExport
+	err := row.Scan(
+		&i.ID,
+		&i.EntryID,
+		&i.EntryPath,
+		&i.Revision,
+		&i.ContentSha256,
+		&i.Filename,
+		&i.StorageKey,
+		&i.Sha256,
+		&i.Size,
+		&i.RequestedBy,
+		&i.Date,
+	)
+	return i, err
+}
+
 const insertPdfNameSchema = `-- name: InsertPdfNameSchema :exec
 INSERT INTO pdf_name_schema (template_id, value)
 VALUES (?, ?)
@@ -491,6 +2014,16 @@ func (q *Queries) InsertPdfNameSchema(ctx context.Context, arg InsertPdfNameSche
 	return err
 }
 
+const insertPerson = `-- name: InsertPerson :exec
+INSERT INTO people (name)
+VALUES (?)
+`
+
+func (q *Queries) InsertPerson(ctx context.Context, name string) error {
+	_, err := q.db.ExecContext(ctx, insertPerson, name)
+	return err
+}
+
 const insertTabDescSchema = `-- name: InsertTabDescSchema :exec
 INSERT INTO tab_desc_schema (template_id, value)
 VALUES (?, ?)
@@ -506,65 +2039,472 @@ func (q *Queries) InsertTabDescSchema(ctx context.Context, arg InsertTabDescSche
 	return err
 }
 
-const updateDataById = `-- name: UpdateDataById :exec
-UPDATE entries
-SET data = ?
+const insertTemplateVersion = `-- name: InsertTemplateVersion :one
+INSERT INTO template_versions (template_id, empty_yaml, date)
+VALUES (?, ?, ?)
+RETURNING id
+`
+
+type InsertTemplateVersionParams struct {
+	TemplateID int64
+	EmptyYaml  string
+	Date       int64
+}
+
+func (q *Queries) InsertTemplateVersion(ctx context.Context, arg InsertTemplateVersionParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, insertTemplateVersion, arg.TemplateID, arg.EmptyYaml, arg.Date)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
+const nextRevisionByID = `-- name: NextRevisionByID :one
+UPDATE entries SET revision = revision + 1
 WHERE id = ?
+RETURNING revision
 `
 
-type UpdateDataByIdParams struct {
-	Data string
-	ID   int64
+func (q *Queries) NextRevisionByID(ctx context.Context, id int64) (int64, error) {
+	row := q.db.QueryRowContext(ctx, nextRevisionByID, id)
+	var revision int64
+	err := row.Scan(&revision)
+	return revision, err
 }
 
-func (q *Queries) UpdateDataById(ctx context.Context, arg UpdateDataByIdParams) error {
-	_, err := q.db.ExecContext(ctx, updateDataById, arg.Data, arg.ID)
+const purgeDeletedEntriesBefore = `-- name: PurgeDeletedEntriesBefore :execrows
+DELETE FROM entries
+WHERE deleted_at IS NOT NULL AND deleted_at < ?
+`
+
+func (q *Queries) PurgeDeletedEntriesBefore(ctx context.Context, deletedAt sql.NullInt64) (int64, error) {
+	result, err := q.db.ExecContext(ctx, purgeDeletedEntriesBefore, deletedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const purgeEntryByPath = `-- name: PurgeEntryByPath :exec
+DELETE FROM entries
+WHERE path = ? AND deleted_at IS NOT NULL
+`
+
+// Only entries in the trash can be purged
+func (q *Queries) PurgeEntryByPath(ctx context.Context, path string) error {
+	_, err := q.db.ExecContext(ctx, purgeEntryByPath, path)
 	return err
 }
 
-const updateTemplateById = `-- name: UpdateTemplateById :exec
-UPDATE templates SET empty_yaml = ?, file = ? WHERE id = ?
+const removeCustomFieldByID = `-- name: RemoveCustomFieldByID :exec
+UPDATE custom_fields
+SET removed_at = ?
+WHERE id = ? AND removed_at IS NULL
 `
 
-type UpdateTemplateByIdParams struct {
-	EmptyYaml sql.NullString
-	File      sql.NullString
+type RemoveCustomFieldByIDParams struct {
+	RemovedAt sql.NullInt64
 	ID        int64
 }
 
-func (q *Queries) UpdateTemplateById(ctx context.Context, arg UpdateTemplateByIdParams) error {
-	_, err := q.db.ExecContext(ctx, updateTemplateById, arg.EmptyYaml, arg.File, arg.ID)
+// Hides the field, but keeps its values
+func (q *Queries) RemoveCustomFieldByID(ctx context.Context, arg RemoveCustomFieldByIDParams) error {
+	_, err := q.db.ExecContext(ctx, removeCustomFieldByID, arg.RemovedAt, arg.ID)
+	return err
+}
+
+const renameAttachmentTask = `-- name: RenameAttachmentTask :exec
+UPDATE attachments
+SET task = ?1
+WHERE entry_id = ?2 AND task = ?3
+`
+
+type RenameAttachmentTaskParams struct {
+	NewTask sql.NullString
+	EntryID int64
+	OldTask sql.NullString
+}
+
+func (q *Queries) RenameAttachmentTask(ctx context.Context, arg RenameAttachmentTaskParams) error {
+	_, err := q.db.ExecContext(ctx, renameAttachmentTask, arg.NewTask, arg.EntryID, arg.OldTask)
+	return err
+}
+
+const renameItemState = `-- name: RenameItemState :exec
+UPDATE OR IGNORE item_states
+SET task = ?1
+WHERE entry_id = ?2 AND task = ?3
+`
+
+type RenameItemStateParams struct {
+	NewTask string
+	EntryID int64
+	OldTask string
+}
+
+// A task, which already has a state, keeps it
+func (q *Queries) RenameItemState(ctx context.Context, arg RenameItemStateParams) error {
+	_, err := q.db.ExecContext(ctx, renameItemState, arg.NewTask, arg.EntryID, arg.OldTask)
+	return err
+}
+
+const restoreCustomField = `-- name: RestoreCustomField :one
+INSERT INTO custom_fields (template_id, key, desc, position, removed_at)
+VALUES (?, ?, ?, ?, ?)
+RETURNING id
+`
+
+type RestoreCustomFieldParams struct {
+	TemplateID int64
+	Key        string
+	Desc       string
+	Position   int64
+	RemovedAt  sql.NullInt64
+}
+
+func (q *Queries) RestoreCustomField(ctx context.Context, arg RestoreCustomFieldParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, restoreCustomField,
+		arg.TemplateID,
+		arg.Key,
+		arg.Desc,
+		arg.Position,
+		arg.RemovedAt,
+	)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
+const restoreEntry = `-- name: RestoreEntry :one
+INSERT INTO entries (
+  template_id, path, date, deleted_at, status, due, assignee_id,
+  progress_checked, progress_total, required_checked, required_total,
+  answers, revision, template_version_id
+)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+RETURNING id
+`
+
+type RestoreEntryParams struct {
+	TemplateID        int64
+	Path              string
+	Date              sql.NullInt64
+	DeletedAt         sql.NullInt64
+	Status            string
+	Due               sql.NullInt64
+	AssigneeID        sql.NullInt64
+	ProgressChecked   int64
+	ProgressTotal     int64
+	RequiredChecked   int64
+	RequiredTotal     int64
+	Answers           sql.NullString
+	Revision          int64
+	TemplateVersionID sql.NullInt64
+}
+
+// Inserts an entry from a backup with all of its columns
+func (q *Queries) RestoreEntry(ctx context.Context, arg RestoreEntryParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, restoreEntry,
+		arg.TemplateID,
+		arg.Path,
+		arg.Date,
+		arg.DeletedAt,
+		arg.Status,
+		arg.Due,
+		arg.AssigneeID,
+		arg.ProgressChecked,
+		arg.ProgressTotal,
+		arg.RequiredChecked,
+		arg.RequiredTotal,
+		arg.Answers,
+		arg.Revision,
+		arg.TemplateVersionID,
+	)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
+const restoreEntryByPath = `-- name: RestoreEntryByPath :exec
+UPDATE entries
+SET deleted_at = NULL
+WHERE path = ?
+`
+
+func (q *Queries) RestoreEntryByPath(ctx context.Context, path string) error {
+	_, err := q.db.ExecContext(ctx, restoreEntryByPath, path)
+	return err
+}
+
+const restoreItemState = `-- name: RestoreItemState :exec
+INSERT INTO item_states (entry_id, task, checked, text, checked_revision, text_revision)
+VALUES (?, ?, ?, ?, ?, ?)
+`
+
+type RestoreItemStateParams struct {
+	EntryID         int64
+	Task            string
+	Checked         sql.NullBool
+	Text            sql.NullString
+	CheckedRevision int64
+	TextRevision    int64
+}
+
+func (q *Queries) RestoreItemState(ctx context.Context, arg RestoreItemStateParams) error {
+	_, err := q.db.ExecContext(ctx, restoreItemState,
+		arg.EntryID,
+		arg.Task,
+		arg.Checked,
+		arg.Text,
+		arg.CheckedRevision,
+		arg.TextRevision,
+	)
+	return err
+}
+
+const saveView = `-- name: SaveView :exec
+INSERT INTO saved_views (name, query)
+VALUES (?, ?)
+ON CONFLICT (name) DO UPDATE SET query = excluded.query
+`
+
+type SaveViewParams struct {
+	Name  string
+	Query string
+}
+
+// Saving a view under an existing name replaces it
+func (q *Queries) SaveView(ctx context.Context, arg SaveViewParams) error {
+	_, err := q.db.ExecContext(ctx, saveView, arg.Name, arg.Query)
+	return err
+}
+
+const setEntryValue = `-- name: SetEntryValue :exec
+INSERT INTO entry_values (entry_id, field_id, value)
+VALUES (?, ?, ?)
+ON CONFLICT (entry_id, field_id) DO UPDATE SET value = excluded.value
+`
+
+type SetEntryValueParams struct {
+	EntryID int64
+	FieldID int64
+	Value   string
+}
+
+func (q *Queries) SetEntryValue(ctx context.Context, arg SetEntryValueParams) error {
+	_, err := q.db.ExecContext(ctx, setEntryValue, arg.EntryID, arg.FieldID, arg.Value)
+	return err
+}
+
+const setItemChecked = `-- name: SetItemChecked :exec
+INSERT INTO item_states (entry_id, task, checked, checked_revision)
+VALUES (?, ?, ?, ?)
+ON CONFLICT (entry_id, task) DO UPDATE SET checked = excluded.checked, checked_revision = excluded.checked_revision
+`
+
+type SetItemCheckedParams struct {
+	EntryID         int64
+	Task            string
+	Checked         sql.NullBool
+	CheckedRevision int64
+}
+
+func (q *Queries) SetItemChecked(ctx context.Context, arg SetItemCheckedParams) error {
+	_, err := q.db.ExecContext(ctx, setItemChecked,
+		arg.EntryID,
+		arg.Task,
+		arg.Checked,
+		arg.CheckedRevision,
+	)
+	return err
+}
+
+const setItemText = `-- name: SetItemText :exec
+INSERT INTO item_states (entry_id, task, text, text_revision)
+VALUES (?, ?, ?, ?)
+ON CONFLICT (entry_id, task) DO UPDATE SET text = excluded.text, text_revision = excluded.text_revision
+`
+
+type SetItemTextParams struct {
+	EntryID      int64
+	Task         string
+	Text         sql.NullString
+	TextRevision int64
+}
+
+func (q *Queries) SetItemText(ctx context.Context, arg SetItemTextParams) error {
+	_, err := q.db.ExecContext(ctx, setItemText,
+		arg.EntryID,
+		arg.Task,
+		arg.Text,
+		arg.TextRevision,
+	)
+	return err
+}
+
+const setTemplateVersionByID = `-- name: SetTemplateVersionByID :exec
+UPDATE entries SET template_version_id = ? WHERE id = ?
+`
+
+type SetTemplateVersionByIDParams struct {
+	TemplateVersionID sql.NullInt64
+	ID                int64
+}
+
+func (q *Queries) SetTemplateVersionByID(ctx context.Context, arg SetTemplateVersionByIDParams) error {
+	_, err := q.db.ExecContext(ctx, setTemplateVersionByID, arg.TemplateVersionID, arg.ID)
+	return err
+}
+
+const softDeleteEntryByPath = `-- name: SoftDeleteEntryByPath :exec
+UPDATE entries
+SET deleted_at = ?
+WHERE path = ? AND deleted_at IS NULL
+`
+
+type SoftDeleteEntryByPathParams struct {
+	DeletedAt sql.NullInt64
+	Path      string
+}
+
+// Moves the entry into the trash
+func (q *Queries) SoftDeleteEntryByPath(ctx context.Context, arg SoftDeleteEntryByPathParams) error {
+	_, err := q.db.ExecContext(ctx, softDeleteEntryByPath, arg.DeletedAt, arg.Path)
 	return err
 }
 
-const updateYamlById = `-- name: UpdateYamlById :exec
+const updateAnswersByID = `-- name: UpdateAnswersByID :exec
 UPDATE entries
-SET yaml = ?
+SET answers = ?
 WHERE id = ?
 `
 
-type UpdateYamlByIdParams struct {
-	Yaml sql.NullString
-	ID   int64
+type UpdateAnswersByIDParams struct {
+	Answers sql.NullString
+	ID      int64
 }
 
-func (q *Queries) UpdateYamlById(ctx context.Context, arg UpdateYamlByIdParams) error {
-	_, err := q.db.ExecContext(ctx, updateYamlById, arg.Yaml, arg.ID)
+func (q *Queries) UpdateAnswersByID(ctx context.Context, arg UpdateAnswersByIDParams) error {
+	_, err := q.db.ExecContext(ctx, updateAnswersByID, arg.Answers, arg.ID)
 	return err
 }
 
-const updateYamlByPath = `-- name: UpdateYamlByPath :exec
+const updateAssigneeByPath = `-- name: UpdateAssigneeByPath :exec
 UPDATE entries
-SET yaml = ?
-WHERE path = ?
+SET assignee_id = ?
+WHERE path = ? AND deleted_at IS NULL
 `
 
-type UpdateYamlByPathParams struct {
-	Yaml sql.NullString
+type UpdateAssigneeByPathParams struct {
+	AssigneeID sql.NullInt64
+	Path       string
+}
+
+func (q *Queries) UpdateAssigneeByPath(ctx context.Context, arg UpdateAssigneeByPathParams) error {
+	_, err := q.db.ExecContext(ctx, updateAssigneeByPath, arg.AssigneeID, arg.Path)
+	return err
+}
+
+const updateCustomFieldByID = `-- name: UpdateCustomFieldByID :exec
+UPDATE custom_fields
+SET key = ?, desc = ?, position = ?, removed_at = NULL
+WHERE id = ?
+`
+
+type UpdateCustomFieldByIDParams struct {
+	Key      string
+	Desc     string
+	Position int64
+	ID       int64
+}
+
+func (q *Queries) UpdateCustomFieldByID(ctx context.Context, arg UpdateCustomFieldByIDParams) error {
+	_, err := q.db.ExecContext(ctx, updateCustomFieldByID,
+		arg.Key,
+		arg.Desc,
+		arg.Position,
+		arg.ID,
+	)
+	return err
+}
+
+const updateDueByPath = `-- name: UpdateDueByPath :exec
+UPDATE entries
+SET due = ?
+WHERE path = ? AND deleted_at IS NULL
+`
+
+type UpdateDueByPathParams struct {
+	Due  sql.NullInt64
 	Path string
 }
 
-func (q *Queries) UpdateYamlByPath(ctx context.Context, arg UpdateYamlByPathParams) error {
-	_, err := q.db.ExecContext(ctx, updateYamlByPath, arg.Yaml, arg.Path)
+func (q *Queries) UpdateDueByPath(ctx context.Context, arg UpdateDueByPathParams) error {
+	_, err := q.db.ExecContext(ctx, updateDueByPath, arg.Due, arg.Path)
+	return err
+}
+
+const updateProgressByID = `-- name: UpdateProgressByID :exec
+UPDATE entries
+SET progress_checked = ?, progress_total = ?, required_checked = ?, required_total = ?
+WHERE id = ?
+`
+
+type UpdateProgressByIDParams struct {
+	ProgressChecked int64
+	ProgressTotal   int64
+	RequiredChecked int64
+	RequiredTotal   int64
+	ID              int64
+}
+
+func (q *Queries) UpdateProgressByID(ctx context.Context, arg UpdateProgressByIDParams) error {
+	_, err := q.db.ExecContext(ctx, updateProgressByID,
+		arg.ProgressChecked,
+		arg.ProgressTotal,
+		arg.RequiredChecked,
+		arg.RequiredTotal,
+		arg.ID,
+	)
+	return err
+}
+
+const updateStatusByPath = `-- name: UpdateStatusByPath :exec
+UPDATE entries
+SET status = ?
+WHERE path = ? AND deleted_at IS NULL
+`
+
+type UpdateStatusByPathParams struct {
+	Status string
+	Path   string
+}
+
+func (q *Queries) UpdateStatusByPath(ctx context.Context, arg UpdateStatusByPathParams) error {
+	_, err := q.db.ExecContext(ctx, updateStatusByPath, arg.Status, arg.Path)
+	return err
+}
+
+const updateTemplateById = `-- name: UpdateTemplateById :exec
+UPDATE templates SET empty_yaml = ?, file = ?, due_in = ?, due_field = ?, case_field = ? WHERE id = ?
+`
+
+type UpdateTemplateByIdParams struct {
+	EmptyYaml sql.NullString
+	File      sql.NullString
+	DueIn     sql.NullString
+	DueField  sql.NullString
+	CaseField sql.NullString
+	ID        int64
+}
+
+func (q *Queries) UpdateTemplateById(ctx context.Context, arg UpdateTemplateByIdParams) error {
+	_, err := q.db.ExecContext(ctx, updateTemplateById,
+		arg.EmptyYaml,
+		arg.File,
+		arg.DueIn,
+		arg.DueField,
+		arg.CaseField,
+		arg.ID,
+	)
 	return err
 }
@@ -0,0 +1,64 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Full-text search, see migrations/0009_search.sql.
+// This file is not generated by sqlc: sqlc can't resolve 'MATCH' on the name of an fts5 table.
+// Hits are wrapped in char(2) and char(3), so they can be highlighted after escaping.
+const searchEntries = `SELECT
+    entries.path,
+    entries.status,
+    highlight(entries_search, 0, char(2), char(3)) AS data,
+    highlight(entries_search, 1, char(2), char(3)) AS template,
+    snippet(entries_search, 2, char(2), char(3), '…', 12) AS answers
+FROM entries_search
+JOIN entries ON entries.id = entries_search.rowid
+WHERE entries_search MATCH ?1 AND entries.deleted_at IS NULL
+ORDER BY entries_search.rank
+LIMIT ?2
+`
+
+type SearchEntriesParams struct {
+	Query string
+	Limit int64
+}
+
+type SearchEntriesRow struct {
+	Path     string
+	Status   string
+	Data     sql.NullString
+	Template sql.NullString
+	Answers  sql.NullString
+}
+
+func (q *Queries) SearchEntries(ctx context.Context, arg SearchEntriesParams) ([]SearchEntriesRow, error) {
+	rows, err := q.db.QueryContext(ctx, searchEntries, arg.Query, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchEntriesRow
+	for rows.Next() {
+		var i SearchEntriesRow
+		if err := rows.Scan(
+			&i.Path,
+			&i.Status,
+			&i.Data,
+			&i.Template,
+			&i.Answers,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
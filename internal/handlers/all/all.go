@@ -1,12 +1,9 @@
 package all
 
 import (
-	"context"
 	"database/sql"
-	"encoding/json"
 	"log"
 	"net/http"
-	"time"
 
 	"github.com/gorilla/mux"
 
@@ -30,74 +27,92 @@ func (h *AllHandler) New(srv *server.Server){
 // Sets /delete and all subroutes
 func (h *AllHandler) Routes(){
 	h.Router.HandleFunc("/all", h.Display).Methods("GET")
+	h.Router.HandleFunc("/all/entries", h.Entries).Methods("GET")
+	h.Router.HandleFunc("/mine", h.Mine).Methods("GET")
 }
 
-// Return rendered html for GET to /delete
+// Return rendered html for GET to /all
 func (h *AllHandler) Display(w http.ResponseWriter, r *http.Request){
 	ctx := r.Context()
 	var templates = []string{
 		"all/templates/all.html",
-		"all/templates/entries.html",
+		"browser.html",
+		"due.html",
+		"progress.html",
 		"nav.html",
 		"header.html",
 	}
   tmpl := handlers.LoadTemplates(templates)
-	var view []handlers.EntryView	
-	query := database.New(h.DB)
-	all, err := query.GetAllEntriesPlusTemplateName(ctx)
-	for _, a := range all{
-		tmp := h.ViewForTemplate(ctx, a)
-		view = append(view, tmp)
+	browser, err := h.browse(r)
+	if err != nil{
+		msg := "Couldn't load the entries."
+		log.Printf("%s\n Error: %v\n", msg, err)
+		http.Error(w, msg, http.StatusInternalServerError)
+		return
+	}
+	options, err := handlers.LoadBrowserOptions(ctx, h.DB)
+	if err != nil{
+		log.Printf("Couldn't load the options of the list.\n Error: %v\n", err)
 	}
 	err = tmpl.Execute(w, map[string]any{
-		"Entries": view,
+		"Browser": browser,
+		"Options": options,
   })
 
   if err != nil {
     http.Error(w, err.Error(), http.StatusInternalServerError)
-    log.Fatalf("%q \n", err)
   }
 }
 
-// TODO: Get the 'Template Name' from the template_id found in the database entry. Right now the information is redundant...
-// Maybe connect the template_name and template_id over a JOIN()?
-func (h *AllHandler) ViewForTemplate(ctx context.Context, entry database.GetAllEntriesPlusTemplateNameRow) handlers.EntryView{
-		var dataMap map[string]string
-		err := json.Unmarshal([]byte(entry.Data), &dataMap)
-		if err != nil{
-			log.Fatalf("Error while unmarshaling json.\n Error: %q \n", err)
-		}
-		var length int = len(dataMap)
-		var viewMap []handlers.DescValueView = make([]handlers.DescValueView, length)
-		query := database.New(h.DB)
-		custom_fields, err := query.GetCustomFieldsByTemplateName(ctx, entry.TemplateName)
-		var count int = 0
-		// Use the order from the database-table 'custom_fields'
-		for _, field := range custom_fields{
-			if val, ok := dataMap[field.Key];ok{
-				viewMap[count] = handlers.DescValueView{
-					Desc: field.Desc, 
-					Value: val, 
-					Key: field.Key,
-				}
-			}else{
-				log.Fatalf("Key '%s' not found in dataMap: '%q' \n",field.Key,dataMap)
-			}
-			count += 1
-		}
-		var t time.Time
-		if entry.Date.Valid{
-			t = time.Unix(entry.Date.Int64,0)		
-		}else{
-			t = time.Time{}
-		}
-		return handlers.EntryView{
-			Date: t.Format("02.01.2006 15:04:05"),
-			Path: entry.Path,
-			Data: viewMap,
-		}
+// Returns only the list of entries.
+// Used for paging, sorting and filtering and to refresh the list after a bulk action.
+func (h *AllHandler) Entries(w http.ResponseWriter, r *http.Request){
+	tmpl := handlers.LoadTemplates([]string{"browser.html", "due.html", "progress.html"})
+	browser, err := h.browse(r)
+	if err != nil{
+		msg := "Couldn't load the entries."
+		log.Printf("%s\n Error: %v\n", msg, err)
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	handlers.PushBrowserURL(w, r, browser)
+	err = tmpl.ExecuteTemplate(w, "browser.html", browser)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
 }
 
+func (h *AllHandler) browse(r *http.Request) (handlers.BrowserView, error){
+	browser, err := handlers.Browse(r.Context(), h.DB, handlers.ParseBrowserQuery(r.URL.Query()))
+	browser.Page = "/all"
+	browser.Endpoint = "/all/entries"
+	return browser, err
+}
+
+// Shows the open entries of a single person.
+// The person is remembered in the browser.
+func (h *AllHandler) Mine(w http.ResponseWriter, r *http.Request){
+	var templates = []string{
+		"all/templates/mine.html",
+		"nav.html",
+		"header.html",
+	}
+	tmpl := handlers.LoadTemplates(templates)
+	people, err := database.New(h.DB).GetAllPeople(r.Context())
+	if err != nil{
+		msg := "Couldn't load all people."
+		log.Printf("%s\n Error: %v\n", msg, err)
+		http.Error(w, msg, http.StatusInternalServerError)
+		return
+	}
+	err = tmpl.Execute(w, map[string]any{
+		"People": people,
+		"Selected": r.URL.Query().Get("person"),
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
 
 func init(){
 	handlers.RegisterHandler(&AllHandler{})
@@ -0,0 +1,108 @@
+package backup
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/hmaier-dev/checklist-tool/internal/backup"
+	"github.com/hmaier-dev/checklist-tool/internal/handlers"
+	"github.com/hmaier-dev/checklist-tool/internal/server"
+)
+
+// Downloads and restores a backup of the whole instance
+type BackupHandler struct {
+	Router *mux.Router
+	DB     *sql.DB
+}
+
+var _ handlers.DisplayHandler = (*BackupHandler)(nil)
+
+func (h *BackupHandler) New(srv *server.Server) {
+	h.Router = srv.Router
+	h.DB = srv.DB
+}
+
+// Sets /backup and all subroutes
+func (h *BackupHandler) Routes() {
+	sub := h.Router.PathPrefix("/backup").Subrouter()
+	sub.HandleFunc("", h.Display).Methods("GET")
+	sub.HandleFunc("/download", h.Download).Methods("GET")
+	sub.HandleFunc("/restore", h.Restore).Methods("POST")
+}
+
+var templates = []string{
+	"backup/templates/backup.html",
+	"nav.html",
+	"header.html",
+}
+
+// Return rendered html for GET to /backup
+func (h *BackupHandler) Display(w http.ResponseWriter, r *http.Request) {
+	h.render(w, map[string]any{})
+}
+
+func (h *BackupHandler) render(w http.ResponseWriter, data map[string]any) {
+	tmpl := handlers.LoadTemplates(templates)
+	if err := tmpl.Execute(w, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// Writes all templates, entries, people and views as one archive
+func (h *BackupHandler) Download(w http.ResponseWriter, r *http.Request) {
+	archive, err := backup.Export(r.Context(), h.DB)
+	if err != nil {
+		msg := "Couldn't export the backup."
+		log.Printf("%s\n Error: %v\n", msg, err)
+		http.Error(w, msg, http.StatusInternalServerError)
+		return
+	}
+	name := fmt.Sprintf("checklist-tool_%s.json.gz", time.Now().Format("20060102"))
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", name))
+	if err := backup.Write(w, archive); err != nil {
+		log.Printf("Couldn't write the backup.\n Error: %v\n", err)
+	}
+}
+
+// Restores an uploaded archive with the mode chosen in the form
+func (h *BackupHandler) Restore(w http.ResponseWriter, r *http.Request) {
+	mode, err := backup.ParseMode(r.FormValue("mode"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "Es wurde keine Datei hochgeladen.", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+	archive, err := backup.Read(file)
+	if err == nil {
+		var report backup.Report
+		report, err = backup.Restore(r.Context(), h.DB, archive, mode)
+		if err == nil {
+			h.render(w, map[string]any{"Report": report, "Mode": mode})
+			return
+		}
+	}
+	if errors.Is(err, backup.ErrInvalid) {
+		w.WriteHeader(http.StatusBadRequest)
+		h.render(w, map[string]any{"Error": err.Error()})
+		return
+	}
+	msg := "Couldn't restore the backup."
+	log.Printf("%s\n Error: %v\n", msg, err)
+	http.Error(w, msg, http.StatusInternalServerError)
+}
+
+func init() {
+	handlers.RegisterHandler(&BackupHandler{})
+}
@@ -0,0 +1,367 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hmaier-dev/checklist-tool/internal/database"
+)
+
+// The entries-browser is the paginated list of entries on /all and /delete.
+// Filtering, sorting and paging is done by the database (see BrowseEntries in query.sql),
+// so only a single page of entries is loaded.
+
+// Entries per page, when the url doesn't say otherwise
+const DefaultPageSize = 25
+
+var PageSizes = []int{25, 50, 100}
+
+// State of the entries-browser.
+// It is read from the query string, so every page of a list has its own url.
+type BrowserQuery struct {
+	Template string
+	// Creation date range as 'yyyy-mm-dd', both days are included
+	From string
+	To   string
+	// Id of a person, "none" or empty for everybody
+	Assignee string
+	// One of DueFilters
+	Due string
+	// Hides finished entries
+	Open bool
+	// Value of Statuses or empty for every status
+	Status string
+	// Key of a custom field, whose value has to contain Value
+	Field string
+	Value string
+	// "", "template", "due", "progress" or "field:<key>" for a custom field.
+	// An empty value sorts by the creation date.
+	Sort string
+	// "asc" or "desc". When empty, the creation date is sorted descending
+	// and everything else ascending.
+	Order string
+	Page  int
+	Size  int
+	// Values of BrowserColumns, which aren't shown
+	Hide []string
+}
+
+// Columns of the list, which can be hidden
+var BrowserColumns = []Status{
+	{Value: "status", Label: "Status"},
+	{Value: "progress", Label: "Fortschritt"},
+	{Value: "assignee", Label: "Zuständig"},
+	{Value: "due", Label: "Fällig"},
+	{Value: "date", Label: "Erstellungsdatum"},
+}
+
+func ParseBrowserQuery(v url.Values) BrowserQuery {
+	q := BrowserQuery{
+		Template: v.Get("template"),
+		From:     v.Get("from"),
+		To:       v.Get("to"),
+		Assignee: v.Get("assignee"),
+		Due:      v.Get("filter"),
+		Open:     v.Get("open") == "true",
+		Status:   v.Get("status"),
+		Field:    v.Get("field"),
+		Value:    v.Get("value"),
+		Sort:     v.Get("sort"),
+		Order:    v.Get("order"),
+		Page:     1,
+		Size:     DefaultPageSize,
+	}
+	if page, err := strconv.Atoi(v.Get("page")); err == nil && page > 0 {
+		q.Page = page
+	}
+	if size, err := strconv.Atoi(v.Get("size")); err == nil {
+		for _, s := range PageSizes {
+			if s == size {
+				q.Size = size
+			}
+		}
+	}
+	// Keeps the order of BrowserColumns, so the same columns give the same url
+	for _, c := range BrowserColumns {
+		if slices.Contains(v["hide"], c.Value) {
+			q.Hide = append(q.Hide, c.Value)
+		}
+	}
+	return q
+}
+
+// Reports whether a column of BrowserColumns is shown
+func (q BrowserQuery) Shows(column string) bool {
+	return !slices.Contains(q.Hide, column)
+}
+
+// Returns the query string of the browser. Default values are left out.
+func (q BrowserQuery) Encode() string {
+	v := url.Values{}
+	set := func(key, value string) {
+		if value != "" {
+			v.Set(key, value)
+		}
+	}
+	set("template", q.Template)
+	set("from", q.From)
+	set("to", q.To)
+	set("assignee", q.Assignee)
+	set("filter", q.Due)
+	if q.Open {
+		v.Set("open", "true")
+	}
+	set("status", q.Status)
+	set("field", q.Field)
+	set("value", q.Value)
+	set("sort", q.Sort)
+	set("order", q.Order)
+	if q.Page > 1 {
+		v.Set("page", strconv.Itoa(q.Page))
+	}
+	if q.Size != DefaultPageSize {
+		v.Set("size", strconv.Itoa(q.Size))
+	}
+	for _, c := range q.Hide {
+		v.Add("hide", c)
+	}
+	return v.Encode()
+}
+
+// Query string of another page of the same list
+func (q BrowserQuery) WithPage(page int) string {
+	q.Page = page
+	return q.Encode()
+}
+
+func (q BrowserQuery) Descending() bool {
+	if q.Order == "" {
+		return q.Sort == ""
+	}
+	return q.Order == "desc"
+}
+
+// SQLite has no booleans, the flags of BrowseEntries are integers
+func boolInt(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// Converts the query into the parameters of BrowseEntries and CountBrowseEntries
+func (q BrowserQuery) params(now time.Time) (database.BrowseEntriesParams, error) {
+	p := database.BrowseEntriesParams{
+		Template:    q.Template,
+		Open:        boolInt(q.Open),
+		Status:      q.Status,
+		Assignee:    q.Assignee,
+		DueFilter:   q.Due,
+		Now:         now.Unix(),
+		Soon:        now.Add(DueSoon).Unix(),
+		FilterField: q.Field,
+		FilterValue: escapeLike(q.Value),
+		Sort:        q.Sort,
+		Descending:  boolInt(q.Descending()),
+		Limit:       int64(q.Size),
+		Offset:      int64((q.Page - 1) * q.Size),
+	}
+	if key, ok := strings.CutPrefix(q.Sort, "field:"); ok {
+		p.Sort = "field"
+		p.Field = key
+	}
+	if q.From != "" {
+		t, err := ParseDate(q.From)
+		if err != nil {
+			return p, err
+		}
+		// ParseDate returns the end of the day
+		p.DateFrom = t.AddDate(0, 0, -1).Add(time.Second).Unix()
+	}
+	if q.To != "" {
+		t, err := ParseDate(q.To)
+		if err != nil {
+			return p, err
+		}
+		p.DateTo = t.Unix()
+	}
+	return p, nil
+}
+
+// Makes '%' and '_' in the filter match themselves, the queries use ESCAPE '\'
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
+
+// A single page of the entries-browser as rendered by browser.html
+type BrowserView struct {
+	Query   BrowserQuery
+	Entries []EntryView
+	Total   int
+	Pages   int
+	// Route of the whole page, e.g. /all
+	Page string
+	// Route returning only the list, e.g. /all/entries
+	Endpoint string
+	// Shows a button to move an entry into the trash
+	Delete bool
+}
+
+func (v BrowserView) HasPrev() bool { return v.Query.Page > 1 }
+func (v BrowserView) HasNext() bool { return v.Query.Page < v.Pages }
+func (v BrowserView) PrevPage() int { return v.Query.Page - 1 }
+func (v BrowserView) NextPage() int { return v.Query.Page + 1 }
+
+// Link to another page of the list
+func (v BrowserView) PageURL(page int) string {
+	return v.Page + "?" + v.Query.WithPage(page)
+}
+
+// Same as PageURL, but only returns the list
+func (v BrowserView) EndpointURL(page int) string {
+	return v.Endpoint + "?" + v.Query.WithPage(page)
+}
+
+// Loads a single page of entries.
+// The values of the whole page are loaded at once instead of per entry.
+func Browse(ctx context.Context, db *sql.DB, query BrowserQuery) (BrowserView, error) {
+	view := BrowserView{Query: query}
+	params, err := query.params(time.Now())
+	if err != nil {
+		return view, err
+	}
+	q := database.New(db)
+	total, err := q.CountBrowseEntries(ctx, database.CountBrowseEntriesParams{
+		Template:    params.Template,
+		DateFrom:    params.DateFrom,
+		DateTo:      params.DateTo,
+		Open:        params.Open,
+		Assignee:    params.Assignee,
+		DueFilter:   params.DueFilter,
+		Now:         params.Now,
+		Soon:        params.Soon,
+		FilterField: params.FilterField,
+		FilterValue: params.FilterValue,
+		Status:      params.Status,
+	})
+	if err != nil {
+		return view, fmt.Errorf("couldn't count the entries: %w", err)
+	}
+	view.Total = int(total)
+	view.Pages = (view.Total + query.Size - 1) / query.Size
+	rows, err := q.BrowseEntries(ctx, params)
+	if err != nil {
+		return view, fmt.Errorf("couldn't load the entries: %w", err)
+	}
+	ids := make([]int64, len(rows))
+	for i, row := range rows {
+		ids[i] = row.ID
+	}
+	values, err := LoadValues(ctx, q, ids...)
+	if err != nil {
+		return view, fmt.Errorf("couldn't load the values: %w", err)
+	}
+	for _, row := range rows {
+		view.Entries = append(view.Entries, ViewForEntryWithValues(values[row.ID], database.GetAllEntriesPlusTemplateNameRow(row)))
+	}
+	if err := SetAssignees(ctx, q, view.Entries); err != nil {
+		return view, fmt.Errorf("couldn't set the assignees: %w", err)
+	}
+	return view, nil
+}
+
+// Paths of all entries matching the query, sorted like the list.
+// Paging is ignored, at most 'limit' paths are returned.
+func BrowsePaths(ctx context.Context, db *sql.DB, query BrowserQuery, limit int) ([]string, error) {
+	params, err := query.params(time.Now())
+	if err != nil {
+		return nil, err
+	}
+	params.Limit = int64(limit)
+	params.Offset = 0
+	rows, err := database.New(db).BrowseEntries(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't load the entries: %w", err)
+	}
+	paths := make([]string, len(rows))
+	for i, row := range rows {
+		paths[i] = row.Path
+	}
+	return paths, nil
+}
+
+// Values for the select boxes above the list
+type BrowserOptions struct {
+	Templates []database.Template
+	People    []database.Person
+	Sorts     []Status
+	// Custom fields, which can be filtered by
+	Fields     []Status
+	DueFilters []Status
+	Statuses   []Status
+	PageSizes  []int
+	Columns    []Status
+}
+
+// Besides the fixed sorts, every custom field can be sorted and filtered by.
+// Fields with the same key in different templates are offered once.
+func LoadBrowserOptions(ctx context.Context, db *sql.DB) (BrowserOptions, error) {
+	q := database.New(db)
+	templates, err := q.GetAllTemplates(ctx)
+	if err != nil {
+		return BrowserOptions{}, err
+	}
+	people, err := q.GetAllPeople(ctx)
+	if err != nil {
+		return BrowserOptions{}, err
+	}
+	fields, err := q.GetAllCustomFields(ctx)
+	if err != nil {
+		return BrowserOptions{}, err
+	}
+	sorts := append([]Status{}, Sorts...)
+	sorts = append(sorts, Status{Value: "template", Label: "Checkliste"})
+	var filters []Status
+	seen := map[string]bool{}
+	for _, f := range fields {
+		if seen[f.Key] {
+			continue
+		}
+		seen[f.Key] = true
+		sorts = append(sorts, Status{Value: "field:" + f.Key, Label: f.Desc})
+		filters = append(filters, Status{Value: f.Key, Label: f.Desc})
+	}
+	return BrowserOptions{
+		Templates:  templates,
+		People:     people,
+		Sorts:      sorts,
+		Fields:     filters,
+		DueFilters: DueFilters,
+		Statuses:   Statuses,
+		PageSizes:  PageSizes,
+		Columns:    BrowserColumns,
+	}, nil
+}
+
+// Keeps the url in the browser in sync with the list, when the list was loaded by htmx.
+// Lists shown on other pages, e.g. /mine, don't change the url.
+func PushBrowserURL(w http.ResponseWriter, r *http.Request, view BrowserView) {
+	current, err := url.Parse(r.Header.Get("HX-Current-URL"))
+	if err != nil || current.Path != view.Page {
+		return
+	}
+	target := view.Page
+	if query := view.Query.Encode(); query != "" {
+		target += "?" + query
+	}
+	w.Header().Set("HX-Push-Url", target)
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestBrowserQuery(t *testing.T) {
+	v, _ := url.ParseQuery("template=setup+devices&sort=field:imei&page=3&size=50&open=true&status=done&field=typ&value=iPhone")
+	q := ParseBrowserQuery(v)
+	if q.Page != 3 || q.Size != 50 || !q.Open || q.Sort != "field:imei" || q.Field != "typ" || q.Value != "iPhone" || q.Status != "done" {
+		t.Fatalf("unexpected query: %+v", q)
+	}
+	// Unknown page sizes fall back to the default
+	if q := ParseBrowserQuery(url.Values{"size": {"7"}, "page": {"-1"}}); q.Size != DefaultPageSize || q.Page != 1 {
+		t.Errorf("expected defaults, got %+v", q)
+	}
+	if got, want := q.WithPage(1), "field=typ&open=true&size=50&sort=field%3Aimei&status=done&template=setup+devices&value=iPhone"; got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+	if again := ParseBrowserQuery(mustParse(t, q.Encode())); !reflect.DeepEqual(again, q) {
+		t.Errorf("expected %+v after encoding, got %+v", q, again)
+	}
+}
+
+func TestBrowserQueryHide(t *testing.T) {
+	q := ParseBrowserQuery(mustParse(t, "hide=date&hide=unknown&hide=status"))
+	if !reflect.DeepEqual(q.Hide, []string{"status", "date"}) {
+		t.Fatalf("expected known columns in order, got %q", q.Hide)
+	}
+	if q.Shows("status") || !q.Shows("due") {
+		t.Errorf("unexpected columns shown for %q", q.Hide)
+	}
+	if got, want := q.Encode(), "hide=status&hide=date"; got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+}
+
+func TestBrowserQueryDescending(t *testing.T) {
+	tests := []struct {
+		sort, order string
+		want        bool
+	}{
+		{"", "", true},
+		{"due", "", false},
+		{"", "asc", false},
+		{"template", "desc", true},
+	}
+	for _, tt := range tests {
+		q := BrowserQuery{Sort: tt.sort, Order: tt.order}
+		if got := q.Descending(); got != tt.want {
+			t.Errorf("sort '%s', order '%s': expected %v, got %v", tt.sort, tt.order, tt.want, got)
+		}
+	}
+}
+
+func TestBrowserQueryEscape(t *testing.T) {
+	p, err := BrowserQuery{Field: "typ", Value: `100%_a\b`}.params(time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.FilterValue != `100\%\_a\\b` {
+		t.Errorf("expected the wildcards to be escaped, got %s", p.FilterValue)
+	}
+}
+
+func mustParse(t *testing.T, query string) url.Values {
+	t.Helper()
+	v, err := url.ParseQuery(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
@@ -0,0 +1,153 @@
+package bulk
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hmaier-dev/checklist-tool/internal/handlers"
+	"github.com/hmaier-dev/checklist-tool/internal/handlers/checklist"
+	"github.com/hmaier-dev/checklist-tool/internal/pdf"
+)
+
+// Entries, which can be exported by a single request to /bulk/export-all
+const maxBatch = 500
+
+// Pdfs rendered at the same time
+const renderWorkers = 4
+
+// Rendering many entries takes longer than the write timeout of the server
+const batchTimeout = 15 * time.Minute
+
+type rendered struct {
+	path string
+	name string
+	data []byte
+	err  error
+}
+
+// Exports every entry matching the filter of /all, which is passed as query string.
+// ?format=zip returns a zip, everything else one merged pdf with a table of contents.
+func (h *BulkHandler) ExportAll(w http.ResponseWriter, r *http.Request) {
+	query := handlers.ParseBrowserQuery(r.URL.Query())
+	paths, err := handlers.BrowsePaths(r.Context(), h.DB, query, maxBatch+1)
+	if err != nil {
+		msg := "Couldn't load the entries."
+		log.Printf("%s\n Error: %v\n", msg, err)
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	if len(paths) > maxBatch {
+		http.Error(w, fmt.Sprintf("Es können höchstens %d Einträge auf einmal exportiert werden. Bitte den Filter einschränken.", maxBatch), http.StatusUnprocessableEntity)
+		return
+	}
+	// Not every ResponseWriter supports deadlines, then the server's timeout stays
+	http.NewResponseController(w).SetWriteDeadline(time.Now().Add(batchTimeout))
+	h.export(w, r, paths, r.URL.Query().Get("format"), true)
+}
+
+// Renders the entries through the same path as /checklist/print,
+// at most renderWorkers at once. The results keep the order of 'paths'.
+func (h *BulkHandler) renderAll(r *http.Request, paths []string) []rendered {
+	results := make([]rendered, len(paths))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range min(renderWorkers, len(paths)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				name, data, err := checklist.RenderPDF(r, h.DB, h.PDF, h.Archive, paths[i], false)
+				results[i] = rendered{path: paths[i], name: name, data: data, err: err}
+			}
+		}()
+	}
+	for i := range paths {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return results
+}
+
+// Row of the table of contents
+type tocEntry struct {
+	Nr   int
+	Name string
+	// 0, when the pages of the pdfs couldn't be counted
+	Page int
+}
+
+// Renders the first pages of a merged export, which list the entries and the page each starts on
+func (h *BulkHandler) tableOfContents(r *http.Request, names []string, pdfs [][]byte) ([]byte, error) {
+	counts := make([]int, len(pdfs))
+	known := true
+	for i, data := range pdfs {
+		n, err := pdf.PageCount(data)
+		if err != nil {
+			// Without the pages of every entry, no page number would be right
+			log.Printf("Couldn't count the pages of '%s', the table of contents has no page numbers.\n Error: %v\n", names[i], err)
+			known = false
+			break
+		}
+		counts[i] = n
+	}
+	render := func(tocPages int) ([]byte, error) {
+		entries := make([]tocEntry, len(names))
+		page := tocPages + 1
+		for i, name := range names {
+			entries[i].Nr = i + 1
+			entries[i].Name = strings.TrimSuffix(name, ".pdf")
+			if known {
+				entries[i].Page = page
+				page += counts[i]
+			}
+		}
+		return h.renderTOC(r, entries)
+	}
+	toc, err := render(1)
+	if err != nil {
+		return nil, err
+	}
+	if !known {
+		return toc, nil
+	}
+	// Long lists need more than one page, which moves all entries back
+	n, err := pdf.PageCount(toc)
+	if err != nil {
+		log.Printf("Couldn't count the pages of the table of contents.\n Error: %v\n", err)
+	} else if n > 1 {
+		return render(n)
+	}
+	return toc, nil
+}
+
+func (h *BulkHandler) renderTOC(r *http.Request, entries []tocEntry) ([]byte, error) {
+	tmpl := handlers.LoadTemplates([]string{"bulk/templates/toc.html"})
+	date := time.Now().Format("02.01.2006, 15:04:05")
+	var buf bytes.Buffer
+	err := tmpl.Execute(&buf, map[string]any{
+		"Entries": entries,
+		"Date":    date,
+	})
+	if err != nil {
+		return nil, err
+	}
+	doc := pdf.Document{
+		Name: "Inhaltsverzeichnis.pdf",
+		HTML: buf.Bytes(),
+		Date: date,
+	}
+	for _, e := range entries {
+		field := pdf.Field{Desc: e.Name}
+		if e.Page > 0 {
+			field.Value = fmt.Sprintf("Seite %d", e.Page)
+		}
+		doc.Fields = append(doc.Fields, field)
+	}
+	return h.PDF.Render(r.Context(), doc)
+}
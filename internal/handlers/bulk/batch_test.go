@@ -0,0 +1,80 @@
+//go:build sqlite_fts5
+
+package bulk
+
+import (
+	"archive/zip"
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/hmaier-dev/checklist-tool/internal/database/dbtest"
+	"github.com/hmaier-dev/checklist-tool/internal/pdf"
+	"github.com/hmaier-dev/checklist-tool/internal/pdf/gotenbergtest"
+)
+
+// Entries read by BrowseEntries and RenderPDF
+const testData = `
+INSERT INTO templates (id, name, empty_yaml) VALUES (1, 'geräte', '- task: "Auspacken"' || char(10) || '  checked: false');
+INSERT INTO custom_fields (id, template_id, key, desc) VALUES (1, 1, 'name', 'Name');
+INSERT INTO pdf_name_schema (template_id, value) VALUES (1, 'name');
+INSERT INTO entries (id, template_id, path, date, status) VALUES
+  (1, 1, 'a', 1, 'done'), (2, 1, 'b', 2, 'open'), (3, 1, 'c', 3, 'done');
+INSERT INTO entry_values VALUES (1, 1, 'Anna'), (2, 1, 'Ben'), (3, 1, 'Anna');
+`
+
+func exportAll(t *testing.T, h *BulkHandler, query string) *httptest.ResponseRecorder {
+	t.Helper()
+	w := httptest.NewRecorder()
+	h.ExportAll(w, httptest.NewRequest("GET", "/bulk/export-all?"+query, nil))
+	return w
+}
+
+func TestExportAll(t *testing.T) {
+	// The templates are loaded relative to the root of the repository
+	t.Chdir("../../..")
+	db := dbtest.Open(t)
+	if _, err := db.Exec(testData); err != nil {
+		t.Fatal(err)
+	}
+	fake := gotenbergtest.NewServer(t)
+	h := &BulkHandler{DB: db, PDF: &pdf.Gotenberg{URL: fake.URL, Retries: 1}}
+
+	w := exportAll(t, h, "status=done&format=zip")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+	}
+	// Newest first like the list, the same name is numbered
+	if !slices.Equal(names, []string{"Anna.pdf", "Anna_2.pdf"}) {
+		t.Errorf("unexpected files %q", names)
+	}
+
+	w = exportAll(t, h, "status=done&format=pdf")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body)
+	}
+	body := w.Body.String()
+	toc := bytes.Index(w.Body.Bytes(), []byte("Inhaltsverzeichnis"))
+	entry := bytes.Index(w.Body.Bytes(), []byte("Auspacken"))
+	if toc < 0 || entry < 0 || toc > entry {
+		t.Errorf("expected the table of contents in front of the entries, got %q", body)
+	}
+	if bytes.Contains(w.Body.Bytes(), []byte("Ben")) {
+		t.Errorf("expected only finished entries")
+	}
+
+	w = exportAll(t, h, "template=unknown")
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected 422 for an empty filter, got %d", w.Code)
+	}
+}
@@ -0,0 +1,418 @@
+package bulk
+
+import (
+	"archive/zip"
+	"bytes"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"gopkg.in/yaml.v3"
+
+	"github.com/hmaier-dev/checklist-tool/internal/archive"
+	"github.com/hmaier-dev/checklist-tool/internal/database"
+	"github.com/hmaier-dev/checklist-tool/internal/handlers"
+	"github.com/hmaier-dev/checklist-tool/internal/handlers/checklist"
+	"github.com/hmaier-dev/checklist-tool/internal/live"
+	"github.com/hmaier-dev/checklist-tool/internal/pdf"
+	"github.com/hmaier-dev/checklist-tool/internal/server"
+)
+
+// Runs actions on multiple entries selected on /all or /delete
+type BulkHandler struct {
+	Router *mux.Router
+	DB     *sql.DB
+	PDF    pdf.Renderer
+	// Keeps every generated pdf, nil when archiving is off
+	Archive *archive.Archive
+	// Open pages of the changed entries are updated like after a change on /checklist
+	Live *live.Hub
+}
+
+var _ handlers.DisplayHandler = (*BulkHandler)(nil)
+
+func (h *BulkHandler) New(srv *server.Server) {
+	h.Router = srv.Router
+	h.DB = srv.DB
+	h.PDF = srv.PDF
+	h.Archive = srv.Archive
+	h.Live = srv.Live
+}
+
+// Sets /bulk and all subroutes
+func (h *BulkHandler) Routes() {
+	sub := h.Router.PathPrefix("/bulk").Subrouter()
+	sub.HandleFunc("/toolbar", h.Display).Methods("GET")
+	sub.HandleFunc("/delete", h.Delete).Methods("POST")
+	sub.HandleFunc("/status", h.Status).Methods("POST")
+	sub.HandleFunc("/check", h.Check).Methods("POST")
+	sub.HandleFunc("/export", h.Export).Methods("POST")
+	sub.HandleFunc("/export-all", h.ExportAll).Methods("GET")
+}
+
+// Outcome of an action for a single entry
+type ResultView struct {
+	Label   string
+	Path    string
+	Ok      bool
+	Message string
+}
+
+// Returned by an action, when it can't be applied to a single entry.
+// The other entries are still processed.
+type skipError string
+
+func (e skipError) Error() string { return string(e) }
+
+// Returns the toolbar, which is loaded by htmx into /all and /delete
+func (h *BulkHandler) Display(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	q := database.New(h.DB)
+	templates, err := q.GetAllTemplates(ctx)
+	if err != nil {
+		msg := "Couldn't load all templates."
+		log.Println(msg)
+		http.Error(w, msg, http.StatusInternalServerError)
+		return
+	}
+	// Every task of every template can be checked
+	type taskOption struct {
+		Task     string
+		Template string
+	}
+	var tasks []taskOption
+	for _, t := range templates {
+		var items []*checklist.Item
+		yaml.Unmarshal([]byte(t.EmptyYaml.String), &items)
+		for _, task := range flattenTasks(items) {
+			tasks = append(tasks, taskOption{Task: task, Template: t.Name})
+		}
+	}
+	tmpl := handlers.LoadTemplates([]string{"bulk/templates/toolbar.html"})
+	err = tmpl.ExecuteTemplate(w, "toolbar.html", map[string]any{
+		"Statuses": handlers.Statuses,
+		"Tasks":    tasks,
+		// Moving entries into the trash makes no sense on the trash page
+		"Delete": r.URL.Query().Get("delete") != "false",
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func flattenTasks(items []*checklist.Item) []string {
+	var result []string
+	for _, item := range items {
+		result = append(result, item.Task)
+		result = append(result, flattenTasks(item.Children)...)
+	}
+	return result
+}
+
+// Returns the selected paths without duplicates
+func selectedPaths(r *http.Request) []string {
+	r.ParseForm()
+	var paths []string
+	seen := make(map[string]bool)
+	for _, p := range r.Form["path"] {
+		if p == "" || seen[p] {
+			continue
+		}
+		seen[p] = true
+		paths = append(paths, p)
+	}
+	return paths
+}
+
+// Label for an entry in the result list built from 'tab_desc_schema'
+func entryLabel(r *http.Request, q *database.Queries, entry database.Entry) string {
+	schema, err := q.GetTabDescriptionsByTemplateID(r.Context(), entry.TemplateID)
+	if err != nil {
+		return entry.Path
+	}
+	values, err := handlers.LoadValues(r.Context(), q, entry.ID)
+	if err != nil {
+		return entry.Path
+	}
+	label := handlers.BuildTabDescription(schema, handlers.ValueMap(values[entry.ID]))
+	if label == "" {
+		return entry.Path
+	}
+	return label
+}
+
+// Runs 'action' for every selected entry inside one transaction.
+// A skipError is reported for the entry, every other error rolls back all changes.
+func (h *BulkHandler) inTx(r *http.Request, paths []string, action func(qtx *database.Queries, entry database.Entry) (string, error)) ([]ResultView, error) {
+	ctx := r.Context()
+	tx, err := h.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	qtx := database.New(h.DB).WithTx(tx)
+	var results []ResultView
+	for _, path := range paths {
+		entry, err := qtx.GetEntryByPath(ctx, path)
+		if err != nil {
+			results = append(results, ResultView{Label: path, Path: path, Message: "Eintrag nicht gefunden."})
+			continue
+		}
+		res := ResultView{Label: entryLabel(r, qtx, entry), Path: path}
+		msg, err := action(qtx, entry)
+		var skip skipError
+		switch {
+		case err == nil:
+			res.Ok = true
+			res.Message = msg
+		case errors.As(err, &skip):
+			res.Message = skip.Error()
+		default:
+			return nil, fmt.Errorf("entry '%s': %w", path, err)
+		}
+		results = append(results, res)
+	}
+	return results, tx.Commit()
+}
+
+// Renders the results of an action and tells the page to reload its entries
+func (h *BulkHandler) respond(w http.ResponseWriter, results []ResultView, err error) {
+	if err != nil {
+		log.Printf("Bulk action failed.\n Error: %v\n", err)
+		html := `<div class='text-red-700'>Ein Fehler ist aufgetreten. Es wurde nichts geändert.</div>`
+		w.Write([]byte(html))
+		return
+	}
+	tmpl := handlers.LoadTemplates([]string{"bulk/templates/result.html"})
+	// The lists on /all and /delete listen for this event
+	w.Header().Set("HX-Trigger", "entriesChanged")
+	err = tmpl.ExecuteTemplate(w, "result.html", map[string]any{
+		"Results": results,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func noSelection(w http.ResponseWriter) {
+	html := `<div class='text-red-700'>Es wurden keine Einträge ausgewählt.</div>`
+	w.Write([]byte(html))
+}
+
+// Moves all selected entries into the trash
+func (h *BulkHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	paths := selectedPaths(r)
+	if len(paths) == 0 {
+		noSelection(w)
+		return
+	}
+	now := time.Now().Unix()
+	results, err := h.inTx(r, paths, func(qtx *database.Queries, entry database.Entry) (string, error) {
+		err := qtx.SoftDeleteEntryByPath(r.Context(), database.SoftDeleteEntryByPathParams{
+			DeletedAt: sql.NullInt64{Valid: true, Int64: now},
+			Path:      entry.Path,
+		})
+		return "In den Papierkorb verschoben.", err
+	})
+	h.respond(w, results, err)
+}
+
+// Sets the same status for all selected entries
+func (h *BulkHandler) Status(w http.ResponseWriter, r *http.Request) {
+	paths := selectedPaths(r)
+	if len(paths) == 0 {
+		noSelection(w)
+		return
+	}
+	status := r.FormValue("status")
+	if !handlers.IsValidStatus(status) {
+		http.Error(w, "Unknown status.", http.StatusBadRequest)
+		return
+	}
+	label := handlers.StatusFor(status).Label
+	var changed []string
+	results, err := h.inTx(r, paths, func(qtx *database.Queries, entry database.Entry) (string, error) {
+		if entry.Status == status {
+			return fmt.Sprintf("Status war bereits '%s'.", label), nil
+		}
+		err := qtx.UpdateStatusByPath(r.Context(), database.UpdateStatusByPathParams{
+			Status: status,
+			Path:   entry.Path,
+		})
+		changed = append(changed, entry.Path)
+		return fmt.Sprintf("Status auf '%s' gesetzt.", label), err
+	})
+	if err == nil {
+		q := database.New(h.DB)
+		for _, path := range changed {
+			checklist.PublishFields(r.Context(), h.Live, q, path)
+		}
+	}
+	h.respond(w, results, err)
+}
+
+// Checks (or unchecks) the item with the passed task in all selected entries
+func (h *BulkHandler) Check(w http.ResponseWriter, r *http.Request) {
+	paths := selectedPaths(r)
+	if len(paths) == 0 {
+		noSelection(w)
+		return
+	}
+	task := strings.TrimSpace(r.FormValue("task"))
+	if task == "" {
+		html := `<div class='text-red-700'>Bitte einen Punkt der Checkliste angeben.</div>`
+		w.Write([]byte(html))
+		return
+	}
+	checked := r.FormValue("checked") != "false"
+	// Items are published after the commit, nothing is sent for a rollback
+	type checkedItem struct {
+		entry database.Entry
+		item  *checklist.Item
+	}
+	var changed []checkedItem
+	results, err := h.inTx(r, paths, func(qtx *database.Queries, entry database.Entry) (string, error) {
+		revision, err := qtx.NextRevisionByID(r.Context(), entry.ID)
+		if err != nil {
+			return "", err
+		}
+		items, err := checklist.LoadItems(r.Context(), qtx, entry.ID)
+		if err != nil {
+			return "", skipError("Checkliste konnte nicht gelesen werden.")
+		}
+		item := checklist.FindItem(items, task)
+		if item == nil {
+			return "", skipError(fmt.Sprintf("Punkt '%s' ist nicht vorhanden.", task))
+		}
+		item.Checked = checked
+		item.CheckedRevision = revision
+		err = checklist.SaveChecked(r.Context(), qtx, entry.ID, task, checked, revision)
+		if err == nil {
+			err = checklist.UpdateProgress(r.Context(), qtx, entry.ID, items)
+		}
+		changed = append(changed, checkedItem{entry: entry, item: item})
+		if checked {
+			return fmt.Sprintf("'%s' abgehakt.", task), err
+		}
+		return fmt.Sprintf("Haken bei '%s' entfernt.", task), err
+	})
+	if err == nil {
+		q := database.New(h.DB)
+		for _, c := range changed {
+			checklist.PublishItem(r.Context(), h.Live, q, c.entry.ID, c.entry.Path, c.item)
+			checklist.Notify(h.Live, c.entry.Path, "progress")
+		}
+	}
+	h.respond(w, results, err)
+}
+
+// Renders the selected entries through the same path as /checklist/print
+// and returns them as one merged pdf or as zip.
+func (h *BulkHandler) Export(w http.ResponseWriter, r *http.Request) {
+	// Not every ResponseWriter supports deadlines, then the server's timeout stays
+	http.NewResponseController(w).SetWriteDeadline(time.Now().Add(batchTimeout))
+	h.export(w, r, selectedPaths(r), r.FormValue("format"), false)
+}
+
+// Sends the rendered entries as zip or merged pdf. When a single entry fails,
+// nothing is downloaded. 'batch' adds a table of contents to the merged pdf.
+func (h *BulkHandler) export(w http.ResponseWriter, r *http.Request, paths []string, format string, batch bool) {
+	var results []ResultView
+	var names []string
+	var pdfs [][]byte
+	failed := false
+	for _, res := range h.renderAll(r, paths) {
+		if res.err != nil {
+			log.Printf("Couldn't render '%s' for export.\n Error: %v\n", res.path, res.err)
+			results = append(results, ResultView{Label: res.path, Path: res.path, Message: res.err.Error()})
+			failed = true
+			continue
+		}
+		results = append(results, ResultView{Label: res.name, Path: res.path, Ok: true, Message: "OK"})
+		names = append(names, res.name)
+		pdfs = append(pdfs, res.data)
+	}
+	if len(paths) == 0 || failed {
+		// Nothing is downloaded, instead the user sees what went wrong
+		tmpl := handlers.LoadTemplates([]string{"bulk/templates/export-error.html", "bulk/templates/result.html", "header.html"})
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		tmpl.Execute(w, map[string]any{
+			"Results": results,
+			"Batch":   batch,
+		})
+		return
+	}
+
+	date := time.Now().Format("20060102")
+	switch format {
+	case "zip":
+		var buf bytes.Buffer
+		zw := zip.NewWriter(&buf)
+		for i, name := range zipNames(names) {
+			f, err := zw.Create(name)
+			if err == nil {
+				_, err = f.Write(pdfs[i])
+			}
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+		if err := zw.Close(); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s_checklisten.zip", date))
+		w.Write(buf.Bytes())
+	default:
+		if batch {
+			toc, err := h.tableOfContents(r, names, pdfs)
+			if err != nil {
+				log.Printf("Couldn't render the table of contents.\n Error: %v\n", err)
+				status, msg := checklist.PDFError(err)
+				http.Error(w, msg, status)
+				return
+			}
+			pdfs = append([][]byte{toc}, pdfs...)
+		}
+		merged, err := h.PDF.Merge(r.Context(), pdfs)
+		if errors.Is(err, pdf.ErrMergeUnsupported) {
+			http.Error(w, "Zusammengeführte PDFs benötigen Gotenberg. Bitte als ZIP exportieren.", http.StatusNotImplemented)
+			return
+		}
+		if err != nil {
+			log.Printf("Couldn't merge the pdfs.\n Error: %v\n", err)
+			status, msg := checklist.PDFError(err)
+			http.Error(w, msg, status)
+			return
+		}
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s_checklisten.pdf", date))
+		w.Write(merged)
+	}
+}
+
+// Numbers names which are used more than once, e.g. by entries with the same values in 'pdf_name_schema'.
+// A numbered name can be the name of another entry as well, so the number is counted up until it is free.
+func zipNames(names []string) []string {
+	unique := make([]string, len(names))
+	used := make(map[string]bool)
+	for i, name := range names {
+		unique[i] = name
+		for n := 2; used[unique[i]]; n++ {
+			unique[i] = fmt.Sprintf("%s_%d.pdf", strings.TrimSuffix(name, ".pdf"), n)
+		}
+		used[unique[i]] = true
+	}
+	return unique
+}
+
+func init() {
+	handlers.RegisterHandler(&BulkHandler{})
+}
@@ -0,0 +1,14 @@
+package bulk
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestZipNames(t *testing.T) {
+	got := zipNames([]string{"Anna.pdf", "Anna_2.pdf", "Anna.pdf", "Ben.pdf", "Anna.pdf"})
+	expected := []string{"Anna.pdf", "Anna_2.pdf", "Anna_3.pdf", "Ben.pdf", "Anna_4.pdf"}
+	if !slices.Equal(got, expected) {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+}
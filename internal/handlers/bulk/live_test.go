@@ -0,0 +1,68 @@
+//go:build sqlite_fts5
+
+package bulk
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/hmaier-dev/checklist-tool/internal/database/dbtest"
+	"github.com/hmaier-dev/checklist-tool/internal/live"
+)
+
+func post(t *testing.T, action func(w http.ResponseWriter, r *http.Request), form url.Values) {
+	t.Helper()
+	r := httptest.NewRequest("POST", "/bulk", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	action(w, r)
+	if strings.Contains(w.Body.String(), "Fehler") {
+		t.Fatalf("unexpected result: %s", w.Body)
+	}
+}
+
+// Names of the events waiting on 'ch'
+func received(ch <-chan live.Event) []string {
+	var names []string
+	for {
+		select {
+		case e := <-ch:
+			names = append(names, e.Name)
+		default:
+			return names
+		}
+	}
+}
+
+func TestLive(t *testing.T) {
+	// The templates are loaded relative to the root of the repository
+	t.Chdir("../../..")
+	db := dbtest.Open(t)
+	if _, err := db.Exec(testData); err != nil {
+		t.Fatal(err)
+	}
+	hub := live.NewHub()
+	h := &BulkHandler{DB: db, Live: hub}
+	a, _, _ := hub.Subscribe("a")
+	b, _, _ := hub.Subscribe("b")
+
+	post(t, h.Check, url.Values{"path": {"a", "b"}, "task": {"Auspacken"}})
+	for _, ch := range []<-chan live.Event{a, b} {
+		names := received(ch)
+		if len(names) != 2 || !strings.HasPrefix(names[0], "item-") || names[1] != "progress" {
+			t.Errorf("expected the item and the progress, got %q", names)
+		}
+	}
+
+	// 'a' is already done, so only 'b' changes
+	post(t, h.Status, url.Values{"path": {"a", "b"}, "status": {"done"}})
+	if names := received(a); len(names) != 0 {
+		t.Errorf("expected no events for an unchanged entry, got %q", names)
+	}
+	if names := received(b); len(names) == 0 || names[0] != "status" {
+		t.Errorf("expected the status, got %q", names)
+	}
+}
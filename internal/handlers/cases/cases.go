@@ -0,0 +1,71 @@
+package cases
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/hmaier-dev/checklist-tool/internal/database"
+	"github.com/hmaier-dev/checklist-tool/internal/handlers"
+	"github.com/hmaier-dev/checklist-tool/internal/handlers/checklist"
+	"github.com/hmaier-dev/checklist-tool/internal/server"
+)
+
+// Shows all entries sharing the value of 'case_field'
+type CaseHandler struct {
+	Router *mux.Router
+	DB     *sql.DB
+}
+
+var _ handlers.DisplayHandler = (*CaseHandler)(nil)
+
+func (h *CaseHandler) New(srv *server.Server) {
+	h.Router = srv.Router
+	h.DB = srv.DB
+}
+
+// Sets /case
+func (h *CaseHandler) Routes() {
+	// The key is passed as query parameter, because it is user input and may contain slashes
+	h.Router.HandleFunc("/case", h.Display).Methods("GET")
+}
+
+// Return rendered html for GET to /case?key=
+func (h *CaseHandler) Display(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	var templates = []string{
+		"cases/templates/overview.html",
+		"checklist/templates/case.html",
+		"progress.html",
+		"nav.html",
+		"header.html",
+	}
+	key := r.URL.Query().Get("key")
+	if key == "" {
+		http.Error(w, "Missing 'key'.", http.StatusBadRequest)
+		return
+	}
+	q := database.New(h.DB)
+	entries, err := checklist.CaseEntries(ctx, q, key)
+	if err != nil {
+		msg := "Couldn't load the case."
+		log.Printf("%s\n Error: %v\n", msg, err)
+		http.Error(w, msg, http.StatusInternalServerError)
+		return
+	}
+	tmpl := handlers.LoadTemplates(templates)
+	err = tmpl.Execute(w, map[string]any{
+		"Key":     key,
+		"Entries": entries,
+		"Progress": checklist.CaseProgress(entries),
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func init() {
+	handlers.RegisterHandler(&CaseHandler{})
+}
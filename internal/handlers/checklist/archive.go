@@ -0,0 +1,96 @@
+package checklist
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/hmaier-dev/checklist-tool/internal/archive"
+	"github.com/hmaier-dev/checklist-tool/internal/database"
+	"github.com/hmaier-dev/checklist-tool/internal/handlers"
+)
+
+// Archived pdf as listed on the checklist page
+type ExportView struct {
+	ID          int64
+	Filename    string
+	Date        string
+	RequestedBy string
+	Revision    int64
+	Content     string
+	Sha256      string
+	Size        string
+}
+
+// Lists the archived pdfs of an entry.
+// Is loaded by the checklist page and reloaded, when the page gets the focus back after a download.
+func (h *ChecklistHandler) Exports(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	tmpl := handlers.LoadTemplates([]string{"checklist/templates/exports.html"})
+	// Listed by path, the archived pdfs outlive a purged or overwritten entry
+	exports, err := database.New(h.DB).GetPdfExportsByEntryPath(ctx, mux.Vars(r)["id"])
+	if err != nil {
+		msg := "Couldn't load the archived pdfs."
+		log.Printf("%s\n Error: %v\n", msg, err)
+		http.Error(w, msg, http.StatusInternalServerError)
+		return
+	}
+	var views []ExportView
+	for _, e := range exports {
+		views = append(views, ExportView{
+			ID:          e.ID,
+			Filename:    e.Filename,
+			Date:        time.Unix(e.Date, 0).Format("02.01.2006 15:04:05"),
+			RequestedBy: e.RequestedBy,
+			Revision:    e.Revision,
+			Content:     e.ContentSha256,
+			Sha256:      e.Sha256,
+			Size:        fmt.Sprintf("%.1f KB", float64(e.Size)/1024),
+		})
+	}
+	err = tmpl.ExecuteTemplate(w, "exports.html", map[string]any{
+		"Exports": views,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// Downloads an archived pdf as it was handed out, after checking its checksum
+func (h *ChecklistHandler) ArchivedPDF(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if h.Archive == nil {
+		http.Error(w, "Das Archiv ist nicht eingerichtet.", http.StatusNotFound)
+		return
+	}
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "'id' is weird.", http.StatusBadRequest)
+		return
+	}
+	export, err := database.New(h.DB).GetPdfExportByID(ctx, id)
+	if err != nil {
+		http.Error(w, "Archivierte PDF nicht gefunden.", http.StatusNotFound)
+		return
+	}
+	data, err := h.Archive.Load(ctx, export)
+	if err != nil {
+		log.Printf("Couldn't load the archived pdf %d.\n Error: %v\n", id, err)
+		switch {
+		case errors.Is(err, archive.ErrNotFound):
+			http.Error(w, "Die Datei fehlt im Archiv.", http.StatusNotFound)
+		case errors.Is(err, archive.ErrChecksum):
+			http.Error(w, "Die Datei im Archiv wurde verändert, sie stimmt nicht mit ihrer Prüfsumme überein.", http.StatusInternalServerError)
+		default:
+			http.Error(w, "Das Archiv ist nicht erreichbar.", http.StatusBadGateway)
+		}
+		return
+	}
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", export.Filename))
+	w.Write(data)
+}
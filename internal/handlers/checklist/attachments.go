@@ -0,0 +1,219 @@
+package checklist
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"io"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/hmaier-dev/checklist-tool/internal/database"
+	"github.com/hmaier-dev/checklist-tool/internal/pdf"
+)
+
+// Only these types are accepted.
+// The type is detected from the file content, not from the name or the header sent by the browser.
+var allowedContentTypes = map[string]bool{
+	"image/png":       true,
+	"image/jpeg":      true,
+	"image/gif":       true,
+	"image/webp":      true,
+	"application/pdf": true,
+}
+
+// Single attachment shown as thumbnail or link
+type AttachmentView struct {
+	ID       int64
+	Filename string
+	IsImage  bool
+	// Either a link to the file or the file itself as data-url (for the pdf)
+	Src template.URL
+}
+
+// Attachments of an entry grouped by the task they belong to.
+// Attachments of the entry itself are found under the empty string.
+type AttachmentMap map[string][]AttachmentView
+
+func isImage(contentType string) bool {
+	return strings.HasPrefix(contentType, "image/")
+}
+
+func attachmentsForEntry(ctx context.Context, q *database.Queries, entryID int64) (AttachmentMap, error) {
+	attachments, err := q.GetAttachmentsByEntryID(ctx, entryID)
+	if err != nil {
+		return nil, err
+	}
+	var result = make(AttachmentMap)
+	for _, a := range attachments {
+		result[a.Task.String] = append(result[a.Task.String], AttachmentView{
+			ID:       a.ID,
+			Filename: a.Filename,
+			IsImage:  isImage(a.ContentType),
+			Src:      template.URL(fmt.Sprintf("/checklist/attachment/%d", a.ID)),
+		})
+	}
+	return result, nil
+}
+
+// Images grouped by task like AttachmentMap and attached pdfs, which are appended to the exported pdf.
+func attachmentsForPrint(ctx context.Context, q *database.Queries, entryID int64) (map[string][]pdf.Image, []pdf.Attachment, error) {
+	attachments, err := q.GetAttachmentsWithDataByEntryID(ctx, entryID)
+	if err != nil {
+		return nil, nil, err
+	}
+	var images = make(map[string][]pdf.Image)
+	var pdfs []pdf.Attachment
+	for _, a := range attachments {
+		if !isImage(a.ContentType) {
+			pdfs = append(pdfs, pdf.Attachment{Filename: a.Filename, Data: a.Data})
+			continue
+		}
+		images[a.Task.String] = append(images[a.Task.String], pdf.Image{
+			Filename:    a.Filename,
+			ContentType: a.ContentType,
+			Data:        a.Data,
+		})
+	}
+	return images, pdfs, nil
+}
+
+// Images are embedded into print.html as data-url, so gotenberg doesn't need to call us.
+func printImages(images map[string][]pdf.Image) AttachmentMap {
+	var result = make(AttachmentMap)
+	for task, list := range images {
+		for _, img := range list {
+			src := "data:" + img.ContentType + ";base64," + base64.StdEncoding.EncodeToString(img.Data)
+			result[task] = append(result[task], AttachmentView{
+				Filename: img.Filename,
+				IsImage:  true,
+				Src:      template.URL(src),
+			})
+		}
+	}
+	return result
+}
+
+// Stores the uploaded file for the entry.
+// When 'task' is set, the file belongs to this item.
+func (h *ChecklistHandler) UploadAttachment(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	path := mux.Vars(r)["id"]
+	limit := h.MaxAttachmentMB << 20
+	// Some extra space for the rest of the multipart form
+	r.Body = http.MaxBytesReader(w, r.Body, limit+(1<<20))
+	if err := r.ParseMultipartForm(1 << 20); err != nil {
+		msg := fmt.Sprintf("Die Datei ist größer als %d MB.", h.MaxAttachmentMB)
+		http.Error(w, msg, http.StatusRequestEntityTooLarge)
+		return
+	}
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "Keine Datei hochgeladen.", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+	if header.Size > limit {
+		msg := fmt.Sprintf("Die Datei ist größer als %d MB.", h.MaxAttachmentMB)
+		http.Error(w, msg, http.StatusRequestEntityTooLarge)
+		return
+	}
+	var buf bytes.Buffer
+	if _, err := io.Copy(&buf, file); err != nil {
+		http.Error(w, "Couldn't read the file.", http.StatusBadRequest)
+		return
+	}
+	contentType := http.DetectContentType(buf.Bytes())
+	if !allowedContentTypes[contentType] {
+		msg := fmt.Sprintf("Dateityp '%s' wird nicht unterstützt. Erlaubt sind Bilder (PNG, JPEG, GIF, WebP) und PDFs.", contentType)
+		http.Error(w, msg, http.StatusUnsupportedMediaType)
+		return
+	}
+
+	q := database.New(h.DB)
+	entry, err := q.GetEntryByPath(ctx, path)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	task := r.FormValue("task")
+	err = q.InsertAttachment(ctx, database.InsertAttachmentParams{
+		EntryID:     entry.ID,
+		Task:        sql.NullString{Valid: task != "", String: task},
+		Filename:    filepath.Base(header.Filename),
+		ContentType: contentType,
+		Size:        int64(buf.Len()),
+		Data:        buf.Bytes(),
+		Date:        time.Now().Unix(),
+	})
+	if err != nil {
+		msg := "Couldn't save the attachment."
+		log.Printf("%s\n Error: %v\n", msg, err)
+		http.Error(w, msg, http.StatusInternalServerError)
+		return
+	}
+	// Special header for htmx
+	w.Header().Set("HX-Redirect", "/checklist/"+path)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Sends the raw file to the browser
+func (h *ChecklistHandler) Attachment(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "'id' is weird.", http.StatusBadRequest)
+		return
+	}
+	q := database.New(h.DB)
+	a, err := q.GetAttachmentByID(ctx, id)
+	if err != nil {
+		http.Error(w, "Attachment not found.", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", a.ContentType)
+	// Don't let the browser guess another type than the one we checked
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", a.Filename))
+	w.Write(a.Data)
+}
+
+// Deletes the attachment 'id' of the entry 'path'
+func (h *ChecklistHandler) DeleteAttachment(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id, err := strconv.ParseInt(r.FormValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "'id' is weird.", http.StatusBadRequest)
+		return
+	}
+	q := database.New(h.DB)
+	entry, err := q.GetEntryByPath(ctx, r.FormValue("path"))
+	if err != nil {
+		http.Error(w, "Entry not found.", http.StatusNotFound)
+		return
+	}
+	n, err := q.DeleteAttachmentByIDAndEntryID(ctx, database.DeleteAttachmentByIDAndEntryIDParams{
+		ID:      id,
+		EntryID: entry.ID,
+	})
+	if err != nil {
+		msg := "Couldn't delete the attachment."
+		log.Printf("%s\n Error: %v\n", msg, err)
+		http.Error(w, msg, http.StatusInternalServerError)
+		return
+	}
+	if n == 0 {
+		http.Error(w, "Attachment not found.", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("HX-Redirect", "/checklist/"+entry.Path)
+	w.WriteHeader(http.StatusNoContent)
+}
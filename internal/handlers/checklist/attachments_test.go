@@ -0,0 +1,79 @@
+//go:build sqlite_fts5
+
+package checklist
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func deleteAttachment(t *testing.T, h *ChecklistHandler, id, path string) *httptest.ResponseRecorder {
+	t.Helper()
+	form := url.Values{"id": {id}, "path": {path}}
+	r := httptest.NewRequest("POST", "/checklist/attachment/delete", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	h.DeleteAttachment(w, r)
+	return w
+}
+
+func TestDeleteAttachment(t *testing.T) {
+	db := testDB(t, 1)
+	_, err := db.Exec(`
+INSERT INTO entries (id, template_id, path, yaml) VALUES (2, 1, 'other', '');
+INSERT INTO attachments (id, entry_id, task, filename, content_type, size, data, date)
+  VALUES (1, 2, NULL, 'bild.png', 'image/png', 1, 'x', 1);`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := &ChecklistHandler{DB: db}
+	count := func() int {
+		var n int
+		if err := db.QueryRow("SELECT count(*) FROM attachments").Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+
+	// The attachment belongs to 'other', not to 'path'
+	if w := deleteAttachment(t, h, "1", "path"); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for the attachment of another entry, got %d", w.Code)
+	}
+	if w := deleteAttachment(t, h, "1", "missing"); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for a missing entry, got %d", w.Code)
+	}
+	if n := count(); n != 1 {
+		t.Fatalf("expected the attachment to be kept, got %d attachments", n)
+	}
+
+	w := deleteAttachment(t, h, "1", "other")
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d: %s", w.Code, w.Body)
+	}
+	if got := w.Header().Get("HX-Redirect"); got != "/checklist/other" {
+		t.Errorf("unexpected redirect %q", got)
+	}
+	if n := count(); n != 0 {
+		t.Errorf("expected the attachment to be deleted, got %d attachments", n)
+	}
+	if w := deleteAttachment(t, h, "1", "other"); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for a deleted attachment, got %d", w.Code)
+	}
+}
+
+func TestIsImage(t *testing.T) {
+	for contentType, want := range map[string]bool{
+		"image/png":                true,
+		"image/webp":               true,
+		"application/pdf":          false,
+		"text/html; charset=utf-8": false,
+		"":                         false,
+	} {
+		if got := isImage(contentType); got != want {
+			t.Errorf("isImage(%q) = %v, expected %v", contentType, got, want)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package checklist
+
+import (
+	"context"
+
+	"github.com/hmaier-dev/checklist-tool/internal/database"
+	"github.com/hmaier-dev/checklist-tool/internal/handlers"
+)
+
+// Entry of a case with its progress
+type CaseEntryView struct {
+	Path         string
+	TemplateName string
+	Label        string
+	Status       handlers.Status
+	Progress     handlers.ProgressView
+}
+
+// Returns the value of 'case_field' of the entry.
+// Is empty, when the template doesn't group entries into cases.
+func CaseKey(ctx context.Context, q *database.Queries, entry database.Entry) (string, error) {
+	template, err := q.GetTemplateById(ctx, entry.TemplateID)
+	if err != nil {
+		return "", err
+	}
+	if !template.CaseField.Valid || template.CaseField.String == "" {
+		return "", nil
+	}
+	values, err := handlers.LoadValues(ctx, q, entry.ID)
+	if err != nil {
+		return "", err
+	}
+	return handlers.ValueMap(values[entry.ID])[template.CaseField.String], nil
+}
+
+// Returns all entries of a case
+func CaseEntries(ctx context.Context, q *database.Queries, key string) ([]CaseEntryView, error) {
+	entries, err := q.GetEntriesByCaseKey(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]int64, len(entries))
+	for i, e := range entries {
+		ids[i] = e.ID
+	}
+	values, err := handlers.LoadValues(ctx, q, ids...)
+	if err != nil {
+		return nil, err
+	}
+	var view = make([]CaseEntryView, len(entries))
+	for i, e := range entries {
+		schema, err := q.GetTabDescriptionsByTemplateID(ctx, e.TemplateID)
+		if err != nil {
+			return nil, err
+		}
+		view[i] = CaseEntryView{
+			Path:         e.Path,
+			TemplateName: e.TemplateName,
+			Label:        handlers.BuildTabDescription(schema, handlers.ValueMap(values[e.ID])),
+			Status:       handlers.StatusFor(e.Status),
+			Progress:     handlers.ProgressFor(e.ProgressChecked, e.ProgressTotal, e.RequiredChecked, e.RequiredTotal),
+		}
+	}
+	return view, nil
+}
+
+// Sums up the progress of all entries of a case
+func CaseProgress(entries []CaseEntryView) handlers.ProgressView {
+	var result handlers.ProgressView
+	for _, e := range entries {
+		result = result.Add(e.Progress)
+	}
+	return result
+}
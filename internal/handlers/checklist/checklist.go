@@ -2,21 +2,24 @@ package checklist
 
 import (
 	"bytes"
+	"context"
 	"database/sql"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"log"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/hmaier-dev/checklist-tool/internal/archive"
 	"github.com/hmaier-dev/checklist-tool/internal/database"
 	"github.com/hmaier-dev/checklist-tool/internal/handlers"
+	"github.com/hmaier-dev/checklist-tool/internal/live"
 	"github.com/hmaier-dev/checklist-tool/internal/pdf"
 	"github.com/hmaier-dev/checklist-tool/internal/server"
-	"gopkg.in/yaml.v3"
 )
 
 // Single checkpoint of the list
@@ -24,13 +27,24 @@ type Item struct {
 	Task     string  `yaml:"task"`
 	Checked  bool    `yaml:"checked"`
 	Text     *string `yaml:"text"` // this needs to be a pointer, because that way {{ if .Text }} displays input fields, even with an empty string
+	Required bool    `yaml:"required,omitempty"`
 	Children []*Item `yaml:"children,omitempty"`
 	Path     string  `yaml:"Path"`
+	// Earlier task of the item. Updating the template carries its state over.
+	RenamedFrom string `yaml:"renamed_from,omitempty"`
+	// Revision of the entry, when the checkbox or the text field was changed last
+	CheckedRevision int64 `yaml:"-"`
+	TextRevision    int64 `yaml:"-"`
 }
 
 type ChecklistHandler struct{
 	Router *mux.Router	
 	DB *sql.DB
+	MaxAttachmentMB int64
+	Live *live.Hub
+	PDF pdf.Renderer
+	// Keeps every generated pdf, nil when archiving is off
+	Archive *archive.Archive
 }
 
 var _ handlers.DisplayHandler = (*ChecklistHandler)(nil)
@@ -38,6 +52,10 @@ var _ handlers.DisplayHandler = (*ChecklistHandler)(nil)
 func (h *ChecklistHandler) New(srv *server.Server){
 	h.Router = srv.Router	
 	h.DB = srv.DB
+	h.MaxAttachmentMB = srv.Config.MaxAttachmentMB
+	h.Live = srv.Live
+	h.PDF = srv.PDF
+	h.Archive = srv.Archive
 }
 
 func (h *ChecklistHandler) Routes(){
@@ -45,15 +63,41 @@ func (h *ChecklistHandler) Routes(){
 	sub := h.Router.PathPrefix("/checklist").Subrouter()
 	sub.HandleFunc(`/update/check/{id:\w*}`, h.UpdateCheckedState).Methods("POST")
 	sub.HandleFunc(`/update/text/{id:\w*}`, h.UpdateText).Methods("POST")
+	sub.HandleFunc(`/update/status/{id:\w*}`, h.UpdateStatus).Methods("POST")
+	sub.HandleFunc(`/update/due/{id:\w*}`, h.UpdateDue).Methods("POST")
+	sub.HandleFunc(`/due/{id:\w*}`, h.Due).Methods("GET")
+	sub.HandleFunc(`/update/assignee/{id:\w*}`, h.UpdateAssignee).Methods("POST")
+	sub.HandleFunc(`/events/{id:\w*}`, h.Events).Methods("GET")
+	sub.HandleFunc(`/progress/{id:\w*}`, h.Progress).Methods("GET")
+	sub.HandleFunc(`/live/{id:\w*}`, h.Stream).Methods("GET")
 	sub.HandleFunc(`/print/{id:\w*}`, h.Print).Methods("GET")
+	sub.HandleFunc(`/export/{id:\w*}`, h.Export).Methods("GET")
+	sub.HandleFunc(`/exports/{id:\w*}`, h.Exports).Methods("GET")
+	sub.HandleFunc(`/archive/{id:\d+}`, h.ArchivedPDF).Methods("GET")
 	sub.HandleFunc("/delete", h.Delete).Methods("POST")
+	sub.HandleFunc(`/comment/{id:\w*}`, h.Comment).Methods("POST")
+	sub.HandleFunc(`/attachment/upload/{id:\w*}`, h.UploadAttachment).Methods("POST")
+	sub.HandleFunc(`/attachment/{id:\d+}`, h.Attachment).Methods("GET")
+	sub.HandleFunc("/attachment/delete", h.DeleteAttachment).Methods("POST")
 }
 
 func (h *ChecklistHandler) Display(w http.ResponseWriter, r *http.Request){
+	if r.URL.Query().Has("format"){
+		h.Export(w, r)
+		return
+	}
 	ctx := r.Context()
   path := mux.Vars(r)["id"]
 	paths := []string{
 		"checklist/templates/checklist.html",
+		"checklist/templates/items.html",
+		"checklist/templates/fields.html",
+		"checklist/templates/comments.html",
+		"checklist/templates/attachments.html",
+		"checklist/templates/events.html",
+		"checklist/templates/case.html",
+		"progress.html",
+		"due.html",
 		"nav.html",
 		"header.html",
 		"history/templates/history.html",
@@ -67,44 +111,95 @@ func (h *ChecklistHandler) Display(w http.ResponseWriter, r *http.Request){
 		return
 	}
 
-	var data map[string]string
-	err = json.Unmarshal([]byte(entry.Data),&data)
-	if err != nil{
-		log.Fatalln("Unmarshaling json from db wen't wrong.")
+	values, err := handlers.LoadValues(ctx, q, entry.ID)
+	if err != nil {
+		msg := "Couldn't load the values of the entry."
+		log.Printf("%s\n Error: %v\n", msg, err)
+		http.Error(w, msg, http.StatusInternalServerError)
 		return
 	}
-	
 	templateName, err := q.GetTemplateNameById(ctx, entry.TemplateID)
-	customFields, err := q.GetCustomFieldsByTemplateName(ctx, templateName)
-	result := handlers.BuildEntryViewForTemplate(customFields, &entry)
+	if err != nil {
+		msg := "Couldn't load the template of the entry."
+		log.Printf("%s\n Error: %v\n", msg, err)
+		http.Error(w, msg, http.StatusInternalServerError)
+		return
+	}
+	result := handlers.BuildEntryViewForTemplate(values[entry.ID], &entry)
 
 	// Build string for browser-tab title
 	tab_desc_schema, err := q.GetTabDescriptionsByTemplateID(ctx, entry.TemplateID)
-	var tab_desc string
-	for i, desc := range tab_desc_schema{
-		key := desc.Value
-		if i == len(tab_desc_schema) - 1 {
-			tab_desc += data[key]
-		}else{
-			tab_desc += data[key] + " | "
+	if err != nil {
+		msg := "Couldn't load the tab description of the template."
+		log.Printf("%s\n Error: %v\n", msg, err)
+		http.Error(w, msg, http.StatusInternalServerError)
+		return
+	}
+	tab_desc := handlers.BuildTabDescription(tab_desc_schema, handlers.ValueMap(values[entry.ID]))
+	items, err := LoadItems(ctx, q, entry.ID)
+	if err != nil{
+		msg := "Checklist couldn't be loaded."
+		log.Printf("%s\n Error: %v\n", msg, err)
+		http.Error(w, msg, http.StatusInternalServerError)
+		return
+	}
+	comments, err := commentsForEntry(ctx, q, entry.ID)
+	if err != nil {
+		msg := "Couldn't load the comments."
+		log.Printf("%s\n Error: %v\n", msg, err)
+		http.Error(w, msg, http.StatusInternalServerError)
+		return
+	}
+	attachments, err := attachmentsForEntry(ctx, q, entry.ID)
+	if err != nil {
+		msg := "Couldn't load the attachments."
+		log.Printf("%s\n Error: %v\n", msg, err)
+		http.Error(w, msg, http.StatusInternalServerError)
+		return
+	}
+	events, err := eventsForEntry(ctx, q, entry.ID)
+	if err != nil {
+		msg := "Couldn't load the history."
+		log.Printf("%s\n Error: %v\n", msg, err)
+		http.Error(w, msg, http.StatusInternalServerError)
+		return
+	}
+	// Other entries of the same case
+	var siblings []CaseEntryView
+	caseKey, err := CaseKey(ctx, q, entry)
+	if err == nil && caseKey != "" {
+		all, err := CaseEntries(ctx, q, caseKey)
+		if err != nil {
+			log.Printf("Couldn't load the case '%s'.\n Error: %v\n", caseKey, err)
+		}
+		for _, e := range all {
+			if e.Path != entry.Path {
+				siblings = append(siblings, e)
+			}
 		}
 	}
-	var items []*Item
-	var y string
-	if entry.Yaml.Valid{
-		y = entry.Yaml.String
-	}else{
-		log.Printf("%#v \n", entry.Yaml)
-		http.Error(w, "Checklist will not render correctly. 'yaml'-field wasn't valid.", http.StatusInternalServerError)
+	people, err := q.GetAllPeople(ctx)
+	if err != nil {
+		msg := "Couldn't load all people."
+		log.Printf("%s\n Error: %v\n", msg, err)
+		http.Error(w, msg, http.StatusInternalServerError)
 		return
 	}
-	yaml.Unmarshal([]byte(y), &items)
 	err = tmpl.Execute(w, map[string]any{
 		"TemplateName": templateName,
 		"TabDescription": tab_desc,
 		"EntryView": result,
 		"Items": items,
 		"Path": path,
+		"Comments": comments,
+		"Attachments": attachments,
+		"MaxAttachmentMB": h.MaxAttachmentMB,
+		"Statuses": handlers.Statuses,
+		"People": people,
+		"Events": events,
+		"CaseKey": caseKey,
+		"Siblings": siblings,
+		"Archive": h.Archive != nil,
   })
   if err != nil {
     http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -112,6 +207,11 @@ func (h *ChecklistHandler) Display(w http.ResponseWriter, r *http.Request){
   }
 }
 
+// Only the row of the clicked task is written.
+// The progress is counted in the same transaction,
+// so clicks arriving at the same time don't overwrite each other.
+// When someone else changed the task after the page was loaded,
+// the click is rejected and the current state is shown instead.
 func (h *ChecklistHandler) UpdateCheckedState(w http.ResponseWriter, r *http.Request){
 	ctx := r.Context()
 	path :=  mux.Vars(r)["id"]
@@ -120,65 +220,228 @@ func (h *ChecklistHandler) UpdateCheckedState(w http.ResponseWriter, r *http.Req
 		http.Error(w, "Unable to parse form", http.StatusBadRequest)
 		return
 	}
-  var checked bool = false
-  // if ["checked"] isset
-  if _, ok := r.Form["checked"]; ok{
-    checked = true
-  }
-	var alteredItem Item
-	alteredItem = Item{
-		Task: r.Form.Get("task"),
-		Text: nil,
-		Checked: checked,
+	// if ["checked"] isset
+	_, checked := r.Form["checked"]
+	task := r.Form.Get("task")
+	seen, err := seenRevision(r)
+	if err != nil {
+		http.Error(w, "Invalid revision.", http.StatusBadRequest)
+		return
 	}
-  // Fetch Row from Database
 	q := database.New(h.DB)
-  entry, err := q.GetEntryByPath(ctx, path)
+	entry, err := q.GetEntryByPath(ctx, path)
 	if err != nil {
 		http.Error(w,err.Error(), http.StatusInternalServerError)
 		return
 	}
-  var oldItems []*Item
-	var y string
-	if entry.Yaml.Valid{
-		y = entry.Yaml.String
-	}else{
-		msg := "Checkpoint state couldn't get updated. 'yaml'-field wasn't valid."
-		log.Println(msg)
+	tx, err := h.DB.BeginTx(ctx, nil)
+	if err != nil{
+		http.Error(w, "Database error.", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+	qtx := q.WithTx(tx)
+	revision, err := qtx.NextRevisionByID(ctx, entry.ID)
+	if err != nil{
+		msg := "Checkpoint state couldn't get updated."
+		log.Printf("%s\n Error: %v\n", msg, err)
 		http.Error(w, msg, http.StatusInternalServerError)
+		return
 	}
-  err = yaml.Unmarshal([]byte(y), &oldItems)
-  alterCheckedState(alteredItem, oldItems)
-  yamlBytes, err := yaml.Marshal(oldItems)
-  if err != nil {
-      log.Println("Error marshaling Yaml: ", err)
-      return
-  }
-	
-	arg := database.UpdateYamlByPathParams{
-		Yaml: sql.NullString{Valid: true, String: string(yamlBytes)},
-		Path: path,
+	items, err := LoadItems(ctx, qtx, entry.ID)
+	if err != nil{
+		msg := "Checkpoint state couldn't get updated."
+		log.Printf("%s\n Error: %v\n", msg, err)
+		http.Error(w, msg, http.StatusInternalServerError)
+		return
+	}
+	item := FindItem(items, task)
+	if item == nil{
+		http.Error(w, "Unknown task.", http.StatusBadRequest)
+		return
+	}
+	if err := CheckRevision(item.CheckedRevision, seen, item.Checked == checked); err != nil{
+		tx.Rollback()
+		renderItem(w, http.StatusConflict, "item-check", path, item, conflictNotice)
+		return
+	}
+	if err := SaveChecked(ctx, qtx, entry.ID, task, checked, revision); err != nil{
+		msg := "Checkpoint state couldn't get updated."
+		log.Printf("%s\n Error: %v\n", msg, err)
+		http.Error(w, msg, http.StatusInternalServerError)
+		return
+	}
+	item.Checked = checked
+	item.CheckedRevision = revision
+	if err := UpdateProgress(ctx, qtx, entry.ID, items); err != nil{
+		log.Printf("Couldn't update the progress.\n Error: %v\n", err)
 	}
+	if err := tx.Commit(); err != nil{
+		http.Error(w, "Database error.", http.StatusInternalServerError)
+		return
+	}
+	PublishItem(ctx, h.Live, q, entry.ID, path, item)
+	Notify(h.Live, path, "progress")
+	// The progress bar on the page reloads itself
+	w.Header().Set("HX-Trigger", "progressChanged")
+	renderItem(w, http.StatusOK, "item-check", path, item, "")
+}
+
+const conflictNotice = "Wurde inzwischen von jemand anderem geändert."
+
+// Revision of the item the client has seen.
+// Requests without one, e.g. from pages loaded before revisions existed, aren't checked.
+func seenRevision(r *http.Request) (int64, error) {
+	v := r.Form.Get("revision")
+	if v == "" {
+		return math.MaxInt64, nil
+	}
+	return strconv.ParseInt(v, 10, 64)
+}
+
+// Answers a change with the controls of the item, see items.html
+func renderItem(w http.ResponseWriter, status int, name string, path string, item *Item, notice string){
+	tmpl := handlers.LoadTemplates([]string{"checklist/templates/items.html"})
+	w.WriteHeader(status)
+	err := tmpl.ExecuteTemplate(w, name, []any{path, item, notice})
+	if err != nil{
+		log.Printf("Couldn't render the item '%s'.\n Error: %v\n", item.Task, err)
+	}
+}
+
+// Sets the checked state of the first item with this task.
+// Returns false when no item has this task.
+func SetChecked(items []*Item, task string, checked bool) bool{
+	for _, item := range items{
+		if item.Task == task{
+			item.Checked = checked
+			return true
+		}
+		if SetChecked(item.Children, task, checked){
+			return true
+		}
+	}
+	return false
+}
 
-  q.UpdateYamlByPath(ctx, arg)
+// Returns the rendered progress of an entry
+func (h *ChecklistHandler) Progress(w http.ResponseWriter, r *http.Request){
+	ctx := r.Context()
+	path :=  mux.Vars(r)["id"]
+	q := database.New(h.DB)
+	entry, err := q.GetEntryByPath(ctx, path)
+	if err != nil{
+		http.Error(w, "Entry not found.", http.StatusNotFound)
+		return
+	}
+	tmpl := handlers.LoadTemplates([]string{"progress.html"})
+	progress := handlers.ProgressFor(entry.ProgressChecked, entry.ProgressTotal, entry.RequiredChecked, entry.RequiredTotal)
+	err = tmpl.ExecuteTemplate(w, "progress.html", progress)
+	if err != nil{
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// Sets the text answer of the item with 'task'.
+// Returns false, when the task doesn't exist or has no text field.
+func SetText(items []*Item, task string, text string) bool{
+	for _, item := range items{
+		if item.Task == task && item.Text != nil{
+			item.Text = &text
+			return true
+		}
+		if SetText(item.Children, task, text){
+			return true
+		}
+	}
+	return false
+}
+
+// Returns all items with a filled in text answer
+func TextAnswers(items []*Item) []*Item{
+	var result []*Item
+	for _, item := range items{
+		if item.Text != nil && strings.TrimSpace(*item.Text) != ""{
+			result = append(result, item)
+		}
+		result = append(result, TextAnswers(item.Children)...)
+	}
+	return result
+}
+
+func (h *ChecklistHandler) UpdateStatus(w http.ResponseWriter, r *http.Request){
+	ctx := r.Context()
+	path :=  mux.Vars(r)["id"]
+	status := r.FormValue("status")
+	if !handlers.IsValidStatus(status){
+		http.Error(w, "Unknown status.", http.StatusBadRequest)
+		return
+	}
+	q := database.New(h.DB)
+	err := q.UpdateStatusByPath(ctx, database.UpdateStatusByPathParams{
+		Status: status,
+		Path: path,
+	})
+	if err != nil{
+		msg := "Couldn't update the status."
+		log.Printf("%s\n Error: %v\n", msg, err)
+		http.Error(w, msg, http.StatusInternalServerError)
+		return
+	}
+	PublishFields(ctx, h.Live, q, path)
+	// Finished entries aren't overdue anymore
+	w.Header().Set("HX-Trigger", "statusChanged")
 	w.Write([]byte{})
 }
 
-func alterCheckedState(newItem Item, checklistSlice []*Item){
-  for _, item := range checklistSlice{
-		// The first occurence of a task is altered.
-		// This way 'Item.Task' should be unique. Otherwise it cannot get altered.
-    if newItem.Task == item.Task{
-				item.Checked = newItem.Checked
-      return
-    }
-		// Go into the lower levels
-    if len(item.Children) > 0 {
-			alterCheckedState(newItem, item.Children)
+// Sets or removes the due date of an entry.
+// Returns the rendered due date.
+func (h *ChecklistHandler) UpdateDue(w http.ResponseWriter, r *http.Request){
+	ctx := r.Context()
+	path :=  mux.Vars(r)["id"]
+	var due sql.NullInt64
+	if val := r.FormValue("due"); val != ""{
+		t, err := handlers.ParseDate(val)
+		if err != nil{
+			http.Error(w, "Invalid due date.", http.StatusBadRequest)
+			return
 		}
-  }
+		due = sql.NullInt64{Valid: true, Int64: t.Unix()}
+	}
+	q := database.New(h.DB)
+	err := q.UpdateDueByPath(ctx, database.UpdateDueByPathParams{
+		Due: due,
+		Path: path,
+	})
+	if err != nil{
+		msg := "Couldn't update the due date."
+		log.Printf("%s\n Error: %v\n", msg, err)
+		http.Error(w, msg, http.StatusInternalServerError)
+		return
+	}
+	PublishFields(ctx, h.Live, q, path)
+	h.Due(w, r)
 }
 
+// Returns the rendered due date of an entry
+func (h *ChecklistHandler) Due(w http.ResponseWriter, r *http.Request){
+	ctx := r.Context()
+	path :=  mux.Vars(r)["id"]
+	q := database.New(h.DB)
+	entry, err := q.GetEntryByPath(ctx, path)
+	if err != nil{
+		http.Error(w, "Entry not found.", http.StatusNotFound)
+		return
+	}
+	tmpl := handlers.LoadTemplates([]string{"due.html"})
+	err = tmpl.ExecuteTemplate(w, "due.html", handlers.DueViewFor(entry.Due, entry.Status, time.Now()))
+	if err != nil{
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// Same as UpdateCheckedState for the text field of a task.
+// A rejected text is shown next to the current one, so it isn't lost.
 func (h *ChecklistHandler) UpdateText(w http.ResponseWriter, r *http.Request){
 	ctx := r.Context()
 	path :=  mux.Vars(r)["id"]
@@ -187,146 +450,266 @@ func (h *ChecklistHandler) UpdateText(w http.ResponseWriter, r *http.Request){
 		http.Error(w, "Unable to parse form", http.StatusBadRequest)
 		return
 	}
-	text :=  r.Form.Get("text")
-	var alteredItem Item
-	alteredItem = Item{
-		Task: r.Form.Get("task"),
-		Text: &text,
+	task := r.Form.Get("task")
+	text := r.Form.Get("text")
+	seen, err := seenRevision(r)
+	if err != nil {
+		http.Error(w, "Invalid revision.", http.StatusBadRequest)
+		return
 	}
 	q := database.New(h.DB)
-  entry, err := q.GetEntryByPath(ctx, path)
+	entry, err := q.GetEntryByPath(ctx, path)
 	if err != nil {
 		http.Error(w,err.Error(), http.StatusInternalServerError)
 		return
 	}
-  var oldItems []*Item
-	var y string
-	if entry.Yaml.Valid{
-		y = entry.Yaml.String
-	}else{
-		msg := "Text field couldn't get updated. 'yaml'-field wasn't valid."
-		log.Println(msg)
+	tx, err := h.DB.BeginTx(ctx, nil)
+	if err != nil{
+		http.Error(w, "Database error.", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+	qtx := q.WithTx(tx)
+	revision, err := qtx.NextRevisionByID(ctx, entry.ID)
+	if err != nil{
+		msg := "Text field couldn't get updated."
+		log.Printf("%s\n Error: %v\n", msg, err)
 		http.Error(w, msg, http.StatusInternalServerError)
+		return
 	}
-  err = yaml.Unmarshal([]byte(y), &oldItems)
-  updateTextState(alteredItem, oldItems)
-  yamlBytes, err := yaml.Marshal(oldItems)
-
-  if err != nil {
-      log.Println("Error marshaling Yaml: ", err)
-      return
-  }
-
-	arg := database.UpdateYamlByPathParams{
-		Yaml: sql.NullString{Valid: true, String: string(yamlBytes)},
-		Path: path,
+	items, err := LoadItems(ctx, qtx, entry.ID)
+	if err != nil{
+		msg := "Text field couldn't get updated."
+		log.Printf("%s\n Error: %v\n", msg, err)
+		http.Error(w, msg, http.StatusInternalServerError)
+		return
 	}
-
-  q.UpdateYamlByPath(ctx, arg)
-	w.Write([]byte{})
+	item := FindItem(items, task)
+	if item == nil || item.Text == nil{
+		http.Error(w, "Unknown task.", http.StatusBadRequest)
+		return
+	}
+	if err := CheckRevision(item.TextRevision, seen, *item.Text == text); err != nil{
+		tx.Rollback()
+		notice := fmt.Sprintf("%s Deine Eingabe: %s", conflictNotice, text)
+		renderItem(w, http.StatusConflict, "item-text", path, item, notice)
+		return
+	}
+	if err := SaveText(ctx, qtx, entry.ID, task, text, revision); err != nil{
+		msg := "Text field couldn't get updated."
+		log.Printf("%s\n Error: %v\n", msg, err)
+		http.Error(w, msg, http.StatusInternalServerError)
+		return
+	}
+	item.Text = &text
+	item.TextRevision = revision
+	if err := UpdateAnswers(ctx, qtx, entry.ID, items); err != nil{
+		log.Printf("Couldn't update the search index.\n Error: %v\n", err)
+	}
+	if err := tx.Commit(); err != nil{
+		http.Error(w, "Database error.", http.StatusInternalServerError)
+		return
+	}
+	PublishItem(ctx, h.Live, q, entry.ID, path, item)
+	renderItem(w, http.StatusOK, "item-text", path, item, "")
 }
 
-func updateTextState(newItem Item, checklistSlice []*Item){
-  for _, item := range checklistSlice{
-    if newItem.Task == item.Task{
-			*item.Text = *newItem.Text
-      return
-    }
-		// Go into the lower levels
-    if len(item.Children) > 0 {
-			updateTextState(newItem, item.Children)
+func (h *ChecklistHandler) Print(w http.ResponseWriter, r *http.Request){
+	path :=  mux.Vars(r)["id"]
+	// Comments are only appended when asked for with ?comments=true
+	withComments := r.URL.Query().Get("comments") == "true"
+	// Gotenberg can take longer than the write timeout of the server (see -gotenberg-timeout).
+	// Not every ResponseWriter supports deadlines, then the server's timeout stays
+	if g, ok := h.PDF.(*pdf.Gotenberg); ok {
+		deadline := time.Time{}
+		if d := g.MaxRenderTime(); d > 0 {
+			// Loading the entry and archiving the pdf keep the usual 10 seconds
+			deadline = time.Now().Add(d + 10*time.Second)
 		}
-  }
+		http.NewResponseController(w).SetWriteDeadline(deadline)
+	}
+	pdfName, pdfBytes, err := RenderPDF(r, h.DB, h.PDF, h.Archive, path, withComments)
+	if errors.Is(err, context.Canceled) {
+		// The browser is gone, nobody reads the answer
+		return
+	}
+	if err != nil {
+		log.Printf("Couldn't create the pdf of '%s'.\n Error: %v\n", path, err)
+		status, msg := PDFError(err)
+		http.Error(w, msg, status)
+		return
+	}
+
+	// Setting the header before sending the file to the browser
+	w.Header().Set("Content-Type", "application/pdf")
+	disposition := fmt.Sprintf("attachment; filename=%s", pdfName)
+	w.Header().Set("Content-Disposition", disposition)
+
+	_, err = w.Write(pdfBytes)
+	if err != nil {
+		log.Printf("Couldn't send pdf to browser.\nError: %q \n", err)
+	}
 }
 
-func (h *ChecklistHandler) Print(w http.ResponseWriter, r *http.Request){
+// Returned by RenderPDF, when the pdf couldn't be archived.
+// The pdf isn't handed out then, because nobody could prove later what it contained.
+var ErrArchive = errors.New("couldn't archive the pdf")
+
+// Renders the entry to a pdf and returns it together with its name built from 'pdf_name_schema'.
+// Is used by Print() and when exporting multiple entries at once.
+// When 'arch' isn't nil, the pdf is archived for the user requesting it.
+func RenderPDF(r *http.Request, db *sql.DB, renderer pdf.Renderer, arch *archive.Archive, path string, withComments bool) (string, []byte, error){
 	ctx := r.Context()
-	path :=  mux.Vars(r)["id"]
 	tmpl := handlers.LoadTemplates([]string{"checklist/templates/print.html"})
 
-	q := database.New(h.DB)
+	q := database.New(db)
 	entry, err := q.GetEntryByPath(ctx, path)
-	var items []Item
-	var y string
-	if entry.Yaml.Valid{
-		y = entry.Yaml.String
-	}else{
-		msg := "Couldn't render the document. 'yaml'-field wasn't valid."
-		log.Println(msg)
-		http.Error(w, msg, http.StatusInternalServerError)
+	if err != nil{
+		return "", nil, fmt.Errorf("couldn't find entry '%s': %w", path, err)
 	}
-	err = yaml.Unmarshal([]byte(y), &items)
-
-	templateName, err := q.GetTemplateNameById(ctx, entry.TemplateID)
-	customFields, err := q.GetCustomFieldsByTemplateName(ctx, templateName)
-	result := handlers.BuildEntryViewForTemplate(customFields, &entry)
-	
-	var data map[string]string
-	err = json.Unmarshal([]byte(entry.Data), &data)
+	items, err := LoadItems(ctx, q, entry.ID)
 	if err != nil{
-		log.Fatalln("Unmarshaling json from db wen't wrong.")
-		return
+		return "", nil, err
 	}
-	if data == nil {
-		log.Fatalln("Error: data map is nil after unmarshaling.")
-	return
+
+	values, err := handlers.LoadValues(ctx, q, entry.ID)
+	if err != nil{
+		return "", nil, fmt.Errorf("couldn't load the values: %w", err)
 	}
+	result := handlers.BuildEntryViewForTemplate(values[entry.ID], &entry)
+	data := handlers.ValueMap(values[entry.ID])
 	
-	// Build pdf_name_schema from entry.Data
-	// Add date to the data-map because it is an extra field in the db and not present in entry.Data
-	nameSchema, err := q.GetPdfNamingByTemplateID(ctx, entry.TemplateID)
-	data["date"] = time.Now().Format("20060102")
-	var pdfName string
-	for i, desc := range nameSchema{
-		key := desc.Value
-		if i == len(nameSchema) - 1 {
-			pdfName += data[key] + ".pdf"
-		}else{
-			// Removes all <spaces> in the file name
-			val := strings.ReplaceAll(data[key], " ", "_")
-			pdfName += val + "_"
+	pdfName, err := fileName(ctx, q, entry.TemplateID, data, ".pdf")
+	if err != nil{
+		return "", nil, fmt.Errorf("couldn't build the name of the pdf: %w", err)
+	}
+
+	var comments []CommentView
+	if withComments{
+		comments, err = commentsForEntry(ctx, q, entry.ID)
+		if err != nil{
+			return "", nil, fmt.Errorf("couldn't load the comments: %w", err)
 		}
 	}
 
+	images, attachedPdfs, err := attachmentsForPrint(ctx, q, entry.ID)
+	if err != nil{
+		return "", nil, fmt.Errorf("couldn't load the attachments: %w", err)
+	}
+
+	date := time.Now().Format("02.01.2006, 15:04:05")
 	var buf bytes.Buffer
 	err = tmpl.Execute(&buf, map[string]any{
 		"Title": pdfName,
 		"Items": items,
 		"EntryView": result,
-		"Date": time.Now().Format("02.01.2006, 15:04:05"),
+		"Date": date,
+		"Comments": comments,
+		"Attachments": printImages(images),
 	})
-	bodyBytes, err := io.ReadAll(&buf)
 	if err != nil {
-		http.Error(w, "Failed to read request body", http.StatusBadRequest)
-		return
+		return "", nil, err
 	}
 
-	defer r.Body.Close() // Close the body after reading
-	// Reponse from gotenberg api
-	response, err := pdf.Generate(r, pdfName, bodyBytes)
+	// Everything the built-in renderer needs to draw the same content
+	doc := pdf.Document{
+		Name: pdfName,
+		HTML: buf.Bytes(),
+		Progress: fmt.Sprintf("%d%% (%d/%d)", result.Progress.Percent, result.Progress.Checked, result.Progress.Total),
+		Date: date,
+		Items: documentItems(items, images),
+		Images: images[""],
+		Attachments: attachedPdfs,
+	}
+	for _, d := range result.Data{
+		doc.Fields = append(doc.Fields, pdf.Field{Desc: d.Desc, Value: d.Value})
+	}
+	for _, c := range comments{
+		doc.Comments = append(doc.Comments, pdf.Comment{Author: c.Author, Date: c.Date, Body: c.Body})
+	}
+	pdfBytes, err := renderer.Render(ctx, doc)
 	if err != nil {
-		log.Printf("Couldn't send pdf to browser.\nError: %q \n", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return 
+		return "", nil, err
+	}
+	if arch != nil{
+		// 'entry' was loaded before rendering, so its revision is the one printed
+		if _, err := arch.Save(ctx, q, entry, doc.ContentHash(), arch.User(r), pdfName, pdfBytes); err != nil{
+			return "", nil, fmt.Errorf("%w: %w", ErrArchive, err)
+		}
 	}
-	defer response.Body.Close()
+	return pdfName, pdfBytes, nil
+}
 
-	// Setting the header before sending the file to the browser
-	w.Header().Set("Content-Type", "application/pdf")
-	disposition := fmt.Sprintf("attachment; filename=%s", pdfName)
-	w.Header().Set("Content-Disposition", disposition)
+// Builds the name of an exported file from 'pdf_name_schema' and the values of the entry.
+// 'ext' is appended, e.g. ".pdf".
+func fileName(ctx context.Context, q *database.Queries, templateID int64, data map[string]string, ext string) (string, error){
+	nameSchema, err := q.GetPdfNamingByTemplateID(ctx, templateID)
+	if err != nil{
+		return "", err
+	}
+	// Add date to the data-map because it is an extra field in the db and not a custom field
+	data["date"] = time.Now().Format("20060102")
+	var name string
+	for i, desc := range nameSchema{
+		key := desc.Value
+		if i == len(nameSchema) - 1 {
+			name += data[key] + ext
+		}else{
+			// Removes all <spaces> in the file name
+			val := strings.ReplaceAll(data[key], " ", "_")
+			name += val + "_"
+		}
+	}
+	return name, nil
+}
 
-	_, err = io.Copy(w, response.Body)
-	if err != nil {
-		log.Fatalf("Couldn't send pdf to browser.\nError: %q \n", err)
+// Status and message shown to the user, when creating a pdf failed
+func PDFError(err error) (int, string) {
+	var status *pdf.StatusError
+	switch {
+	case errors.Is(err, ErrArchive):
+		return http.StatusInternalServerError, "Die PDF konnte nicht archiviert werden und wird deshalb nicht ausgegeben. Bitte später erneut versuchen."
+	case errors.Is(err, sql.ErrNoRows):
+		return http.StatusNotFound, "Eintrag nicht gefunden."
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout, "Gotenberg hat nicht rechtzeitig geantwortet. Bitte später erneut versuchen."
+	case errors.Is(err, pdf.ErrUnavailable):
+		return http.StatusBadGateway, "Gotenberg ist nicht erreichbar. Bitte später erneut versuchen."
+	case errors.As(err, &status):
+		return http.StatusBadGateway, "Gotenberg konnte die PDF nicht erstellen."
+	}
+	return http.StatusInternalServerError, "Die PDF konnte nicht erstellt werden."
+}
+
+func documentItems(items []*Item, images map[string][]pdf.Image) []pdf.Item {
+	var result []pdf.Item
+	for _, item := range items{
+		result = append(result, pdf.Item{
+			Task: item.Task,
+			Checked: item.Checked,
+			Text: item.Text,
+			Images: images[item.Task],
+			Children: documentItems(item.Children, images),
+		})
 	}
+	return result
 }
 
 func (h *ChecklistHandler) Delete(w http.ResponseWriter, r *http.Request){
 	ctx := r.Context()
 	path := r.FormValue("path")
 	q := database.New(h.DB)
-	q.DeleteEntryByPath(ctx,path)
+	// Entry is moved into the trash and can be restored on /delete
+	err := q.SoftDeleteEntryByPath(ctx, database.SoftDeleteEntryByPathParams{
+		DeletedAt: sql.NullInt64{Valid: true, Int64: time.Now().Unix()},
+		Path: path,
+	})
+	if err != nil{
+		msg := "Couldn't move the entry into the trash."
+		log.Printf("%s\n Error: %v\n", msg, err)
+		http.Error(w,msg,http.StatusInternalServerError)
+		return
+	}
 
 	// Special header for htmx
 	w.Header().Set("HX-Redirect", "/all")
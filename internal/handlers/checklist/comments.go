@@ -0,0 +1,81 @@
+package checklist
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/hmaier-dev/checklist-tool/internal/database"
+	"github.com/hmaier-dev/checklist-tool/internal/handlers"
+)
+
+// Single comment below the checklist
+type CommentView struct {
+	Author string
+	Date   string
+	Body   string
+}
+
+// Returns the comment thread of an entry in the order they were written
+func commentsForEntry(ctx context.Context, q *database.Queries, entryID int64) ([]CommentView, error) {
+	comments, err := q.GetCommentsByEntryID(ctx, entryID)
+	if err != nil {
+		return nil, err
+	}
+	var view = make([]CommentView, len(comments))
+	for i, c := range comments {
+		view[i] = CommentView{
+			Author: c.Author,
+			Date:   time.Unix(c.Date, 0).Format("02.01.2006 15:04"),
+			Body:   c.Body,
+		}
+	}
+	return view, nil
+}
+
+// Adds a comment to the thread and returns the whole thread as html
+func (h *ChecklistHandler) Comment(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	path := mux.Vars(r)["id"]
+	author := strings.TrimSpace(r.FormValue("author"))
+	body := strings.TrimSpace(r.FormValue("body"))
+	if author == "" || body == "" {
+		http.Error(w, "Name und Kommentar dürfen nicht leer sein.", http.StatusBadRequest)
+		return
+	}
+	q := database.New(h.DB)
+	entry, err := q.GetEntryByPath(ctx, path)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	err = q.InsertComment(ctx, database.InsertCommentParams{
+		EntryID: entry.ID,
+		Author:  author,
+		Body:    body,
+		Date:    time.Now().Unix(),
+	})
+	if err != nil {
+		msg := "Couldn't save the comment."
+		log.Printf("%s\n Error: %v\n", msg, err)
+		http.Error(w, msg, http.StatusInternalServerError)
+		return
+	}
+	comments, err := commentsForEntry(ctx, q, entry.ID)
+	if err != nil {
+		msg := "Couldn't load the comments."
+		log.Printf("%s\n Error: %v\n", msg, err)
+		http.Error(w, msg, http.StatusInternalServerError)
+		return
+	}
+	tmpl := handlers.LoadTemplates([]string{"checklist/templates/comments.html"})
+	err = tmpl.ExecuteTemplate(w, "comments.html", map[string]any{
+		"Comments": comments,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
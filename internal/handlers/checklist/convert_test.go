@@ -0,0 +1,104 @@
+//go:build sqlite_fts5
+
+package checklist
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/hmaier-dev/checklist-tool/internal/database/dbtest"
+)
+
+// An entry as written by the first version: values as json in 'data', the state in 'yaml'
+const baselineData = `
+INSERT INTO templates (id, name, empty_yaml) VALUES (1, 'geräte', '
+- task: "Auspacken"
+  checked: false
+- task: "IMEI notieren"
+  checked: false
+  text: ""
+');
+INSERT INTO custom_fields (id, template_id, key, desc) VALUES (1, 1, 'name', 'Name'), (2, 1, 'imei', 'IMEI');
+INSERT INTO entries (id, template_id, data, path, yaml, date) VALUES (1, 1, '{"name":"Max","imei":"3569"}', 'path', '
+- task: "Auspacken"
+  checked: true
+- task: "IMEI notieren"
+  checked: false
+  text: "Seriennummer 4711"
+', 1);
+`
+
+// Same steps as the startup in main.go
+func TestMigrateBaseline(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.Baseline(t)
+	if _, err := db.Exec(baselineData); err != nil {
+		t.Fatal(err)
+	}
+	dbtest.Migrate(t, db)
+	if err := ConvertYaml(ctx, db); err != nil {
+		t.Fatal(err)
+	}
+	if err := BackfillProgress(ctx, db); err != nil {
+		t.Fatal(err)
+	}
+	if err := BackfillAnswers(ctx, db); err != nil {
+		t.Fatal(err)
+	}
+
+	values := make(map[string]string)
+	rows, err := db.Query("SELECT cf.key, ev.value FROM entry_values ev JOIN custom_fields cf ON cf.id = ev.field_id WHERE ev.entry_id = 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			t.Fatal(err)
+		}
+		values[key] = value
+	}
+	if len(values) != 2 || values["name"] != "Max" || values["imei"] != "3569" {
+		t.Errorf("expected the values of 'data' in entry_values, got %v", values)
+	}
+
+	var checked sql.NullBool
+	var text sql.NullString
+	if err := db.QueryRow("SELECT checked FROM item_states WHERE entry_id = 1 AND task = 'Auspacken'").Scan(&checked); err != nil {
+		t.Fatal(err)
+	}
+	if !checked.Valid || !checked.Bool {
+		t.Errorf("expected 'Auspacken' to be checked, got %v", checked)
+	}
+	if err := db.QueryRow("SELECT text FROM item_states WHERE entry_id = 1 AND task = 'IMEI notieren'").Scan(&text); err != nil {
+		t.Fatal(err)
+	}
+	if text.String != "Seriennummer 4711" {
+		t.Errorf("expected the text answer, got %v", text)
+	}
+
+	var yaml, answers sql.NullString
+	var progress, total int
+	err = db.QueryRow("SELECT yaml, answers, progress_checked, progress_total FROM entries WHERE id = 1").Scan(&yaml, &answers, &progress, &total)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if yaml.Valid {
+		t.Errorf("expected 'yaml' to be cleared, got %q", yaml.String)
+	}
+	if progress != 1 || total != 2 {
+		t.Errorf("expected 1/2 checked, got %d/%d", progress, total)
+	}
+	if answers.String != "Seriennummer 4711" {
+		t.Errorf("expected the answers to be filled in, got %v", answers)
+	}
+	// The search index finds the entry by a value and by an answer
+	for _, term := range []string{"3569", "4711"} {
+		var id int64
+		if err := db.QueryRow("SELECT rowid FROM entries_search WHERE entries_search MATCH ?", term).Scan(&id); err != nil || id != 1 {
+			t.Errorf("expected '%s' to find the entry, got %d (%v)", term, id, err)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package checklist
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/hmaier-dev/checklist-tool/internal/database"
+	"github.com/hmaier-dev/checklist-tool/internal/handlers"
+)
+
+// Single change in the history of an entry
+type EventView struct {
+	Date    string
+	Message string
+}
+
+// Returns the history of an entry from old to new
+func eventsForEntry(ctx context.Context, q *database.Queries, entryID int64) ([]EventView, error) {
+	events, err := q.GetEntryEventsByEntryID(ctx, entryID)
+	if err != nil {
+		return nil, err
+	}
+	var view = make([]EventView, len(events))
+	for i, e := range events {
+		view[i] = EventView{
+			Date:    time.Unix(e.Date, 0).Format("02.01.2006 15:04"),
+			Message: e.Message,
+		}
+	}
+	return view, nil
+}
+
+// Assigns the entry to another person and writes the change into its history
+func (h *ChecklistHandler) UpdateAssignee(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	path := mux.Vars(r)["id"]
+	q := database.New(h.DB)
+	assignee, err := handlers.ParseAssignee(ctx, q, r.FormValue("assignee"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	tx, err := h.DB.BeginTx(ctx, nil)
+	if err != nil {
+		http.Error(w, "Database error.", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+	qtx := q.WithTx(tx)
+	entry, err := qtx.GetEntryByPath(ctx, path)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	err = qtx.UpdateAssigneeByPath(ctx, database.UpdateAssigneeByPathParams{
+		AssigneeID: assignee,
+		Path:       path,
+	})
+	if err == nil {
+		err = handlers.RecordAssigneeChange(ctx, qtx, entry.ID, entry.AssigneeID, assignee)
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		msg := "Couldn't update the assignee."
+		log.Printf("%s\n Error: %v\n", msg, err)
+		http.Error(w, msg, http.StatusInternalServerError)
+		return
+	}
+	PublishFields(ctx, h.Live, q, path)
+	// The history below the checklist reloads itself
+	w.Header().Set("HX-Trigger", "historyChanged")
+	w.Write([]byte{})
+}
+
+// Returns the rendered history of an entry
+func (h *ChecklistHandler) Events(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	path := mux.Vars(r)["id"]
+	q := database.New(h.DB)
+	entry, err := q.GetEntryByPath(ctx, path)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	events, err := eventsForEntry(ctx, q, entry.ID)
+	if err != nil {
+		msg := "Couldn't load the history."
+		log.Printf("%s\n Error: %v\n", msg, err)
+		http.Error(w, msg, http.StatusInternalServerError)
+		return
+	}
+	tmpl := handlers.LoadTemplates([]string{"checklist/templates/events.html"})
+	err = tmpl.ExecuteTemplate(w, "events.html", map[string]any{
+		"Events": events,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,265 @@
+package checklist
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"gopkg.in/yaml.v3"
+
+	"github.com/hmaier-dev/checklist-tool/internal/database"
+	"github.com/hmaier-dev/checklist-tool/internal/handlers"
+)
+
+// Entry as written by the JSON and YAML export
+type ExportEntry struct {
+	Template string `json:"template" yaml:"template"`
+	Path     string `json:"path" yaml:"path"`
+	Status   string `json:"status" yaml:"status"`
+	// RFC 3339
+	Created  string         `json:"created" yaml:"created"`
+	Due      string         `json:"due,omitempty" yaml:"due,omitempty"`
+	Assignee string         `json:"assignee,omitempty" yaml:"assignee,omitempty"`
+	Progress ExportProgress `json:"progress" yaml:"progress"`
+	Fields   []ExportField  `json:"fields" yaml:"fields"`
+	Items    []ExportItem   `json:"items" yaml:"items"`
+	Exported string         `json:"exported" yaml:"exported"`
+}
+
+type ExportProgress struct {
+	Checked         int `json:"checked" yaml:"checked"`
+	Total           int `json:"total" yaml:"total"`
+	RequiredChecked int `json:"required_checked" yaml:"required_checked"`
+	RequiredTotal   int `json:"required_total" yaml:"required_total"`
+}
+
+type ExportField struct {
+	Key   string `json:"key" yaml:"key"`
+	Desc  string `json:"desc" yaml:"desc"`
+	Value string `json:"value" yaml:"value"`
+}
+
+type ExportItem struct {
+	Task     string       `json:"task" yaml:"task"`
+	Checked  bool         `json:"checked" yaml:"checked"`
+	Text     *string      `json:"text,omitempty" yaml:"text,omitempty"`
+	Required bool         `json:"required,omitempty" yaml:"required,omitempty"`
+	Children []ExportItem `json:"children,omitempty" yaml:"children,omitempty"`
+}
+
+// Formats of ?format= with their file extension and content type
+var exportFormats = map[string]struct {
+	ext         string
+	contentType string
+}{
+	"json":     {".json", "application/json"},
+	"yaml":     {".yaml", "application/yaml; charset=utf-8"},
+	"markdown": {".md", "text/markdown; charset=utf-8"},
+	"csv":      {".csv", "text/csv; charset=utf-8"},
+}
+
+// Downloads the entry in the format given by ?format=.
+// Is also called by Display(), when /checklist/{id} has the parameter.
+func (h *ChecklistHandler) Export(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	path := mux.Vars(r)["id"]
+	format := r.URL.Query().Get("format")
+	if format == "pdf" {
+		h.Print(w, r)
+		return
+	}
+	f, ok := exportFormats[format]
+	if !ok {
+		http.Error(w, fmt.Sprintf("Unbekanntes Format '%s'. Möglich sind json, yaml, markdown, csv und pdf.", format), http.StatusBadRequest)
+		return
+	}
+
+	q := database.New(h.DB)
+	entry, err := q.GetEntryByPath(ctx, path)
+	if err != nil {
+		http.Error(w, "Eintrag nicht gefunden.", http.StatusNotFound)
+		return
+	}
+	export, err := exportEntry(r, q, entry)
+	if err != nil {
+		msg := "Couldn't load the entry for the export."
+		log.Printf("%s\n Error: %v\n", msg, err)
+		http.Error(w, msg, http.StatusInternalServerError)
+		return
+	}
+
+	var buf bytes.Buffer
+	switch format {
+	case "json":
+		enc := json.NewEncoder(&buf)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(export)
+	case "yaml":
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		err = enc.Encode(export)
+	case "markdown":
+		writeMarkdown(&buf, export)
+	case "csv":
+		err = writeCSV(&buf, export.Items)
+	}
+	if err != nil {
+		msg := "Couldn't write the export."
+		log.Printf("%s\n Error: %v\n", msg, err)
+		http.Error(w, msg, http.StatusInternalServerError)
+		return
+	}
+
+	values := make(map[string]string)
+	for _, field := range export.Fields {
+		values[field.Key] = field.Value
+	}
+	name, err := fileName(ctx, q, entry.TemplateID, values, f.ext)
+	if err != nil || name == f.ext {
+		name = entry.Path + f.ext
+	}
+	w.Header().Set("Content-Type", f.contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", name))
+	w.Write(buf.Bytes())
+}
+
+func exportEntry(r *http.Request, q *database.Queries, entry database.Entry) (ExportEntry, error) {
+	ctx := r.Context()
+	templateName, err := q.GetTemplateNameById(ctx, entry.TemplateID)
+	if err != nil {
+		return ExportEntry{}, fmt.Errorf("couldn't load the template: %w", err)
+	}
+	values, err := handlers.LoadValues(ctx, q, entry.ID)
+	if err != nil {
+		return ExportEntry{}, fmt.Errorf("couldn't load the values: %w", err)
+	}
+	items, err := LoadItems(ctx, q, entry.ID)
+	if err != nil {
+		return ExportEntry{}, err
+	}
+	export := ExportEntry{
+		Template: templateName,
+		Path:     entry.Path,
+		Status:   entry.Status,
+		Created:  time.Unix(entry.Date.Int64, 0).Format(time.RFC3339),
+		Progress: ExportProgress{
+			Checked:         int(entry.ProgressChecked),
+			Total:           int(entry.ProgressTotal),
+			RequiredChecked: int(entry.RequiredChecked),
+			RequiredTotal:   int(entry.RequiredTotal),
+		},
+		Fields:   []ExportField{},
+		Items:    exportItems(items),
+		Exported: time.Now().Format(time.RFC3339),
+	}
+	if entry.Due.Valid {
+		export.Due = time.Unix(entry.Due.Int64, 0).Format(time.RFC3339)
+	}
+	if entry.AssigneeID.Valid {
+		person, err := q.GetPersonByID(ctx, entry.AssigneeID.Int64)
+		if err != nil {
+			return ExportEntry{}, fmt.Errorf("couldn't load the assignee: %w", err)
+		}
+		export.Assignee = person.Name
+	}
+	for _, v := range values[entry.ID] {
+		export.Fields = append(export.Fields, ExportField{Key: v.Key, Desc: v.Desc, Value: v.Value})
+	}
+	return export, nil
+}
+
+func exportItems(items []*Item) []ExportItem {
+	result := []ExportItem{}
+	for _, item := range items {
+		result = append(result, ExportItem{
+			Task:     item.Task,
+			Checked:  item.Checked,
+			Text:     item.Text,
+			Required: item.Required,
+			Children: exportItems(item.Children),
+		})
+	}
+	return result
+}
+
+// Task list, which can be pasted into tickets
+func writeMarkdown(buf *bytes.Buffer, export ExportEntry) {
+	fmt.Fprintf(buf, "# %s\n\n", export.Template)
+	buf.WriteString("| Feld | Wert |\n| --- | --- |\n")
+	row := func(desc, value string) {
+		fmt.Fprintf(buf, "| %s | %s |\n", markdownCell(desc), markdownCell(value))
+	}
+	for _, f := range export.Fields {
+		row(f.Desc, f.Value)
+	}
+	row("Status", handlers.StatusFor(export.Status).Label)
+	p := handlers.ProgressFor(int64(export.Progress.Checked), int64(export.Progress.Total),
+		int64(export.Progress.RequiredChecked), int64(export.Progress.RequiredTotal))
+	row("Fortschritt", fmt.Sprintf("%d%% (%d/%d)", p.Percent, p.Checked, p.Total))
+	if export.Assignee != "" {
+		row("Zuständig", export.Assignee)
+	}
+	if export.Due != "" {
+		due, _ := time.Parse(time.RFC3339, export.Due)
+		row("Fällig", due.Format("02.01.2006 15:04"))
+	}
+	buf.WriteString("\n")
+	markdownItems(buf, export.Items, "")
+}
+
+func markdownItems(buf *bytes.Buffer, items []ExportItem, indent string) {
+	for _, item := range items {
+		mark := " "
+		if item.Checked {
+			mark = "x"
+		}
+		fmt.Fprintf(buf, "%s- [%s] %s\n", indent, mark, strings.ReplaceAll(item.Task, "\n", " "))
+		// Lines indented like the text of the item belong to it
+		if item.Text != nil && *item.Text != "" {
+			for _, line := range strings.Split(*item.Text, "\n") {
+				fmt.Fprintf(buf, "%s  > %s\n", indent, line)
+			}
+		}
+		markdownItems(buf, item.Children, indent+"  ")
+	}
+}
+
+// Tables can't hold line breaks and use '|' as separator
+func markdownCell(s string) string {
+	s = strings.ReplaceAll(s, "|", "\\|")
+	return strings.ReplaceAll(s, "\n", " ")
+}
+
+// One row per item with the tasks of its parents as path
+func writeCSV(buf *bytes.Buffer, items []ExportItem) error {
+	cw := csv.NewWriter(buf)
+	cw.Write([]string{"Punkt", "Abgehakt", "Antwort"})
+	var walk func(items []ExportItem, parent string)
+	walk = func(items []ExportItem, parent string) {
+		for _, item := range items {
+			path := item.Task
+			if parent != "" {
+				path = parent + " > " + item.Task
+			}
+			checked := "nein"
+			if item.Checked {
+				checked = "ja"
+			}
+			text := ""
+			if item.Text != nil {
+				text = *item.Text
+			}
+			cw.Write([]string{path, checked, text})
+			walk(item.Children, path)
+		}
+	}
+	walk(items, "")
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,54 @@
+package checklist
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriteMarkdownAndCSV(t *testing.T) {
+	answer := "IMEI 123\nSIM 456"
+	export := ExportEntry{
+		Template: "Gerät einrichten",
+		Status:   "in_progress",
+		Progress: ExportProgress{Checked: 1, Total: 3},
+		Fields:   []ExportField{{Key: "name", Desc: "Name", Value: "Max | Muster"}},
+		Items: []ExportItem{
+			{Task: "Auspacken", Checked: true, Children: []ExportItem{{Task: "Zubehör"}}},
+			{Task: "Notieren", Text: &answer},
+		},
+	}
+
+	var md bytes.Buffer
+	writeMarkdown(&md, export)
+	want := `# Gerät einrichten
+
+| Feld | Wert |
+| --- | --- |
+| Name | Max \| Muster |
+| Status | In Bearbeitung |
+| Fortschritt | 33% (1/3) |
+
+- [x] Auspacken
+  - [ ] Zubehör
+- [ ] Notieren
+  > IMEI 123
+  > SIM 456
+`
+	if md.String() != want {
+		t.Errorf("unexpected markdown:\n%s", md.String())
+	}
+
+	var csv bytes.Buffer
+	if err := writeCSV(&csv, export.Items); err != nil {
+		t.Fatal(err)
+	}
+	want = `Punkt,Abgehakt,Antwort
+Auspacken,ja,
+Auspacken > Zubehör,nein,
+Notieren,nein,"IMEI 123
+SIM 456"
+`
+	if csv.String() != want {
+		t.Errorf("unexpected csv:\n%s", csv.String())
+	}
+}
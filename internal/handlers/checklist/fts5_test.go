@@ -0,0 +1,11 @@
+//go:build !sqlite_fts5
+
+package checklist
+
+import "testing"
+
+// The states, the exports and the attachments are tested against a real database.
+// Without '-tags sqlite_fts5' they aren't built, this fails the run instead of skipping them silently.
+func TestRequiresFTS5(t *testing.T) {
+	t.Fatal("run the tests with '-tags sqlite_fts5', see fts5.go")
+}
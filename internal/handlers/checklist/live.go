@@ -0,0 +1,103 @@
+package checklist
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/hmaier-dev/checklist-tool/internal/database"
+	"github.com/hmaier-dev/checklist-tool/internal/handlers"
+	"github.com/hmaier-dev/checklist-tool/internal/live"
+)
+
+// Changes made on one page of an entry are sent to all other open pages of it
+// over Server-Sent Events (see static/htmx-ext-sse.js).
+// Events are named like the elements they replace:
+// 'item-<hash of the task>', 'status', 'assignee', 'due' and 'presence'.
+// 'progress' and 'history' carry no data, the elements reload themselves.
+
+// Id of the row of the item on the page and the name of its event
+func (i *Item) ID() string {
+	h := fnv.New64a()
+	h.Write([]byte(i.Task))
+	return fmt.Sprintf("item-%x", h.Sum64())
+}
+
+// Event stream of an entry.
+// Stays open until the browser leaves the page or the server shuts down.
+func (h *ChecklistHandler) Stream(w http.ResponseWriter, r *http.Request) {
+	path := mux.Vars(r)["id"]
+	q := database.New(h.DB)
+	if _, err := q.GetEntryByPath(r.Context(), path); err != nil {
+		http.Error(w, "Entry not found.", http.StatusNotFound)
+		return
+	}
+	h.Live.Serve(w, r, path, func() { h.publishPresence(path) })
+}
+
+// Renders a template of the live parts of the page
+func renderLive(name string, data any) (string, error) {
+	tmpl := handlers.LoadTemplates([]string{
+		"checklist/templates/items.html",
+		"checklist/templates/fields.html",
+		"checklist/templates/attachments.html",
+		"due.html",
+	})
+	var buf bytes.Buffer
+	err := tmpl.ExecuteTemplate(&buf, name, data)
+	return buf.String(), err
+}
+
+// Sends the rendered template to all open pages of the entry
+func publish(hub *live.Hub, path string, event string, name string, data any) {
+	html, err := renderLive(name, data)
+	if err != nil {
+		log.Printf("Couldn't render '%s' for the open pages of '%s'.\n Error: %v\n", name, path, err)
+		return
+	}
+	hub.Publish(path, live.Event{Name: event, Data: html})
+}
+
+// Tells the open pages of the entry to reload an element, e.g. 'progress'
+func Notify(hub *live.Hub, path string, event string) {
+	hub.Publish(path, live.Event{Name: event})
+}
+
+// Sends the row of a changed item to all open pages of the entry
+func PublishItem(ctx context.Context, hub *live.Hub, q *database.Queries, entryID int64, path string, item *Item) {
+	attachments, err := attachmentsForEntry(ctx, q, entryID)
+	if err != nil {
+		log.Printf("Couldn't load the attachments of '%s'.\n Error: %v\n", path, err)
+		return
+	}
+	publish(hub, path, item.ID(), "item-row", []any{path, item, attachments[item.Task]})
+}
+
+// Sends the status, assignee and due date to all open pages of the entry
+func PublishFields(ctx context.Context, hub *live.Hub, q *database.Queries, path string) {
+	entry, err := q.GetEntryByPath(ctx, path)
+	if err != nil {
+		log.Printf("Couldn't load '%s' for its open pages.\n Error: %v\n", path, err)
+		return
+	}
+	people, err := q.GetAllPeople(ctx)
+	if err != nil {
+		log.Printf("Couldn't load all people.\n Error: %v\n", err)
+		return
+	}
+	due := handlers.DueViewFor(entry.Due, entry.Status, time.Now())
+	publish(hub, path, "status", "status-field", []any{path, handlers.Statuses, entry.Status})
+	publish(hub, path, "assignee", "assignee-field", []any{path, people, entry.AssigneeID.Int64})
+	publish(hub, path, "due", "due-field", []any{path, due})
+	Notify(hub, path, "history")
+}
+
+// Tells all open pages of the entry how many pages are open
+func (h *ChecklistHandler) publishPresence(path string) {
+	publish(h.Live, path, "presence", "presence", h.Live.Count(path))
+}
@@ -0,0 +1,127 @@
+//go:build sqlite_fts5
+
+package checklist
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"github.com/hmaier-dev/checklist-tool/internal/archive"
+	"github.com/hmaier-dev/checklist-tool/internal/database"
+	"github.com/hmaier-dev/checklist-tool/internal/pdf"
+	"github.com/hmaier-dev/checklist-tool/internal/pdf/gotenbergtest"
+)
+
+// Values read by RenderPDF in addition to testDB
+const printData = `
+INSERT INTO custom_fields (id, template_id, key, desc) VALUES (1, 1, 'name', 'Name');
+INSERT INTO entry_values VALUES (1, 1, 'Max');
+INSERT INTO pdf_name_schema (template_id, value) VALUES (1, 'name');
+INSERT INTO attachments (entry_id, task, filename, content_type, size, data, date)
+  VALUES (1, NULL, 'rechnung.pdf', 'application/pdf', 5, '-RE-', 1);
+`
+
+func printEntry(t *testing.T, h *ChecklistHandler, path string) *httptest.ResponseRecorder {
+	t.Helper()
+	r := httptest.NewRequest("GET", "/checklist/print/"+path, nil)
+	r = mux.SetURLVars(r, map[string]string{"id": path})
+	w := httptest.NewRecorder()
+	h.Print(w, r)
+	return w
+}
+
+func TestPrint(t *testing.T) {
+	// print.html is loaded relative to the root of the repository
+	t.Chdir("../../..")
+	db := testDB(t, 1)
+	if _, err := db.Exec(printData); err != nil {
+		t.Fatal(err)
+	}
+	fake := gotenbergtest.NewServer(t)
+	h := &ChecklistHandler{DB: db, PDF: &pdf.Gotenberg{URL: fake.URL, Retries: 1}}
+
+	w := printEntry(t, h, "path")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body)
+	}
+	if got := w.Header().Get("Content-Disposition"); got != "attachment; filename=Max.pdf" {
+		t.Errorf("unexpected Content-Disposition %q", got)
+	}
+	body := w.Body.Bytes()
+	if !bytes.HasPrefix(body, []byte("%PDF-fake\n")) || !bytes.Contains(body, []byte("Task 0.8")) {
+		t.Errorf("expected the printed checklist, got %q", body)
+	}
+	// The attached pdf is appended
+	if !bytes.HasSuffix(body, []byte("-RE-")) {
+		t.Errorf("expected the attachment at the end")
+	}
+
+	// gotenberg is down
+	fake.Fail(http.StatusServiceUnavailable, http.StatusServiceUnavailable)
+	w = printEntry(t, h, "path")
+	if w.Code != http.StatusBadGateway || !strings.Contains(w.Body.String(), "Gotenberg ist nicht erreichbar") {
+		t.Errorf("expected 502, got %d: %s", w.Code, w.Body)
+	}
+
+	w = printEntry(t, h, "unknown")
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", w.Code)
+	}
+}
+
+func TestPrintArchive(t *testing.T) {
+	t.Chdir("../../..")
+	db := testDB(t, 1)
+	if _, err := db.Exec(printData); err != nil {
+		t.Fatal(err)
+	}
+	fake := gotenbergtest.NewServer(t)
+	h := &ChecklistHandler{
+		DB:      db,
+		PDF:     &pdf.Gotenberg{URL: fake.URL, Retries: 1},
+		Archive: &archive.Archive{Store: &archive.Dir{Path: t.TempDir()}},
+	}
+
+	r := httptest.NewRequest("GET", "/checklist/print/path?user=Anna", nil)
+	r = mux.SetURLVars(r, map[string]string{"id": "path"})
+	w := httptest.NewRecorder()
+	h.Print(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body)
+	}
+	exports, err := database.New(db).GetPdfExportsByEntryPath(context.Background(), "path")
+	if err != nil || len(exports) != 1 {
+		t.Fatalf("expected one archived pdf, got %d (%v)", len(exports), err)
+	}
+	if e := exports[0]; e.RequestedBy != "Anna" || e.Filename != "Max.pdf" || e.Size != int64(w.Body.Len()) || len(e.ContentSha256) != 64 {
+		t.Errorf("unexpected record %+v", e)
+	}
+
+	// The archived pdf is the one handed out, not a new one
+	r = httptest.NewRequest("GET", "/checklist/archive/1", nil)
+	r = mux.SetURLVars(r, map[string]string{"id": "1"})
+	archived := httptest.NewRecorder()
+	h.ArchivedPDF(archived, r)
+	if archived.Code != http.StatusOK || !bytes.Equal(archived.Body.Bytes(), w.Body.Bytes()) {
+		t.Errorf("expected the archived pdf, got %d", archived.Code)
+	}
+
+	// Without a working archive, nothing is handed out
+	file := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(file, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	h.Archive.Store = &archive.Dir{Path: file}
+	w = printEntry(t, h, "path")
+	if w.Code != http.StatusInternalServerError || !strings.Contains(w.Body.String(), "archiviert") {
+		t.Errorf("expected 500, got %d: %s", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,72 @@
+package checklist
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+
+	"github.com/hmaier-dev/checklist-tool/internal/database"
+	"github.com/hmaier-dev/checklist-tool/internal/handlers"
+)
+
+// Counts the checked items including all children
+func CountProgress(items []*Item) handlers.ProgressView {
+	var checked, total, requiredChecked, requiredTotal int64
+	var count func(items []*Item)
+	count = func(items []*Item) {
+		for _, item := range items {
+			total += 1
+			if item.Required {
+				requiredTotal += 1
+			}
+			if item.Checked {
+				checked += 1
+				if item.Required {
+					requiredChecked += 1
+				}
+			}
+			count(item.Children)
+		}
+	}
+	count(items)
+	return handlers.ProgressFor(checked, total, requiredChecked, requiredTotal)
+}
+
+// Progress of the children of an item. Used by checklist.html.
+func (i *Item) Progress() handlers.ProgressView {
+	return CountProgress(i.Children)
+}
+
+// Stores the progress of 'items' for an entry.
+// Has to be called whenever a checked state of an entry or its template changes.
+func UpdateProgress(ctx context.Context, q *database.Queries, entryID int64, items []*Item) error {
+	p := CountProgress(items)
+	return q.UpdateProgressByID(ctx, database.UpdateProgressByIDParams{
+		ProgressChecked: int64(p.Checked),
+		ProgressTotal:   int64(p.Total),
+		RequiredChecked: int64(p.RequiredChecked),
+		RequiredTotal:   int64(p.RequiredTotal),
+		ID:              entryID,
+	})
+}
+
+// Computes the progress for entries created before it was stored
+func BackfillProgress(ctx context.Context, db *sql.DB) error {
+	q := database.New(db)
+	entries, err := q.GetEntriesWithoutProgress(ctx)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		items, err := LoadItems(ctx, q, e.ID)
+		if err != nil {
+			log.Printf("Couldn't compute the progress of entry %d.\n Error: %v\n", e.ID, err)
+			continue
+		}
+		if err := UpdateProgress(ctx, q, e.ID, items); err != nil {
+			return fmt.Errorf("entry %d: %w", e.ID, err)
+		}
+	}
+	return nil
+}
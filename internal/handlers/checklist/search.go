@@ -0,0 +1,44 @@
+package checklist
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/hmaier-dev/checklist-tool/internal/database"
+)
+
+// Stores the text answers of 'items' in 'entries.answers', which is part of the search index.
+// Has to be called whenever a text answer of an entry changes.
+func UpdateAnswers(ctx context.Context, q *database.Queries, entryID int64, items []*Item) error {
+	var answers []string
+	for _, item := range TextAnswers(items) {
+		answers = append(answers, strings.TrimSpace(*item.Text))
+	}
+	return q.UpdateAnswersByID(ctx, database.UpdateAnswersByIDParams{
+		Answers: sql.NullString{Valid: true, String: strings.Join(answers, " | ")},
+		ID:      entryID,
+	})
+}
+
+// Fills in the answers for entries created before they were indexed
+func BackfillAnswers(ctx context.Context, db *sql.DB) error {
+	q := database.New(db)
+	entries, err := q.GetEntriesWithoutAnswers(ctx)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		items, err := LoadItems(ctx, q, e.ID)
+		if err != nil {
+			log.Printf("Couldn't read the answers of entry %d.\n Error: %v\n", e.ID, err)
+			continue
+		}
+		if err := UpdateAnswers(ctx, q, e.ID, items); err != nil {
+			return fmt.Errorf("entry %d: %w", e.ID, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package checklist
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/hmaier-dev/checklist-tool/internal/database"
+)
+
+// The structure of a checklist is stored once per template in 'templates.empty_yaml'.
+// Entries that were kept at an older version of their template
+// point to a copy of it in 'template_versions' instead.
+// What is checked or filled in per entry lives in 'item_states', one row per task,
+// so a click only writes a single row.
+
+// Returns the checklist of an entry: the items of its template with the state of the entry
+func LoadItems(ctx context.Context, q *database.Queries, entryID int64) ([]*Item, error) {
+	templateYaml, err := q.GetChecklistYamlByEntryID(ctx, entryID)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't load the template: %w", err)
+	}
+	return LoadItemsFor(ctx, q, entryID, templateYaml)
+}
+
+// Same as LoadItems, but with the yaml of the template already loaded
+func LoadItemsFor(ctx context.Context, q *database.Queries, entryID int64, templateYaml string) ([]*Item, error) {
+	var items []*Item
+	if err := yaml.Unmarshal([]byte(templateYaml), &items); err != nil {
+		return nil, fmt.Errorf("error while unmarshaling yaml: %w", err)
+	}
+	states, err := q.GetItemStatesByEntryID(ctx, entryID)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't load the item states: %w", err)
+	}
+	ApplyStates(items, states)
+	return items, nil
+}
+
+// Overwrites the defaults of the template with the states of an entry.
+// Like everywhere else, only the first item with a task has a state.
+// Text answers are only shown, when the item still has a text field.
+func ApplyStates(items []*Item, states []database.ItemState) {
+	byTask := make(map[string]database.ItemState, len(states))
+	for _, s := range states {
+		byTask[s.Task] = s
+	}
+	seen := make(map[string]bool)
+	var apply func(items []*Item)
+	apply = func(items []*Item) {
+		for _, item := range items {
+			if s, ok := byTask[item.Task]; ok && !seen[item.Task] {
+				if s.Checked.Valid {
+					item.Checked = s.Checked.Bool
+					item.CheckedRevision = s.CheckedRevision
+				}
+				if s.Text.Valid && item.Text != nil {
+					text := s.Text.String
+					item.Text = &text
+					item.TextRevision = s.TextRevision
+				}
+			}
+			seen[item.Task] = true
+			apply(item.Children)
+		}
+	}
+	apply(items)
+}
+
+// Returns the first item with this task or nil
+func FindItem(items []*Item, task string) *Item {
+	for _, item := range items {
+		if item.Task == task {
+			return item
+		}
+		if found := FindItem(item.Children, task); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// Returned when an item was changed by someone else
+// after the client has seen it.
+var ErrConflict = errors.New("the item was changed in the meantime")
+
+// Checks the revision a client has seen against the last change of a checkbox or text field.
+// A newer change only conflicts, when the client would overwrite it with something else.
+// Changes to other items are not a conflict, both are kept.
+func CheckRevision(revision int64, seen int64, same bool) error {
+	if revision > seen && !same {
+		return ErrConflict
+	}
+	return nil
+}
+
+// Stores the checked state of a single task.
+// 'revision' is the new revision of the entry, see database.NextRevisionByID.
+// Getting it first in a transaction also takes the write lock,
+// so clicks arriving at the same time wait for each other instead of failing.
+func SaveChecked(ctx context.Context, q *database.Queries, entryID int64, task string, checked bool, revision int64) error {
+	return q.SetItemChecked(ctx, database.SetItemCheckedParams{
+		EntryID:         entryID,
+		Task:            task,
+		Checked:         sql.NullBool{Valid: true, Bool: checked},
+		CheckedRevision: revision,
+	})
+}
+
+// Stores the text answer of a single task, see SaveChecked
+func SaveText(ctx context.Context, q *database.Queries, entryID int64, task string, text string, revision int64) error {
+	return q.SetItemText(ctx, database.SetItemTextParams{
+		EntryID:      entryID,
+		Task:         task,
+		Text:         sql.NullString{Valid: true, String: text},
+		TextRevision: revision,
+	})
+}
+
+// Stores the state of all items, e.g. when converting an old 'yaml'
+func SaveStates(ctx context.Context, q *database.Queries, entryID int64, items []*Item) error {
+	revision, err := q.NextRevisionByID(ctx, entryID)
+	if err != nil {
+		return err
+	}
+	seen := make(map[string]bool)
+	var save func(items []*Item) error
+	save = func(items []*Item) error {
+		for _, item := range items {
+			if !seen[item.Task] {
+				seen[item.Task] = true
+				if err := SaveChecked(ctx, q, entryID, item.Task, item.Checked, revision); err != nil {
+					return err
+				}
+				if item.Text != nil {
+					if err := SaveText(ctx, q, entryID, item.Task, *item.Text, revision); err != nil {
+						return err
+					}
+				}
+			}
+			if err := save(item.Children); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return save(items)
+}
+
+// Moves the state of entries created before 'item_states' out of 'entries.yaml'.
+// Every entry is converted in its own transaction.
+func ConvertYaml(ctx context.Context, db *sql.DB) error {
+	entries, err := database.New(db).GetEntriesWithYaml(ctx)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		var items []*Item
+		if err := yaml.Unmarshal([]byte(e.Yaml.String), &items); err != nil {
+			log.Printf("Couldn't convert the checklist of entry %d.\n Error: %v\n", e.ID, err)
+			continue
+		}
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		qtx := database.New(db).WithTx(tx)
+		if err := SaveStates(ctx, qtx, e.ID, items); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("entry %d: %w", e.ID, err)
+		}
+		if err := qtx.ClearYamlByID(ctx, e.ID); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("entry %d: %w", e.ID, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	if len(entries) > 0 {
+		log.Printf("Converted the checklists of %d entries\n", len(entries))
+	}
+	return nil
+}
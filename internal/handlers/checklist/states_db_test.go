@@ -0,0 +1,187 @@
+//go:build sqlite_fts5
+
+package checklist
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/hmaier-dev/checklist-tool/internal/database"
+	"github.com/hmaier-dev/checklist-tool/internal/database/dbtest"
+)
+
+// Checklist with 'groups' items, which have 9 children each
+func testYaml(groups int) string {
+	var b strings.Builder
+	for i := 0; i < groups; i++ {
+		fmt.Fprintf(&b, "- task: \"Task %d\"\n  checked: false\n  children:\n", i)
+		for j := 0; j < 9; j++ {
+			fmt.Fprintf(&b, "    - task: \"Task %d.%d\"\n      checked: false\n", i, j)
+		}
+	}
+	return b.String()
+}
+
+// Database at the current version with the template 'test' and its entry 'path'
+func testDB(tb testing.TB, groups int) *sql.DB {
+	tb.Helper()
+	db := dbtest.Open(tb)
+	y := testYaml(groups)
+	_, err := db.Exec(`
+INSERT INTO templates (id, name, empty_yaml) VALUES (1, 'test', ?);
+INSERT INTO entries (id, template_id, path, yaml) VALUES (1, 1, 'path', ?);`, y, y)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return db
+}
+
+// Same steps as ChecklistHandler.UpdateCheckedState
+func toggle(ctx context.Context, db *sql.DB, task string, checked bool) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	qtx := database.New(db).WithTx(tx)
+	revision, err := qtx.NextRevisionByID(ctx, 1)
+	if err != nil {
+		return err
+	}
+	items, err := LoadItems(ctx, qtx, 1)
+	if err != nil {
+		return err
+	}
+	if err := SaveChecked(ctx, qtx, 1, task, checked, revision); err != nil {
+		return err
+	}
+	item := FindItem(items, task)
+	item.Checked = checked
+	item.CheckedRevision = revision
+	if err := UpdateProgress(ctx, qtx, 1, items); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func TestToggleConcurrently(t *testing.T) {
+	ctx := context.Background()
+	db := testDB(t, 5)
+	var wg sync.WaitGroup
+	errs := make(chan error, 50)
+	for i := 0; i < 5; i++ {
+		for j := 0; j < 9; j++ {
+			wg.Add(1)
+			go func(task string) {
+				defer wg.Done()
+				errs <- toggle(ctx, db, task, true)
+			}(fmt.Sprintf("Task %d.%d", i, j))
+		}
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	var checked, total int
+	if err := db.QueryRow("SELECT progress_checked, progress_total FROM entries WHERE id = 1").Scan(&checked, &total); err != nil {
+		t.Fatal(err)
+	}
+	// No click got lost
+	if checked != 45 || total != 50 {
+		t.Errorf("expected 45/50 checked, got %d/%d", checked, total)
+	}
+}
+
+func TestCheckRevision(t *testing.T) {
+	ctx := context.Background()
+	db := testDB(t, 1)
+	q := database.New(db)
+	// Both clients loaded the page before anything was changed
+	seen := int64(0)
+	if err := toggle(ctx, db, "Task 0.0", true); err != nil {
+		t.Fatal(err)
+	}
+	items, err := LoadItems(ctx, q, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changed := FindItem(items, "Task 0.0")
+	if changed.CheckedRevision != 1 {
+		t.Fatalf("expected revision 1, got %d", changed.CheckedRevision)
+	}
+	// Other items are merged
+	if err := CheckRevision(FindItem(items, "Task 0.1").CheckedRevision, seen, false); err != nil {
+		t.Errorf("expected a change of another item to be accepted, got %v", err)
+	}
+	// The same item would be overwritten
+	if err := CheckRevision(changed.CheckedRevision, seen, false); !errors.Is(err, ErrConflict) {
+		t.Errorf("expected a conflict, got %v", err)
+	}
+	// Setting the same state loses nothing
+	if err := CheckRevision(changed.CheckedRevision, seen, true); err != nil {
+		t.Errorf("expected the same state to be accepted, got %v", err)
+	}
+	// After reloading the item the change is accepted
+	if err := CheckRevision(changed.CheckedRevision, changed.CheckedRevision, false); err != nil {
+		t.Errorf("expected a change with the current revision to be accepted, got %v", err)
+	}
+	if err := toggle(ctx, db, "Task 0.1", true); err != nil {
+		t.Fatal(err)
+	}
+	var revision int64
+	if err := db.QueryRow("SELECT revision FROM entries WHERE id = 1").Scan(&revision); err != nil {
+		t.Fatal(err)
+	}
+	if revision != 2 {
+		t.Errorf("expected the entry to be at revision 2, got %d", revision)
+	}
+}
+
+// The former way: read the whole yaml of the entry, change it and write it back
+func toggleYaml(ctx context.Context, db *sql.DB, task string, checked bool) error {
+	var y string
+	if err := db.QueryRowContext(ctx, "SELECT yaml FROM entries WHERE path = 'path'").Scan(&y); err != nil {
+		return err
+	}
+	var items []*Item
+	if err := yaml.Unmarshal([]byte(y), &items); err != nil {
+		return err
+	}
+	SetChecked(items, task, checked)
+	b, err := yaml.Marshal(items)
+	if err != nil {
+		return err
+	}
+	if _, err := db.ExecContext(ctx, "UPDATE entries SET yaml = ? WHERE path = 'path'", string(b)); err != nil {
+		return err
+	}
+	return UpdateProgress(ctx, database.New(db), 1, items)
+}
+
+func benchmarkToggle(b *testing.B, groups int, toggle func(context.Context, *sql.DB, string, bool) error) {
+	ctx := context.Background()
+	db := testDB(b, groups)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		task := fmt.Sprintf("Task %d.%d", i%groups, i%9)
+		if err := toggle(ctx, db, task, i%2 == 0); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkToggleYaml10(b *testing.B)       { benchmarkToggle(b, 1, toggleYaml) }
+func BenchmarkToggleItemState10(b *testing.B)  { benchmarkToggle(b, 1, toggle) }
+func BenchmarkToggleYaml200(b *testing.B)      { benchmarkToggle(b, 20, toggleYaml) }
+func BenchmarkToggleItemState200(b *testing.B) { benchmarkToggle(b, 20, toggle) }
@@ -0,0 +1,50 @@
+package checklist
+
+import (
+	"database/sql"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/hmaier-dev/checklist-tool/internal/database"
+)
+
+func TestApplyStates(t *testing.T) {
+	var items []*Item
+	err := yaml.Unmarshal([]byte(`
+- task: "A"
+  checked: true
+  children:
+    - task: "B"
+      checked: false
+      text: "default"
+- task: "C"
+  checked: false
+- task: "B"
+  checked: false
+`), &items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ApplyStates(items, []database.ItemState{
+		// NULL keeps the default of the template
+		{Task: "A", Text: sql.NullString{Valid: true, String: "ignored"}},
+		{Task: "B", Checked: sql.NullBool{Valid: true, Bool: true}, Text: sql.NullString{Valid: true, String: "answer"}},
+		{Task: "C", Checked: sql.NullBool{Valid: true, Bool: true}},
+		{Task: "removed", Checked: sql.NullBool{Valid: true, Bool: true}},
+	})
+	b := items[0].Children[0]
+	if !items[0].Checked || items[0].Text != nil {
+		t.Errorf("expected the default of 'A', got %+v", items[0])
+	}
+	if !b.Checked || b.Text == nil || *b.Text != "answer" {
+		t.Errorf("expected the state of 'B', got %+v", b)
+	}
+	if !items[1].Checked {
+		t.Errorf("expected 'C' to be checked")
+	}
+	// Only the first item with a task has a state
+	if items[2].Checked {
+		t.Errorf("expected the second 'B' to keep its default")
+	}
+}
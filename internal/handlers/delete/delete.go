@@ -5,6 +5,7 @@ import (
 	"log"
 	"net/http"
 	"database/sql"
+	"time"
 
 	"github.com/gorilla/mux"
 
@@ -16,6 +17,7 @@ import (
 type DeleteHandler struct{
 	Router *mux.Router
 	DB *sql.DB
+	PurgeAfterDays int
 }
 
 var _ handlers.ActionHandler = (*DeleteHandler)(nil)
@@ -23,6 +25,7 @@ var _ handlers.ActionHandler = (*DeleteHandler)(nil)
 func (h *DeleteHandler) New(srv *server.Server){
 	h.Router = srv.Router	
 	h.DB = srv.DB
+	h.PurgeAfterDays = srv.Config.PurgeAfterDays
 }
 
 // Sets /delete and all subroutes
@@ -30,8 +33,19 @@ func (h *DeleteHandler)	Routes(){
 	sub := h.Router.PathPrefix("/delete").Subrouter()
 	sub.HandleFunc("", h.Display).Methods("GET")
 	sub.HandleFunc("/entries", h.Entries).Methods("GET")
+	sub.HandleFunc("/trash", h.Trash).Methods("GET")
 	sub.HandleFunc("", h.Execute).Methods("POST")
+	sub.HandleFunc("/restore", h.Restore).Methods("POST")
+	sub.HandleFunc("/purge", h.Purge).Methods("POST")
+}
 
+// Entry in the trash
+type TrashView struct {
+	handlers.EntryView
+	DeletedAt string
+	// Human-readable time until the entry gets purged automatically.
+	// Is empty when purging is disabled.
+	PurgeIn string
 }
 
 // Return rendered html for GET to /delete
@@ -39,67 +53,188 @@ func (h *DeleteHandler)	Display(w http.ResponseWriter, r *http.Request){
 	ctx := r.Context()
 	var templates = []string{
 		"delete/templates/delete.html",
-		"delete/templates/entries.html",
+		"delete/templates/trash.html",
+		"browser.html",
+		"due.html",
+		"progress.html",
 		"nav.html",
 		"header.html",
 	}
   tmpl := handlers.LoadTemplates(templates)
-	q := database.New(h.DB)
-	entries, err := q.GetAllEntriesPlusTemplateName(ctx)
-	var view []handlers.EntryView = make([]handlers.EntryView, len(entries))
-	for i, entry := range entries{
-		view[i] = handlers.ViewForEntry(h.DB, ctx, entry)
+	browser, err := h.browse(r)
+	if err != nil{
+		msg := "Couldn't load the entries."
+		log.Printf("%s\n Error: %v\n", msg, err)
+		http.Error(w,msg,http.StatusInternalServerError)
+		return
+	}
+	options, err := handlers.LoadBrowserOptions(ctx, h.DB)
+	if err != nil{
+		log.Printf("Couldn't load the options of the list.\n Error: %v\n", err)
+	}
+	trash, err := h.trashView(r)
+	if err != nil{
+		msg := "Couldn't load the trash."
+		log.Printf("%s\n Error: %v\n", msg, err)
+		http.Error(w,msg,http.StatusInternalServerError)
+		return
 	}
 	err = tmpl.Execute(w, map[string]any{
-		"Entries": view,
+		"Browser": browser,
+		"Options": options,
+		"Trash": trash,
+		"PurgeAfterDays": h.PurgeAfterDays,
   })
 
   if err != nil {
     http.Error(w, err.Error(), http.StatusInternalServerError)
-    log.Fatalf("%q \n", err)
   }
 
 }
 
-// Returns all entries for a 'template' into deleteEntries.html
+// Returns a page of the entries, which can be moved into the trash
 func (h *DeleteHandler)	Entries(w http.ResponseWriter, r *http.Request){
-	ctx := r.Context()
-	var templates = []string{
-		"delete/templates/entries.html",
+	tmpl := handlers.LoadTemplates([]string{"browser.html", "due.html", "progress.html"})
+	browser, err := h.browse(r)
+	if err != nil{
+		msg := "Couldn't load the entries."
+		log.Printf("%s\n Error: %v\n", msg, err)
+		http.Error(w,msg,http.StatusBadRequest)
+		return
 	}
-  tmpl := handlers.LoadTemplates(templates)
-	q := database.New(h.DB)
-	entries, err := q.GetAllEntriesPlusTemplateName(ctx)
+	handlers.PushBrowserURL(w, r, browser)
+	err = tmpl.ExecuteTemplate(w, "browser.html", browser)
 	if err != nil{
-		msg := "Couldn't load all entries plus template name."
-		log.Println(msg)
+		fmt.Fprintf(w,"Error while load template.\n %q \n", err)
+	}
+}
+
+func (h *DeleteHandler) browse(r *http.Request) (handlers.BrowserView, error){
+	browser, err := handlers.Browse(r.Context(), h.DB, handlers.ParseBrowserQuery(r.URL.Query()))
+	browser.Page = "/delete"
+	browser.Endpoint = "/delete/entries"
+	browser.Delete = true
+	return browser, err
+}
+
+// Returns only the trash. Used to refresh it after a bulk action.
+func (h *DeleteHandler)	Trash(w http.ResponseWriter, r *http.Request){
+	tmpl := handlers.LoadTemplates([]string{"delete/templates/trash.html"})
+	trash, err := h.trashView(r)
+	if err != nil{
+		msg := "Couldn't load the trash."
+		log.Printf("%s\n Error: %v\n", msg, err)
 		http.Error(w,msg,http.StatusInternalServerError)
 		return
 	}
-	var view []handlers.EntryView = make([]handlers.EntryView, len(entries))
-	for i, entry := range entries{
-		view[i] = handlers.ViewForEntry(h.DB, ctx, entry)
-	}
 	err = tmpl.Execute(w, map[string]any{
-		"Entries": view,
+		"Trash": trash,
+		"PurgeAfterDays": h.PurgeAfterDays,
 	})
 	if err != nil{
 		fmt.Fprintf(w,"Error while load template.\n %q \n", err)
 	}
 }
 
-// Removes entry from 'entries'-table by the 'path'-column
+// Moves entry into the trash by the 'path'-column
 func (h *DeleteHandler)	Execute(w http.ResponseWriter, r *http.Request){
 	ctx := r.Context()
 	path := r.FormValue("path")
 	q := database.New(h.DB)
-	q.DeleteEntryByPath(ctx,path)
+	err := q.SoftDeleteEntryByPath(ctx, database.SoftDeleteEntryByPathParams{
+		DeletedAt: sql.NullInt64{Valid: true, Int64: time.Now().Unix()},
+		Path: path,
+	})
+	if err != nil{
+		msg := "Couldn't move the entry into the trash."
+		log.Printf("%s\n Error: %v\n", msg, err)
+		http.Error(w,msg,http.StatusInternalServerError)
+		return
+	}
 
 	// Special header for htmx
 	w.Header().Set("HX-Redirect", "/delete")
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// Takes an entry out of the trash
+func (h *DeleteHandler) Restore(w http.ResponseWriter, r *http.Request){
+	ctx := r.Context()
+	path := r.FormValue("path")
+	q := database.New(h.DB)
+	err := q.RestoreEntryByPath(ctx, path)
+	if err != nil{
+		msg := "Couldn't restore the entry."
+		log.Printf("%s\n Error: %v\n", msg, err)
+		http.Error(w,msg,http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("HX-Redirect", "/delete")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Removes an entry from the trash for good
+func (h *DeleteHandler) Purge(w http.ResponseWriter, r *http.Request){
+	ctx := r.Context()
+	path := r.FormValue("path")
+	q := database.New(h.DB)
+	err := q.PurgeEntryByPath(ctx, path)
+	if err != nil{
+		msg := "Couldn't purge the entry."
+		log.Printf("%s\n Error: %v\n", msg, err)
+		http.Error(w,msg,http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("HX-Redirect", "/delete")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *DeleteHandler) trashView(r *http.Request) ([]TrashView, error){
+	ctx := r.Context()
+	q := database.New(h.DB)
+	deleted, err := q.GetDeletedEntriesPlusTemplateName(ctx)
+	if err != nil{
+		return nil, err
+	}
+	var view = make([]TrashView, len(deleted))
+	for i, d := range deleted{
+		// ViewForEntry doesn't care about the deletion date
+		entry := database.GetAllEntriesPlusTemplateNameRow{
+			ID: d.ID,
+			Path: d.Path,
+			Yaml: d.Yaml,
+			Date: d.Date,
+			Status: d.Status,
+			Due: d.Due,
+			AssigneeID: d.AssigneeID,
+			ProgressChecked: d.ProgressChecked,
+			ProgressTotal: d.ProgressTotal,
+			RequiredChecked: d.RequiredChecked,
+			RequiredTotal: d.RequiredTotal,
+			TemplateName: d.TemplateName,
+		}
+		deletedAt := time.Unix(d.DeletedAt.Int64, 0)
+		var purgeIn string
+		if h.PurgeAfterDays > 0{
+			left := time.Until(deletedAt.AddDate(0, 0, h.PurgeAfterDays))
+			days := int(left.Hours() / 24)
+			if days < 1{
+				purgeIn = "weniger als einem Tag"
+			}else if days == 1{
+				purgeIn = "1 Tag"
+			}else{
+				purgeIn = fmt.Sprintf("%d Tagen", days)
+			}
+		}
+		view[i] = TrashView{
+			EntryView: handlers.ViewForEntry(h.DB, ctx, entry),
+			DeletedAt: deletedAt.Format("02.01.2006 15:04:05"),
+			PurgeIn: purgeIn,
+		}
+	}
+	return view, nil
+}
+
 func init(){
 	handlers.RegisterHandler(&DeleteHandler{})
 }
@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hmaier-dev/checklist-tool/internal/database"
+)
+
+// Entries due within this time are marked as due soon
+const DueSoon = 48 * time.Hour
+
+// Formats accepted for a due date, either typed in or read from a custom field.
+// Dates without time are due at the end of the day.
+var dateLayouts = []string{
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+	"02.01.2006 15:04",
+	"2006-01-02",
+	"02.01.2006",
+}
+
+// Reads a date like '2025-06-30' or '30.06.2025'
+func ParseDate(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range dateLayouts {
+		t, err := time.ParseInLocation(layout, s, time.Local)
+		if err != nil {
+			continue
+		}
+		if !strings.Contains(layout, "15") {
+			t = t.AddDate(0, 0, 1).Add(-time.Second)
+		}
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("'%s' is not a valid date", s)
+}
+
+// Reads 'due_in' from the frontmatter, e.g. '3d', '12h' or '2w'
+func ParseDueIn(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if len(s) < 2 {
+		return 0, fmt.Errorf("'%s' is not a valid duration", s)
+	}
+	n, err := strconv.Atoi(s[:len(s)-1])
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("'%s' is not a valid duration", s)
+	}
+	switch s[len(s)-1] {
+	case 'h':
+		return time.Duration(n) * time.Hour, nil
+	case 'd':
+		return time.Duration(n) * 24 * time.Hour, nil
+	case 'w':
+		return time.Duration(n) * 7 * 24 * time.Hour, nil
+	}
+	return 0, fmt.Errorf("'%s' has an unknown unit, use h, d or w", s)
+}
+
+// Computes the due date of a new entry.
+// A date set by the user wins over 'due_field', which wins over 'due_in'.
+// Without any of them the entry has no due date.
+func ComputeDue(template database.Template, data map[string]string, explicit string, created time.Time) (sql.NullInt64, error) {
+	if strings.TrimSpace(explicit) != "" {
+		t, err := ParseDate(explicit)
+		if err != nil {
+			return sql.NullInt64{}, err
+		}
+		return sql.NullInt64{Valid: true, Int64: t.Unix()}, nil
+	}
+	if template.DueField.Valid && template.DueField.String != "" {
+		if val := data[template.DueField.String]; strings.TrimSpace(val) != "" {
+			t, err := ParseDate(val)
+			if err != nil {
+				return sql.NullInt64{}, err
+			}
+			return sql.NullInt64{Valid: true, Int64: t.Unix()}, nil
+		}
+	}
+	if template.DueIn.Valid && template.DueIn.String != "" {
+		d, err := ParseDueIn(template.DueIn.String)
+		if err != nil {
+			return sql.NullInt64{}, err
+		}
+		return sql.NullInt64{Valid: true, Int64: created.Add(d).Unix()}, nil
+	}
+	return sql.NullInt64{}, nil
+}
+
+// Due date of an entry as shown in the lists and on the checklist
+type DueView struct {
+	// Unix time, 0 when the entry has no due date
+	At   int64
+	Date string
+	// Value for <input type="datetime-local">
+	Input     string
+	Remaining string
+	Overdue   bool
+	Soon      bool
+}
+
+func DueViewFor(due sql.NullInt64, status string, now time.Time) DueView {
+	if !due.Valid {
+		return DueView{}
+	}
+	t := time.Unix(due.Int64, 0)
+	view := DueView{
+		At:    due.Int64,
+		Date:  t.Format("02.01.2006 15:04"),
+		Input: t.Format("2006-01-02T15:04"),
+	}
+	// Finished entries can't be late anymore
+	if status == "done" {
+		return view
+	}
+	left := t.Sub(now)
+	if left < 0 {
+		view.Overdue = true
+		view.Remaining = FormatDuration(-left) + " überfällig"
+		return view
+	}
+	view.Soon = left < DueSoon
+	view.Remaining = "noch " + FormatDuration(left)
+	return view
+}
+
+// Rounds to the largest fitting unit, e.g. '2 Tage' or '5 Stunden'
+func FormatDuration(d time.Duration) string {
+	switch {
+	case d >= 24*time.Hour:
+		return plural(int(d.Round(24*time.Hour)/(24*time.Hour)), "Tag", "Tage")
+	case d >= time.Hour:
+		return plural(int(d.Round(time.Hour)/time.Hour), "Stunde", "Stunden")
+	default:
+		return plural(int(d.Round(time.Minute)/time.Minute), "Minute", "Minuten")
+	}
+}
+
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return "1 " + one
+	}
+	return fmt.Sprintf("%d %s", n, many)
+}
+
+// Options for the select boxes above the lists
+var DueFilters = []Status{
+	{Value: "", Label: "Alle"},
+	{Value: "overdue", Label: "Überfällig"},
+	{Value: "soon", Label: "Bald fällig"},
+	{Value: "none", Label: "Ohne Fälligkeit"},
+}
+
+var Sorts = []Status{
+	{Value: "", Label: "Erstellungsdatum"},
+	{Value: "due", Label: "Fälligkeit"},
+	{Value: "progress", Label: "Fortschritt"},
+}
+
+// Keeps the entries matching 'filter' (due date) and sorts them by 'sortBy'.
+// "due" puts entries without due date last,
+// "progress" starts with the least finished entries.
+func SortAndFilter(view []EntryView, sortBy, filter string) []EntryView {
+	var result []EntryView
+	for _, e := range view {
+		switch filter {
+		case "overdue":
+			if !e.Due.Overdue {
+				continue
+			}
+		case "soon":
+			if !e.Due.Soon && !e.Due.Overdue {
+				continue
+			}
+		case "none":
+			if e.Due.At != 0 {
+				continue
+			}
+		}
+		result = append(result, e)
+	}
+	switch sortBy {
+	case "due":
+		sort.SliceStable(result, func(i, j int) bool {
+			a, b := result[i].Due.At, result[j].Due.At
+			if a == 0 || b == 0 {
+				return b == 0 && a != 0
+			}
+			return a < b
+		})
+	case "progress":
+		sort.SliceStable(result, func(i, j int) bool {
+			return result[i].Progress.Percent < result[j].Progress.Percent
+		})
+	}
+	return result
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/hmaier-dev/checklist-tool/internal/database"
+)
+
+func TestParseDueIn(t *testing.T) {
+	tests := []struct {
+		input string
+		want  time.Duration
+		err   bool
+	}{
+		{"12h", 12 * time.Hour, false},
+		{"3d", 72 * time.Hour, false},
+		{"2w", 14 * 24 * time.Hour, false},
+		{"3", 0, true},
+		{"d", 0, true},
+		{"3m", 0, true},
+		{"-1d", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseDueIn(tt.input)
+		if (err != nil) != tt.err {
+			t.Fatalf("'%s': unexpected error: %v", tt.input, err)
+		}
+		if got != tt.want {
+			t.Errorf("'%s': expected %v, got %v", tt.input, tt.want, got)
+		}
+	}
+}
+
+func TestComputeDue(t *testing.T) {
+	created := time.Date(2025, 6, 1, 10, 0, 0, 0, time.Local)
+	endOfDay := time.Date(2025, 6, 30, 23, 59, 59, 0, time.Local).Unix()
+	template := database.Template{
+		DueIn:    sql.NullString{Valid: true, String: "3d"},
+		DueField: sql.NullString{Valid: true, String: "handover"},
+	}
+	tests := []struct {
+		name     string
+		data     map[string]string
+		explicit string
+		want     int64
+	}{
+		{"set by user", map[string]string{"handover": "01.07.2025"}, "2025-06-30", endOfDay},
+		{"from field", map[string]string{"handover": "30.06.2025"}, "", endOfDay},
+		{"from due_in", map[string]string{"handover": ""}, "", created.Add(72 * time.Hour).Unix()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			due, err := ComputeDue(template, tt.data, tt.explicit, created)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !due.Valid || due.Int64 != tt.want {
+				t.Errorf("expected %d, got %+v", tt.want, due)
+			}
+		})
+	}
+	due, err := ComputeDue(database.Template{}, nil, "", created)
+	if err != nil || due.Valid {
+		t.Errorf("expected no due date without rule, got %+v (%v)", due, err)
+	}
+}
+
+func TestSortAndFilter(t *testing.T) {
+	now := time.Now()
+	at := func(d time.Duration) sql.NullInt64 {
+		return sql.NullInt64{Valid: true, Int64: now.Add(d).Unix()}
+	}
+	view := []EntryView{
+		{Path: "none", Due: DueViewFor(sql.NullInt64{}, "open", now), Progress: ProgressFor(1, 4, 0, 0)},
+		{Path: "later", Due: DueViewFor(at(10*24*time.Hour), "open", now), Progress: ProgressFor(0, 4, 0, 0)},
+		{Path: "overdue", Due: DueViewFor(at(-time.Hour), "open", now), Progress: ProgressFor(3, 4, 0, 0)},
+		{Path: "soon", Due: DueViewFor(at(time.Hour), "open", now), Progress: ProgressFor(2, 4, 0, 0)},
+		{Path: "done", Due: DueViewFor(at(-2*time.Hour), "done", now), Progress: ProgressFor(4, 4, 0, 0)},
+	}
+	paths := func(v []EntryView) []string {
+		var result []string
+		for _, e := range v {
+			result = append(result, e.Path)
+		}
+		return result
+	}
+	tests := []struct {
+		sortBy, filter string
+		want           []string
+	}{
+		{"due", "", []string{"done", "overdue", "soon", "later", "none"}},
+		{"progress", "", []string{"later", "none", "soon", "overdue", "done"}},
+		{"", "overdue", []string{"overdue"}},
+		{"", "soon", []string{"overdue", "soon"}},
+		{"", "none", []string{"none"}},
+	}
+	for _, tt := range tests {
+		got := paths(SortAndFilter(view, tt.sortBy, tt.filter))
+		if len(got) != len(tt.want) {
+			t.Fatalf("sort '%s', filter '%s': expected %q, got %q", tt.sortBy, tt.filter, tt.want, got)
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("sort '%s', filter '%s': expected %q, got %q", tt.sortBy, tt.filter, tt.want, got)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/hmaier-dev/checklist-tool/internal/database"
+)
+
+// Kinds of 'entry_events'
+const (
+	EventAssignee = "assignee"
+	EventClone    = "clone"
+)
+
+// Writes a message into the history of an entry
+func RecordEvent(ctx context.Context, q *database.Queries, entryID int64, kind, msg string) error {
+	return q.InsertEntryEvent(ctx, database.InsertEntryEventParams{
+		EntryID: entryID,
+		Kind:    kind,
+		Message: msg,
+		Date:    time.Now().Unix(),
+	})
+}
+
+// Writes the change of the assignee into the history of the entry.
+// Nothing is written, when the assignee stays the same.
+func RecordAssigneeChange(ctx context.Context, q *database.Queries, entryID int64, old, new sql.NullInt64) error {
+	if old == new {
+		return nil
+	}
+	name := func(id sql.NullInt64) string {
+		p, err := q.GetPersonByID(ctx, id.Int64)
+		if err != nil {
+			return "(gelöscht)"
+		}
+		return p.Name
+	}
+	var msg string
+	switch {
+	case !old.Valid:
+		msg = fmt.Sprintf("Zugewiesen an %s", name(new))
+	case !new.Valid:
+		msg = fmt.Sprintf("Zuweisung an %s entfernt", name(old))
+	default:
+		msg = fmt.Sprintf("Neu zugewiesen von %s an %s", name(old), name(new))
+	}
+	return RecordEvent(ctx, q, entryID, EventAssignee, msg)
+}
@@ -1,9 +1,11 @@
 package handlers
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
 	"encoding/json"
+	"fmt"
 	"html/template"
 	"log"
 	"net/http"
@@ -12,6 +14,9 @@ import (
 	"reflect"
 	"time"
 
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+
 	"github.com/hmaier-dev/checklist-tool/internal/database"
 	"github.com/hmaier-dev/checklist-tool/internal/server"
 )
@@ -44,9 +49,48 @@ type EntryView struct {
 	TemplateName string
 	Date         string
 	Path         string
+	Status       Status
+	Due          DueView
+	Progress     ProgressView
+	AssigneeID   int64
+	// Name of the assignee, set by SetAssignees()
+	Assignee     string
 	Data         []DescValueView
 }
 
+// Workflow state of an entry, stored in 'entries.status'
+type Status struct {
+	Value string
+	Label string
+}
+
+// All states an entry can have, in the order they are passed through
+var Statuses = []Status{
+	{Value: "open", Label: "Offen"},
+	{Value: "in_progress", Label: "In Bearbeitung"},
+	{Value: "done", Label: "Erledigt"},
+}
+
+// Returns the Status for a value from the database.
+// Unknown values are displayed as they are.
+func StatusFor(value string) Status {
+	for _, s := range Statuses {
+		if s.Value == value {
+			return s
+		}
+	}
+	return Status{Value: value, Label: value}
+}
+
+func IsValidStatus(value string) bool {
+	for _, s := range Statuses {
+		if s.Value == value {
+			return true
+		}
+	}
+	return false
+}
+
 type DescValueView struct {
 	Desc  string
 	Value string
@@ -54,39 +98,19 @@ type DescValueView struct {
 }
 
 // Connects the description of a column with it's value for an array of entries.
-func BuildEntriesViewForTemplate(custom_fields []database.CustomField, entries []database.Entry) []EntryView {
+// 'values' is the result of LoadValues for the entries.
+func BuildEntriesViewForTemplate(values map[int64][]DescValueView, entries []database.Entry) []EntryView {
 	var result []EntryView
 	for _, entry := range entries {
-		result = append(result, BuildEntryViewForTemplate(custom_fields, &entry))
+		result = append(result, BuildEntryViewForTemplate(values[entry.ID], &entry))
 	}
 	return result
 }
 
 // Connects the description of a column with it's value for a single entry.
-// The DescValueView could also be build by a JOIN() in SQL.
-// Maybe refactor it in the function, to reduce the codebase.
-func BuildEntryViewForTemplate(custom_fields []database.CustomField, entry *database.Entry) EntryView {
-	var fieldsMap = make(map[string]string, len(custom_fields))
-	for _, field := range custom_fields {
-		fieldsMap[field.Key] = field.Desc
-	}
-	var dataMap map[string]string
-	err := json.Unmarshal([]byte(entry.Data), &dataMap)
-	if err != nil {
-		log.Fatalf("Error while unmarshaling json.\n Error: %q \n", err)
-	}
-	var viewMap []DescValueView
-	for _, field := range custom_fields {
-		if val, ok := dataMap[field.Key]; ok {
-			viewMap = append(viewMap, DescValueView{
-				Desc:  field.Desc,
-				Value: val,
-				Key:   field.Key,
-			})
-		} else {
-			log.Fatalf("Key '%s' not found in dataMap: '%q' \n", field.Key, dataMap)
-		}
-	}
+// The creation date is appended as last column.
+func BuildEntryViewForTemplate(values []DescValueView, entry *database.Entry) EntryView {
+	viewMap := append([]DescValueView{}, values...)
 	var t time.Time
 	if entry.Date.Valid{
 		t = time.Unix(entry.Date.Int64,0)		
@@ -100,6 +124,10 @@ func BuildEntryViewForTemplate(custom_fields []database.CustomField, entry *data
 	})
 	return EntryView{
 		Path: entry.Path,
+		Status: StatusFor(entry.Status),
+		Due: DueViewFor(entry.Due, entry.Status, time.Now()),
+		Progress: ProgressFor(entry.ProgressChecked, entry.ProgressTotal, entry.RequiredChecked, entry.RequiredTotal),
+		AssigneeID: entry.AssigneeID.Int64,
 		Data: viewMap,
 	}
 }
@@ -107,29 +135,16 @@ func BuildEntryViewForTemplate(custom_fields []database.CustomField, entry *data
 // This is just used in delete.go
 // What is the difference to the other EntryView returning function?
 func ViewForEntry(db *sql.DB, ctx context.Context, entry database.GetAllEntriesPlusTemplateNameRow) EntryView {
-	var dataMap map[string]string
-	err := json.Unmarshal([]byte(entry.Data), &dataMap)
+	values, err := LoadValues(ctx, database.New(db), entry.ID)
 	if err != nil {
-		log.Fatalf("Error while unmarshaling json.\n Error: %q \n", err)
-	}
-	var length int = len(dataMap)
-	var viewMap []DescValueView = make([]DescValueView, length)
-	q := database.New(db)
-	custom_fields, err := q.GetCustomFieldsByTemplateName(ctx, entry.TemplateName)
-	var count int = 0
-	// Use the order from the database-table 'custom_fields'
-	for _, field := range custom_fields {
-		if val, ok := dataMap[field.Key]; ok {
-			viewMap[count] = DescValueView{
-				Desc:  field.Desc,
-				Value: val,
-				Key:   field.Key,
-			}
-		} else {
-			log.Fatalf("Key '%s' not found in dataMap: '%q' \n", field.Key, dataMap)
-		}
-		count += 1
+		log.Printf("Couldn't query the values of '%s'.\n Error: %v\n", entry.Path, err)
 	}
+	return ViewForEntryWithValues(values[entry.ID], entry)
+}
+
+// Same as ViewForEntry, but with the values already loaded.
+// Used by the entries-browser, which loads the values of a whole page at once.
+func ViewForEntryWithValues(values []DescValueView, entry database.GetAllEntriesPlusTemplateNameRow) EntryView {
 	var t time.Time
 	if entry.Date.Valid{
 		t = time.Unix(entry.Date.Int64, 0)		
@@ -140,8 +155,102 @@ func ViewForEntry(db *sql.DB, ctx context.Context, entry database.GetAllEntriesP
 		TemplateName: entry.TemplateName,
 		Date:         t.Format("02.01.2006 15:04:05"),
 		Path:         entry.Path,
-		Data:         viewMap,
+		Status:       StatusFor(entry.Status),
+		Due:          DueViewFor(entry.Due, entry.Status, time.Now()),
+		Progress:     ProgressFor(entry.ProgressChecked, entry.ProgressTotal, entry.RequiredChecked, entry.RequiredTotal),
+		AssigneeID:   entry.AssigneeID.Int64,
+		Data:         values,
+	}
+}
+
+// Loads the values of the custom fields for some entries, keyed by the id of the entry.
+// They are in the order of the fields in the template.
+// Fields without a value are included with an empty one.
+func LoadValues(ctx context.Context, q *database.Queries, ids ...int64) (map[int64][]DescValueView, error) {
+	result := make(map[int64][]DescValueView, len(ids))
+	if len(ids) == 0 {
+		return result, nil
 	}
+	rows, err := q.GetEntryValuesByEntryIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		result[row.EntryID] = append(result[row.EntryID], DescValueView{
+			Desc:  row.Desc,
+			Value: row.Value,
+			Key:   row.Key,
+		})
+	}
+	return result, nil
+}
+
+// Same as LoadValues for all given entries
+func LoadValuesForEntries(ctx context.Context, q *database.Queries, entries []database.Entry) (map[int64][]DescValueView, error) {
+	ids := make([]int64, len(entries))
+	for i, e := range entries {
+		ids[i] = e.ID
+	}
+	return LoadValues(ctx, q, ids...)
+}
+
+// Values by their key, as used by tab_desc_schema and pdf_name_schema
+func ValueMap(values []DescValueView) map[string]string {
+	result := make(map[string]string, len(values))
+	for _, v := range values {
+		if v.Key != "" {
+			result[v.Key] = v.Value
+		}
+	}
+	return result
+}
+
+// GitHub flavored, so task lists and tables work as well.
+// Raw html in the input is not rendered by goldmark, so user input is safe to display.
+var md = goldmark.New(goldmark.WithExtensions(extension.GFM))
+
+// Renders user written Markdown to html.
+// Available in all templates as 'markdown'.
+func Markdown(source string) template.HTML {
+	var buf bytes.Buffer
+	if err := md.Convert([]byte(source), &buf); err != nil {
+		log.Printf("Couldn't render markdown.\n Error: %v\n", err)
+		return template.HTML(template.HTMLEscapeString(source))
+	}
+	return template.HTML(buf.String())
+}
+
+// Encodes the pairs of keys and values as json object for 'hx-vals'.
+// html/template only escapes the attribute, a quote in a value would still break the json.
+// Available in all templates as 'vals', e.g. hx-vals='{{ vals "id" .ID }}'.
+func Vals(pairs ...any) (string, error) {
+	if len(pairs)%2 != 0 {
+		return "", fmt.Errorf("vals needs pairs of keys and values, got %d arguments", len(pairs))
+	}
+	m := make(map[string]any, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		key, ok := pairs[i].(string)
+		if !ok {
+			return "", fmt.Errorf("key %v of vals is not a string", pairs[i])
+		}
+		m[key] = pairs[i+1]
+	}
+	b, err := json.Marshal(m)
+	return string(b), err
+}
+
+// Joins the values of 'data' in the order of 'tab_desc_schema'.
+// The result is used as browser-tab title and as label for an entry.
+func BuildTabDescription(schema []database.TabDescSchema, data map[string]string) string {
+	var result string
+	for i, t := range schema {
+		if i == len(schema)-1 {
+			result += data[t.Value]
+		} else {
+			result += data[t.Value] + " | "
+		}
+	}
+	return result
 }
 
 // Takes './internal/handlers' as base-path.
@@ -162,6 +271,8 @@ func LoadTemplates(paths []string) *template.Template {
 		"last": func(x int, a any) bool {
 				return x == reflect.ValueOf(a).Len() - 1
 		},
+		"markdown": Markdown,
+		"vals": Vals,
 	}
 	// add funcMap to base-template
 	first := filepath.Base(full[0])
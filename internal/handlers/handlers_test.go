@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"encoding/json"
+	"html"
+	"html/template"
+	"strings"
+	"testing"
+)
+
+func TestVals(t *testing.T) {
+	tmpl := template.Must(template.New("t").Funcs(template.FuncMap{"vals": Vals}).Parse(
+		`<button hx-vals='{{ vals "task" .Task "id" .ID }}'></button>`))
+	var b strings.Builder
+	task := `Kabel "rot" & 'blau'`
+	if err := tmpl.Execute(&b, map[string]any{"Task": task, "ID": 7}); err != nil {
+		t.Fatal(err)
+	}
+	attr := strings.TrimSuffix(strings.TrimPrefix(b.String(), `<button hx-vals='`), `'></button>`)
+	if strings.Contains(attr, "'") {
+		t.Fatalf("expected the quote to be escaped, got %s", attr)
+	}
+	// The browser unescapes the attribute before htmx parses the json
+	var got map[string]any
+	if err := json.Unmarshal([]byte(html.UnescapeString(attr)), &got); err != nil {
+		t.Fatalf("expected json, got %s: %v", attr, err)
+	}
+	if got["task"] != task || got["id"] != float64(7) {
+		t.Errorf("unexpected values %v", got)
+	}
+
+	if _, err := Vals("task"); err == nil {
+		t.Error("expected an error for a key without value")
+	}
+}
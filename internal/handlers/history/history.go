@@ -62,27 +62,29 @@ func (h *HistoryHandler)	Display(w http.ResponseWriter, r *http.Request) {
 		Path           string
 		// Actual content of the breadcrumb
 		TabDescription string
+		// Checked items in percent
+		Percent        int
 	}{}
 	// The values of a schema are organized in the table `tab_desc_schema`. 
 	// We access them by template_id (which is the primary key for all checklist metadata).
 	for _, entry := range entries{
 		completeSchema, err := q.GetTabDescriptionsByTemplateID(ctx, entry.TemplateID)
 		if err != nil{
-			msg := fmt.Sprintf("Could not fetch TabDescription for, Data: '%s', Path: '%s'", entry.Data, entry.Path)
+			msg := fmt.Sprintf("Could not fetch TabDescription for, Path: '%s'", entry.Path)
 			log.Println(msg)
 			http.Error(w, msg, http.StatusInternalServerError)
 			return
 		}
-		// The schema just have the keys, but we want the data which is in entry.Data
-		var data map[string]string
-		err = json.Unmarshal([]byte(entry.Data),&data)
+		// The schema just have the keys, but we want the values of the entry
+		values, err := handlers.LoadValues(ctx, q, entry.ID)
 		if err != nil{
-			msg := fmt.Sprintf("Could not unmarshal '%s' from 'entries'-table.", entry.Data)
+			msg := fmt.Sprintf("Could not load the values of '%s' from 'entry_values'-table.", entry.Path)
 			log.Println(msg)
 			http.Error(w, msg, http.StatusInternalServerError)
 			return
 		}
-		// This inner loop combines the values in entry.Data to the TabDescription
+		data := handlers.ValueMap(values[entry.ID])
+		// This inner loop combines the values to the TabDescription
 		var result string
 		for i, t := range completeSchema{
 			if i == len(completeSchema)-1 {
@@ -91,7 +93,11 @@ func (h *HistoryHandler)	Display(w http.ResponseWriter, r *http.Request) {
 				result += data[t.Value] + " | "
 			}
 		}
-		history = append(history, struct{Path string; TabDescription string}{Path: entry.Path, TabDescription: result})
+		history = append(history, struct{Path string; TabDescription string; Percent int}{
+			Path: entry.Path,
+			TabDescription: result,
+			Percent: handlers.Percent(int(entry.ProgressChecked), int(entry.ProgressTotal)),
+		})
 	}
 	slices.Reverse(history)
 	tmpl := handlers.LoadTemplates([]string{"history/templates/breadcrumb-history.html"})
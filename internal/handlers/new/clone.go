@@ -0,0 +1,91 @@
+package new
+
+import (
+	"context"
+
+	"github.com/hmaier-dev/checklist-tool/internal/database"
+	"github.com/hmaier-dev/checklist-tool/internal/handlers"
+	"github.com/hmaier-dev/checklist-tool/internal/handlers/checklist"
+)
+
+// Entry, which prefills the form on /?clone=<path>
+type cloneView struct {
+	Path       string
+	TemplateID int64
+	Label      string
+	Values     map[string]string
+	AssigneeID int64
+	// Items with a text answer, which can be carried over
+	Texts []*checklist.Item
+}
+
+func cloneViewFor(ctx context.Context, q *database.Queries, path string) (*cloneView, error) {
+	entry, err := q.GetEntryByPath(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	values, err := handlers.LoadValues(ctx, q, entry.ID)
+	if err != nil {
+		return nil, err
+	}
+	items, err := checklist.LoadItems(ctx, q, entry.ID)
+	if err != nil {
+		return nil, err
+	}
+	label, err := entryLabel(ctx, q, entry)
+	if err != nil {
+		return nil, err
+	}
+	return &cloneView{
+		Path:       entry.Path,
+		TemplateID: entry.TemplateID,
+		Label:      label,
+		Values:     handlers.ValueMap(values[entry.ID]),
+		AssigneeID: entry.AssigneeID.Int64,
+		Texts:      checklist.TextAnswers(items),
+	}, nil
+}
+
+func entryLabel(ctx context.Context, q *database.Queries, entry database.Entry) (string, error) {
+	schema, err := q.GetTabDescriptionsByTemplateID(ctx, entry.TemplateID)
+	if err != nil {
+		return "", err
+	}
+	values, err := handlers.LoadValues(ctx, q, entry.ID)
+	if err != nil {
+		return "", err
+	}
+	return handlers.BuildTabDescription(schema, handlers.ValueMap(values[entry.ID])), nil
+}
+
+// Returns the text answers of the cloned entry for the selected tasks
+// and the label of the cloned entry.
+// Texts are only carried over within the same template.
+func textsFromClone(ctx context.Context, q *database.Queries, template database.Template, path string, tasks []string) (map[string]string, string, error) {
+	source, err := q.GetEntryByPath(ctx, path)
+	if err != nil {
+		return nil, "", err
+	}
+	label, err := entryLabel(ctx, q, source)
+	if err != nil {
+		return nil, "", err
+	}
+	if source.TemplateID != template.ID {
+		return nil, label, nil
+	}
+	items, err := checklist.LoadItems(ctx, q, source.ID)
+	if err != nil {
+		return nil, "", err
+	}
+	answers := make(map[string]string)
+	for _, item := range checklist.TextAnswers(items) {
+		answers[item.Task] = *item.Text
+	}
+	texts := make(map[string]string)
+	for _, task := range tasks {
+		if text, ok := answers[task]; ok {
+			texts[task] = text
+		}
+	}
+	return texts, label, nil
+}
@@ -0,0 +1,302 @@
+package new
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/hmaier-dev/checklist-tool/internal/database"
+	"github.com/hmaier-dev/checklist-tool/internal/handlers"
+)
+
+// Content of an uploaded csv file.
+// The first line is used as header.
+type csvTable struct {
+	Header []string
+	Rows   [][]string
+}
+
+// Reads csv exported by spreadsheets.
+// Excel uses ';' as separator in german locales, so the separator is guessed from the header line.
+func parseCSV(text string) (csvTable, error) {
+	text = strings.TrimPrefix(text, "\uFEFF")
+	firstLine, _, _ := strings.Cut(text, "\n")
+	reader := csv.NewReader(strings.NewReader(text))
+	if strings.Count(firstLine, ";") > strings.Count(firstLine, ",") {
+		reader.Comma = ';'
+	}
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return csvTable{}, err
+	}
+	if len(records) == 0 {
+		return csvTable{}, errors.New("csv file is empty")
+	}
+	var table = csvTable{Header: records[0]}
+	for _, rec := range records[1:] {
+		// skip blank lines at the end of the file
+		if len(rec) == 1 && strings.TrimSpace(rec[0]) == "" {
+			continue
+		}
+		table.Rows = append(table.Rows, rec)
+	}
+	return table, nil
+}
+
+// Returns the value of column 'col' or an empty string, when the row is too short
+func (t csvTable) value(row []string, col int) string {
+	if col < 0 || col >= len(row) {
+		return ""
+	}
+	return strings.TrimSpace(row[col])
+}
+
+// Maps each key of 'custom_fields' to a column of the csv.
+// A column fits, when its header equals the key or the description of the field.
+// Fields without a fitting column are mapped to -1.
+func autoMapping(fields []database.CustomField, header []string) map[string]int {
+	mapping := make(map[string]int, len(fields))
+	for _, f := range fields {
+		mapping[f.Key] = -1
+		for i, h := range header {
+			h = strings.TrimSpace(h)
+			if strings.EqualFold(h, f.Key) || strings.EqualFold(h, f.Desc) {
+				mapping[f.Key] = i
+				break
+			}
+		}
+	}
+	return mapping
+}
+
+// Mapping chosen by the user in the preview.
+// Falls back to autoMapping() for fields missing in the form.
+func mappingFromForm(r *http.Request, fields []database.CustomField, header []string) map[string]int {
+	mapping := autoMapping(fields, header)
+	for _, f := range fields {
+		val, ok := r.Form["map_"+f.Key]
+		if !ok {
+			continue
+		}
+		col, err := strconv.Atoi(val[0])
+		if err != nil || col >= len(header) {
+			col = -1
+		}
+		mapping[f.Key] = col
+	}
+	return mapping
+}
+
+// Select box for a single custom field in the preview
+type MappingView struct {
+	Key     string
+	Desc    string
+	Column  int
+	Columns []string
+}
+
+// Single row of the csv as it will be or has been imported
+type ImportRowView struct {
+	Line   int
+	Values []string
+	// Message is shown next to the row, when something is wrong
+	Error   string
+	Created bool
+}
+
+type importResult struct {
+	Mapping  []MappingView
+	Fields   []database.CustomField
+	Rows     []ImportRowView
+	Valid    int
+	Unmapped bool
+}
+
+// Checks each row of the csv against the database and against the other rows.
+// Rows with an error won't be imported.
+func (h *NewHandler) validateImport(r *http.Request, template database.Template, fields []database.CustomField, table csvTable, mapping map[string]int) importResult {
+	ctx := r.Context()
+	q := database.New(h.DB)
+	var result = importResult{Fields: fields}
+	for _, f := range fields {
+		if mapping[f.Key] < 0 {
+			result.Unmapped = true
+		}
+		result.Mapping = append(result.Mapping, MappingView{
+			Key:     f.Key,
+			Desc:    f.Desc,
+			Column:  mapping[f.Key],
+			Columns: table.Header,
+		})
+	}
+	// line of the first occurrence for each path
+	seen := make(map[string]int)
+	for i, row := range table.Rows {
+		view := ImportRowView{Line: i + 2}
+		data := make(map[string]string, len(fields))
+		for _, f := range fields {
+			val := table.value(row, mapping[f.Key])
+			data[f.Key] = val
+			view.Values = append(view.Values, val)
+			if val == "" && view.Error == "" {
+				view.Error = fmt.Sprintf("'%s' ist leer.", f.Desc)
+			}
+			if f.Key == template.DueField.String && val != "" && view.Error == "" {
+				if _, err := handlers.ParseDate(val); err != nil {
+					view.Error = fmt.Sprintf("'%s' ist kein gültiges Datum.", f.Desc)
+				}
+			}
+		}
+		if view.Error == "" {
+			path := generatePath(data)
+			if line, ok := seen[path]; ok {
+				view.Error = fmt.Sprintf("Doppelt, siehe Zeile %d.", line)
+			} else if _, err := q.DoesPathExist(ctx, path); err == nil {
+				view.Error = "Eintrag ist bereits vorhanden."
+			} else if _, err := q.GetDeletedEntryByPath(ctx, path); err == nil {
+				view.Error = "Eintrag befindet sich im Papierkorb."
+			} else {
+				seen[path] = view.Line
+			}
+		}
+		if view.Error == "" {
+			result.Valid += 1
+		}
+		result.Rows = append(result.Rows, view)
+	}
+	return result
+}
+
+// Reads the csv either from the uploaded file or from the preview form
+func csvFromRequest(r *http.Request) (string, error) {
+	r.ParseMultipartForm(1 << 20)
+	if text := r.FormValue("csvtext"); text != "" {
+		return text, nil
+	}
+	file, _, err := r.FormFile("csv")
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	var buf bytes.Buffer
+	if _, err := io.Copy(&buf, file); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func importError(w http.ResponseWriter, msg string) {
+	html := fmt.Sprintf(`<div class='text-red-700'>%s</div>`, msg)
+	w.Write([]byte(html))
+}
+
+// Shows how the csv columns are mapped to the fields of the template
+// and which rows can be imported.
+func (h *NewHandler) ImportPreview(w http.ResponseWriter, r *http.Request) {
+	h.handleImport(w, r, false)
+}
+
+// Creates all valid rows of the csv in one transaction
+func (h *NewHandler) Import(w http.ResponseWriter, r *http.Request) {
+	h.handleImport(w, r, true)
+}
+
+func (h *NewHandler) handleImport(w http.ResponseWriter, r *http.Request, execute bool) {
+	ctx := r.Context()
+	text, err := csvFromRequest(r)
+	if err != nil {
+		importError(w, "Bitte eine CSV-Datei auswählen.")
+		return
+	}
+	table, err := parseCSV(text)
+	if err != nil {
+		importError(w, fmt.Sprintf("Die CSV-Datei konnte nicht gelesen werden: %s", err))
+		return
+	}
+	templateName := r.FormValue("import_template")
+	if templateName == "" {
+		templateName = r.FormValue("template")
+	}
+	q := database.New(h.DB)
+	template, err := q.GetTemplateByName(ctx, templateName)
+	if err != nil {
+		importError(w, "Da keine Checkliste verfügbar ist, kann kein Eintrag angelegt werden.")
+		return
+	}
+	fields, err := q.GetCustomFieldsByTemplateName(ctx, templateName)
+	if err != nil {
+		msg := fmt.Sprintf("Couldn't get custom fields for template '%s'.", templateName)
+		log.Println(msg)
+		http.Error(w, msg, http.StatusInternalServerError)
+		return
+	}
+	mapping := mappingFromForm(r, fields, table.Header)
+	result := h.validateImport(r, template, fields, table, mapping)
+
+	if execute && !result.Unmapped {
+		err = h.executeImport(r, template, fields, table, mapping, &result)
+		if err != nil {
+			log.Printf("CSV import failed.\n Error: %v\n", err)
+			importError(w, "Beim Import ist ein Fehler aufgetreten. Es wurde kein Eintrag erstellt.")
+			return
+		}
+	}
+
+	tmpl := handlers.LoadTemplates([]string{"new/templates/import-preview.html"})
+	err = tmpl.ExecuteTemplate(w, "import-preview.html", map[string]any{
+		"Template": templateName,
+		"CSV":      text,
+		"Result":   result,
+		"Done":     execute,
+	})
+	if err != nil {
+		msg := "Couldn't render the import preview."
+		log.Printf("%s\n Error: %v\n", msg, err)
+		http.Error(w, msg, http.StatusInternalServerError)
+	}
+}
+
+// Rows which passed the validation are created through createEntry().
+// Duplicates are reported per row, every other error rolls back the whole import.
+func (h *NewHandler) executeImport(r *http.Request, template database.Template, fields []database.CustomField, table csvTable, mapping map[string]int, result *importResult) error {
+	ctx := r.Context()
+	tx, err := h.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	qtx := database.New(h.DB).WithTx(tx)
+	result.Valid = 0
+	for i := range result.Rows {
+		row := &result.Rows[i]
+		if row.Error != "" {
+			continue
+		}
+		values := table.Rows[i]
+		_, err := createEntry(ctx, qtx, template, fields, func(key string) string {
+			return table.value(values, mapping[key])
+		}, entryOptions{})
+		switch {
+		case err == nil:
+			row.Created = true
+			result.Valid += 1
+		case errors.Is(err, ErrInTrash):
+			row.Error = "Eintrag befindet sich im Papierkorb."
+		case errors.Is(err, ErrDuplicate):
+			row.Error = "Eintrag ist bereits vorhanden."
+		case errors.Is(err, ErrInvalidDue):
+			row.Error = "Fälligkeitsdatum ist ungültig."
+		default:
+			return fmt.Errorf("line %d: %w", row.Line, err)
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,60 @@
+package new
+
+import (
+	"testing"
+
+	"github.com/hmaier-dev/checklist-tool/internal/database"
+)
+
+func TestParseCSV(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		header []string
+		rows   int
+	}{
+		{"comma", "a,b\n1,2\n3,4\n", []string{"a", "b"}, 2},
+		{"semicolon from excel", "\uFEFFa;b\r\n1;2,5\r\n", []string{"a", "b"}, 1},
+		{"blank line at the end", "a,b\n1,2\n\n", []string{"a", "b"}, 1},
+		{"short row", "a,b,c\n1\n", []string{"a", "b", "c"}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table, err := parseCSV(tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(table.Header) != len(tt.header) {
+				t.Fatalf("expected header %q, got %q", tt.header, table.Header)
+			}
+			for i := range tt.header {
+				if table.Header[i] != tt.header[i] {
+					t.Errorf("expected header %q, got %q", tt.header, table.Header)
+				}
+			}
+			if len(table.Rows) != tt.rows {
+				t.Errorf("expected %d rows, got %d", tt.rows, len(table.Rows))
+			}
+		})
+	}
+
+	if _, err := parseCSV(""); err == nil {
+		t.Error("expected an error for an empty file")
+	}
+}
+
+func TestAutoMapping(t *testing.T) {
+	fields := []database.CustomField{
+		{Key: "fullname", Desc: "Name"},
+		{Key: "ticket", Desc: "Ticket Number"},
+		{Key: "typ", Desc: "Modell"},
+	}
+	header := []string{"ticket number", "Name ", "IMEI"}
+	mapping := autoMapping(fields, header)
+	expected := map[string]int{"fullname": 1, "ticket": 0, "typ": -1}
+	for key, col := range expected {
+		if mapping[key] != col {
+			t.Errorf("key '%s': expected column %d, got %d", key, col, mapping[key])
+		}
+	}
+}
@@ -1,9 +1,10 @@
 package new
 
 import (
+	"context"
 	"crypto/sha256"
 	"database/sql"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -15,6 +16,7 @@ import (
 
 	"github.com/hmaier-dev/checklist-tool/internal/database"
 	"github.com/hmaier-dev/checklist-tool/internal/handlers"
+	"github.com/hmaier-dev/checklist-tool/internal/handlers/checklist"
 	"github.com/hmaier-dev/checklist-tool/internal/server"
 )
 
@@ -37,6 +39,8 @@ func (h *NewHandler)	Routes(){
 	h.Router.HandleFunc("/entries", h.Entries).Methods("GET")
 	h.Router.HandleFunc("/options", h.Options).Methods("GET")
 	h.Router.HandleFunc("/new", h.Execute).Methods("POST")
+	h.Router.HandleFunc("/import/preview", h.ImportPreview).Methods("POST")
+	h.Router.HandleFunc("/import", h.Import).Methods("POST")
 }
 
 // Return html to http.ResponseWriter for /
@@ -46,6 +50,8 @@ func (h *NewHandler) Display(w http.ResponseWriter, r *http.Request){
 		"new/templates/new.html",
 		"new/templates/entries.html",
 		"new/templates/options.html",
+		"due.html",
+		"progress.html",
 		"nav.html",
 		"header.html",
 	}
@@ -60,18 +66,50 @@ func (h *NewHandler) Display(w http.ResponseWriter, r *http.Request){
 		http.Error(w,msg,http.StatusInternalServerError)
 	}
 	active := ""
+	var activeTemplate database.Template
 	if len(all) > 0 {
 		active = all[0].Name
+		activeTemplate = all[0]
+	}
+	// The form is prefilled with the data of the entry to clone
+	var clone *cloneView
+	if path := r.URL.Query().Get("clone"); path != ""{
+		clone, err = cloneViewFor(ctx, q, path)
+		if err != nil{
+			http.Error(w, "Entry to clone not found.", http.StatusNotFound)
+			return
+		}
+		for _, t := range all{
+			if t.ID == clone.TemplateID{
+				active = t.Name
+				activeTemplate = t
+			}
+		}
 	}
 	entriesActiveTemplate, err := q.GetEntriesByTemplateName(ctx, active)
 	customFields, err := q.GetCustomFieldsByTemplateName(ctx, active)
-	entriesView := handlers.BuildEntriesViewForTemplate(customFields, entriesActiveTemplate)
+	values, err := handlers.LoadValuesForEntries(ctx, q, entriesActiveTemplate)
+	if err != nil{
+		log.Printf("Couldn't load the values of the entries.\n Error: %v\n", err)
+	}
+	entriesView := handlers.BuildEntriesViewForTemplate(values, entriesActiveTemplate)
+	people, err := q.GetAllPeople(ctx)
+	if err != nil{
+		log.Printf("Couldn't load all people.\n Error: %v\n", err)
+	}
+	handlers.SetAssignees(ctx, q, entriesView)
 
 	err = tmpl.Execute(w, map[string]any{
 		"Active": active,
 		"Templates": all,
 		"Inputs": customFields,
+		"DueField": activeTemplate.DueField.String,
+		"DueIn": activeTemplate.DueIn.String,
+		"People": people,
+		"Clone": clone,
 		"Entries": entriesView,
+		"DueFilters": handlers.DueFilters,
+		"Sorts": handlers.Sorts,
   })
 
   if err != nil {
@@ -84,10 +122,17 @@ func (h *NewHandler) Entries(w http.ResponseWriter, r *http.Request){
 	templateName := r.URL.Query().Get("template")
 	q := database.New(h.DB)
 	entries, err := q.GetEntriesByTemplateName(ctx, templateName)
-	tmpl := handlers.LoadTemplates([]string{"new/templates/entries.html"})
-	// building a map to access the descriptions by column names
-	customFields, err := q.GetCustomFieldsByTemplateName(ctx, templateName)
-	result := handlers.BuildEntriesViewForTemplate(customFields, entries)
+	tmpl := handlers.LoadTemplates([]string{"new/templates/entries.html", "due.html", "progress.html"})
+	values, err := handlers.LoadValuesForEntries(ctx, q, entries)
+	if err != nil{
+		msg := fmt.Sprintf("Couldn't load the values of the entries for template: '%s'.", templateName)
+		log.Printf("%s\n Error: %v\n", msg, err)
+		http.Error(w,msg,http.StatusInternalServerError)
+		return
+	}
+	result := handlers.BuildEntriesViewForTemplate(values, entries)
+	handlers.SetAssignees(ctx, q, result)
+	result = handlers.SortAndFilter(result, r.URL.Query().Get("sort"), r.URL.Query().Get("filter"))
 	err = tmpl.Execute(w, map[string]any{
 		"Entries": result,
 	})
@@ -111,49 +156,161 @@ func (h *NewHandler) Execute(w http.ResponseWriter, r *http.Request){
 		return
 	}
 	cols, err := q.GetCustomFieldsByTemplateName(ctx,templateName)
+	assignee, err := handlers.ParseAssignee(ctx, q, r.FormValue("assignee"))
+	if err != nil{
+		html := `<div class='text-red-700'>Die ausgewählte Person ist nicht vorhanden.</div>`
+		w.Write([]byte(html))
+		return
+	}
+	opts := entryOptions{
+		Due: r.FormValue("due"),
+		Assignee: assignee,
+	}
+	if clone := r.FormValue("clone"); clone != ""{
+		opts.Texts, opts.CloneOf, err = textsFromClone(ctx, q, template, clone, r.Form["carry_text"])
+		if err != nil{
+			html := `<div class='text-red-700'>Der kopierte Eintrag ist nicht mehr vorhanden.</div>`
+			w.Write([]byte(html))
+			return
+		}
+	}
+	// All rows of the entry are written at once,
+	// a half-built entry would block creating it again by its path
+	tx, err := h.DB.BeginTx(ctx, nil)
+	if err != nil{
+		log.Printf("Couldn't begin transaction.\n Error: %v\n", err)
+		html := `<div class='text-red-700'>Ein unbekannter Fehler aufgetreten.</div>`
+		w.Write([]byte(html))
+		return
+	}
+	defer tx.Rollback()
+	path, err := createEntry(ctx, q.WithTx(tx), template, cols, r.FormValue, opts)
+	if err == nil{
+		err = tx.Commit()
+	}
+	switch {
+	case err == nil:
+		html := fmt.Sprintf(`<div class='text-emerald-600'>Eintrag erfolgreich erstellt. <a class='underline' href='/checklist/%s' target='_blank'>Öffnen</a></div>`, path)
+		w.Write([]byte(html))
+	case errors.Is(err, ErrInTrash):
+		html := `<div class='text-red-700'>Eintrag befindet sich im Papierkorb und kann unter <a class='underline' href='/delete'>Löschen</a> wiederhergestellt werden.</div>`
+		w.Write([]byte(html))
+	case errors.Is(err, ErrDuplicate):
+		html := `<div class='text-red-700'>Eintrag ist bereits vorhanden und wurde daher nicht erneut erstellt.</div>`
+		w.Write([]byte(html))
+	case errors.Is(err, ErrInvalidDue):
+		html := `<div class='text-red-700'>Das Fälligkeitsdatum ist ungültig.</div>`
+		w.Write([]byte(html))
+	default:
+		log.Printf("Couldn't create entry.\n Error: %v\n", err)
+		html := `<div class='text-red-700'>Ein unbekannter Fehler aufgetreten.</div>`
+		w.Write([]byte(html))
+	}
+}
+
+// Optional settings for a new entry
+type entryOptions struct{
+	// Due date set by the user, otherwise it is computed from the template
+	Due string
+	Assignee sql.NullInt64
+	// Text answers by task, which are carried over from a cloned entry
+	Texts map[string]string
+	// Label of the cloned entry for the history
+	CloneOf string
+}
+
+var (
+	ErrDuplicate = errors.New("entry already exists")
+	ErrInTrash = errors.New("entry already exists in the trash")
+	ErrInvalidDue = errors.New("due date is invalid")
+)
+
+// Inserts a new entry for the template.
+// 'value' returns the user input for a key of 'custom_fields'.
+// Is used by the form on / and by the csv import, so both behave the same.
+// Pass a *database.Queries with a transaction to create multiple entries at once.
+func createEntry(ctx context.Context, q *database.Queries, template database.Template, cols []database.CustomField, value func(key string) string, opts entryOptions) (string, error){
 	data := make(map[string]string)
 	for _, col := range cols{
 		// Only read keys from the form,
 		// which have been specified in 'custom_fields' database schema.
 		// That way, no invalid data can be passed
 		key := col.Key
-		value := r.FormValue(key)
-		data[key] = value
+		data[key] = value(key)
 	}
-	json, err := json.Marshal(data)
+	path := generatePath(data)
+	now := time.Now()
+	dueAt, err := handlers.ComputeDue(template, data, opts.Due, now)
 	if err != nil{
-		msg := fmt.Sprintf("Error while marshaling json.\n Error: %q \n", err)
-		log.Println(msg)
-		http.Error(w,msg,http.StatusInternalServerError)
+		return path, fmt.Errorf("%w: %v", ErrInvalidDue, err)
 	}
-	path := generatePath(data)
 	params := database.InsertEntryParams{
 		TemplateID: template.ID,
-		Data: string(json),
 		Path: path,
-		Yaml: template.EmptyYaml,
-		Date: sql.NullInt64{Valid: true, Int64: time.Now().Unix()},
+		Date: sql.NullInt64{Valid: true, Int64: now.Unix()},
+		Due: dueAt,
+		AssigneeID: opts.Assignee,
 	}
 	// Instead of checking the 'path' manually,
 	// use the CONSTRAINT on the column to generate an error
 	err = q.InsertEntry(ctx, params)
-
 	if err != nil{
-		switch err.Error(){
-		case "UNIQUE constraint failed: entries.path":
-			html := `<div class='text-red-700'>Eintrag ist bereits vorhanden und wurde daher nicht erneut erstellt.</div>`
-			w.Write([]byte(html))
-			return
-		default:
-			html := `<div class='text-red-700'>Ein unbekannter Fehler aufgetreten.</div>`
-			w.Write([]byte(html))
-			return
+		if err.Error() == "UNIQUE constraint failed: entries.path"{
+			// The entry could be hidden in the trash
+			if _, err := q.GetDeletedEntryByPath(ctx, path); err == nil{
+				return path, ErrInTrash
+			}
+			return path, ErrDuplicate
 		}
-	}else{
-		html := `<div class='text-emerald-600'>Eintrag erfolgreich erstellt.</div>`
-		w.Write([]byte(html))
-		return
+		return path, err
 	}
+	entry, err := q.GetEntryByPath(ctx, path)
+	if err != nil{
+		return path, err
+	}
+	for _, col := range cols{
+		arg := database.SetEntryValueParams{
+			EntryID: entry.ID,
+			FieldID: col.ID,
+			Value: data[col.Key],
+		}
+		if err := q.SetEntryValue(ctx, arg); err != nil{
+			return path, err
+		}
+	}
+	items, err := checklist.LoadItemsFor(ctx, q, entry.ID, template.EmptyYaml.String)
+	if err != nil{
+		return path, err
+	}
+	// Only tasks with a text field take over a text
+	if len(opts.Texts) > 0{
+		revision, err := q.NextRevisionByID(ctx, entry.ID)
+		if err != nil{
+			return path, err
+		}
+		for task, text := range opts.Texts{
+			if checklist.SetText(items, task, text){
+				if err := checklist.SaveText(ctx, q, entry.ID, task, text, revision); err != nil{
+					return path, err
+				}
+			}
+		}
+	}
+	if err := checklist.UpdateProgress(ctx, q, entry.ID, items); err != nil{
+		return path, err
+	}
+	if err := checklist.UpdateAnswers(ctx, q, entry.ID, items); err != nil{
+		return path, err
+	}
+	// Write the history of the new entry
+	if opts.CloneOf != ""{
+		msg := fmt.Sprintf("Erstellt als Kopie von %s", opts.CloneOf)
+		if err := handlers.RecordEvent(ctx, q, entry.ID, handlers.EventClone, msg); err != nil{
+			return path, err
+		}
+	}
+	err = handlers.RecordAssigneeChange(ctx, q, entry.ID, sql.NullInt64{}, opts.Assignee)
+	return path, err
 }
 
 // Return the custom inputs fields per template
@@ -167,9 +324,18 @@ func (h *NewHandler) Options(w http.ResponseWriter, r *http.Request){
 		log.Println(msg)
 		http.Error(w,msg,http.StatusInternalServerError)
 	}
+	// The template is only needed for the due date rule
+	template, _ := q.GetTemplateByName(ctx, templateName)
+	people, err := q.GetAllPeople(ctx)
+	if err != nil{
+		log.Printf("Couldn't load all people.\n Error: %v\n", err)
+	}
 	tmpl := handlers.LoadTemplates([]string{"new/templates/options.html"})
 	err = tmpl.Execute(w, map[string]any{
 		"Inputs": customFields,
+		"People": people,
+		"DueField": template.DueField.String,
+		"DueIn": template.DueIn.String,
 	})
 	if err != nil{
 		msg := "Couldn't render options template."
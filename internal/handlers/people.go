@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/hmaier-dev/checklist-tool/internal/database"
+)
+
+// Sets the name of the assignee for every entry
+func SetAssignees(ctx context.Context, q *database.Queries, view []EntryView) error {
+	people, err := q.GetAllPeople(ctx)
+	if err != nil {
+		return err
+	}
+	names := make(map[int64]string, len(people))
+	for _, p := range people {
+		names[p.ID] = p.Name
+	}
+	for i := range view {
+		view[i].Assignee = names[view[i].AssigneeID]
+	}
+	return nil
+}
+
+// Reads the id of a person from a form value.
+// An empty value means nobody is assigned.
+func ParseAssignee(ctx context.Context, q *database.Queries, value string) (sql.NullInt64, error) {
+	if value == "" {
+		return sql.NullInt64{}, nil
+	}
+	var id int64
+	if _, err := fmt.Sscan(value, &id); err != nil {
+		return sql.NullInt64{}, fmt.Errorf("'%s' is not a valid id", value)
+	}
+	if _, err := q.GetPersonByID(ctx, id); err != nil {
+		return sql.NullInt64{}, fmt.Errorf("person %d doesn't exist: %w", id, err)
+	}
+	return sql.NullInt64{Valid: true, Int64: id}, nil
+}
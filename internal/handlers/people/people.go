@@ -0,0 +1,136 @@
+package people
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/hmaier-dev/checklist-tool/internal/database"
+	"github.com/hmaier-dev/checklist-tool/internal/handlers"
+	"github.com/hmaier-dev/checklist-tool/internal/server"
+)
+
+// Manages the team members, which can be assigned to entries
+type PeopleHandler struct {
+	Router *mux.Router
+	DB     *sql.DB
+}
+
+var _ handlers.DisplayHandler = (*PeopleHandler)(nil)
+
+func (h *PeopleHandler) New(srv *server.Server) {
+	h.Router = srv.Router
+	h.DB = srv.DB
+}
+
+// Sets /people and all subroutes
+func (h *PeopleHandler) Routes() {
+	sub := h.Router.PathPrefix("/people").Subrouter()
+	sub.HandleFunc("", h.Display).Methods("GET")
+	sub.HandleFunc("", h.Add).Methods("POST")
+	sub.HandleFunc("/delete", h.Delete).Methods("POST")
+}
+
+// Return rendered html for GET to /people
+func (h *PeopleHandler) Display(w http.ResponseWriter, r *http.Request) {
+	var templates = []string{
+		"people/templates/people.html",
+		"nav.html",
+		"header.html",
+	}
+	tmpl := handlers.LoadTemplates(templates)
+	q := database.New(h.DB)
+	people, err := q.GetAllPeople(r.Context())
+	if err != nil {
+		msg := "Couldn't load all people."
+		log.Printf("%s\n Error: %v\n", msg, err)
+		http.Error(w, msg, http.StatusInternalServerError)
+		return
+	}
+	err = tmpl.Execute(w, map[string]any{
+		"People": people,
+		"Error":  r.URL.Query().Get("error"),
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// Adds a person by name
+func (h *PeopleHandler) Add(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimSpace(r.FormValue("name"))
+	if name == "" {
+		http.Redirect(w, r, "/people?error=empty", http.StatusSeeOther)
+		return
+	}
+	q := database.New(h.DB)
+	err := q.InsertPerson(r.Context(), name)
+	if err != nil {
+		if err.Error() == "UNIQUE constraint failed: people.name" {
+			http.Redirect(w, r, "/people?error=duplicate", http.StatusSeeOther)
+			return
+		}
+		msg := "Couldn't add the person."
+		log.Printf("%s\n Error: %v\n", msg, err)
+		http.Error(w, msg, http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, "/people", http.StatusSeeOther)
+}
+
+// Deletes a person. Their entries become unassigned, which is written into the history of each entry.
+func (h *PeopleHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id, err := strconv.ParseInt(r.FormValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "'id' is weird.", http.StatusBadRequest)
+		return
+	}
+	q := database.New(h.DB)
+	person, err := q.GetPersonByID(ctx, id)
+	if err != nil {
+		http.Error(w, "Person not found.", http.StatusNotFound)
+		return
+	}
+	tx, err := h.DB.BeginTx(ctx, nil)
+	if err != nil {
+		http.Error(w, "Database error.", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+	qtx := q.WithTx(tx)
+	assignee := sql.NullInt64{Valid: true, Int64: id}
+	err = qtx.InsertEntryEventsByAssignee(ctx, database.InsertEntryEventsByAssigneeParams{
+		Message:    fmt.Sprintf("Zuweisung an %s entfernt, da die Person gelöscht wurde", person.Name),
+		Date:       time.Now().Unix(),
+		AssigneeID: assignee,
+	})
+	if err == nil {
+		err = qtx.ClearAssigneeByPersonID(ctx, assignee)
+	}
+	if err == nil {
+		err = qtx.DeletePersonByID(ctx, id)
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		msg := "Couldn't delete the person."
+		log.Printf("%s\n Error: %v\n", msg, err)
+		http.Error(w, msg, http.StatusInternalServerError)
+		return
+	}
+	// Special header for htmx
+	w.Header().Set("HX-Redirect", "/people")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func init() {
+	handlers.RegisterHandler(&PeopleHandler{})
+}
@@ -0,0 +1,39 @@
+package handlers
+
+// Checked items of a checklist as shown by progress.html
+type ProgressView struct {
+	Checked int
+	Total   int
+	Percent int
+	// Items marked with 'required: true'
+	RequiredChecked int
+	RequiredTotal   int
+}
+
+func ProgressFor(checked, total, requiredChecked, requiredTotal int64) ProgressView {
+	return ProgressView{
+		Checked:         int(checked),
+		Total:           int(total),
+		Percent:         Percent(int(checked), int(total)),
+		RequiredChecked: int(requiredChecked),
+		RequiredTotal:   int(requiredTotal),
+	}
+}
+
+// Checked items in percent, rounded down
+func Percent(checked, total int) int {
+	if total == 0 {
+		return 0
+	}
+	return checked * 100 / total
+}
+
+// Sums up the progress of multiple checklists
+func (p ProgressView) Add(o ProgressView) ProgressView {
+	return ProgressFor(
+		int64(p.Checked+o.Checked),
+		int64(p.Total+o.Total),
+		int64(p.RequiredChecked+o.RequiredChecked),
+		int64(p.RequiredTotal+o.RequiredTotal),
+	)
+}
@@ -0,0 +1,118 @@
+package search
+
+import (
+	"database/sql"
+	"html"
+	"html/template"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"github.com/hmaier-dev/checklist-tool/internal/database"
+	"github.com/hmaier-dev/checklist-tool/internal/handlers"
+	"github.com/hmaier-dev/checklist-tool/internal/server"
+)
+
+// Maximum amount of hits shown below the search box
+const limit = 20
+
+// Full-text search over all entries, see migrations/0009_search.sql
+type SearchHandler struct {
+	Router *mux.Router
+	DB     *sql.DB
+}
+
+var _ handlers.DisplayHandler = (*SearchHandler)(nil)
+
+func (h *SearchHandler) New(srv *server.Server) {
+	h.Router = srv.Router
+	h.DB = srv.DB
+}
+
+func (h *SearchHandler) Routes() {
+	h.Router.HandleFunc("/search", h.Display).Methods("GET")
+}
+
+// A single hit with the matches wrapped in <mark>
+type Hit struct {
+	Path     string
+	Status   handlers.Status
+	Template template.HTML
+	Data     template.HTML
+	// Empty, when the hit isn't in the text answers
+	Answers template.HTML
+}
+
+// Return rendered html for GET to /search?q=
+// The search box in nav.html only gets the hits,
+// without htmx the whole page is rendered.
+func (h *SearchHandler) Display(w http.ResponseWriter, r *http.Request) {
+	input := r.URL.Query().Get("q")
+	var hits []Hit
+	if match := MatchQuery(input); match != "" {
+		q := database.New(h.DB)
+		rows, err := q.SearchEntries(r.Context(), database.SearchEntriesParams{
+			Query: match,
+			Limit: limit,
+		})
+		if err != nil {
+			msg := "Couldn't search the entries."
+			log.Printf("%s\n Error: %v\n", msg, err)
+			http.Error(w, msg, http.StatusInternalServerError)
+			return
+		}
+		for _, row := range rows {
+			hit := Hit{
+				Path:     row.Path,
+				Status:   handlers.StatusFor(row.Status),
+				Template: Highlight(row.Template.String),
+				Data:     Highlight(row.Data.String),
+			}
+			if strings.Contains(row.Answers.String, "\x02") {
+				hit.Answers = Highlight(row.Answers.String)
+			}
+			hits = append(hits, hit)
+		}
+	}
+	var templates = []string{"search/templates/results.html"}
+	name := "results.html"
+	if r.Header.Get("HX-Request") == "" {
+		templates = append(templates, "search/templates/search.html", "nav.html", "header.html")
+		name = "search.html"
+	}
+	tmpl := handlers.LoadTemplates(templates)
+	err := tmpl.ExecuteTemplate(w, name, map[string]any{
+		"Query": input,
+		"Hits":  hits,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// Turns the user input into an FTS5 query.
+// Every word is quoted, so characters like '-' or '"' can't break the syntax,
+// and matched as prefix, so a part of an IMEI finds the entry as well.
+// Returns an empty string, when there is nothing to search for.
+func MatchQuery(input string) string {
+	var terms []string
+	for _, word := range strings.Fields(input) {
+		word = strings.ReplaceAll(word, `"`, `""`)
+		terms = append(terms, `"`+word+`"*`)
+	}
+	return strings.Join(terms, " ")
+}
+
+// Escapes the text and replaces the markers set by SearchEntries with <mark>
+func Highlight(s string) template.HTML {
+	s = html.EscapeString(s)
+	s = strings.ReplaceAll(s, "\x02", "<mark>")
+	s = strings.ReplaceAll(s, "\x03", "</mark>")
+	return template.HTML(s)
+}
+
+func init() {
+	handlers.RegisterHandler(&SearchHandler{})
+}
@@ -0,0 +1,29 @@
+package search
+
+import "testing"
+
+func TestMatchQuery(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"", ""},
+		{"   ", ""},
+		{"3569", `"3569"*`},
+		{"max  mustermann", `"max"* "mustermann"*`},
+		{`a"b OR -c`, `"a""b"* "OR"* "-c"*`},
+	}
+	for _, tt := range tests {
+		if got := MatchQuery(tt.input); got != tt.want {
+			t.Errorf("'%s': expected %s, got %s", tt.input, tt.want, got)
+		}
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	got := Highlight("<b>Max</b> | \x02356\x03912")
+	want := "&lt;b&gt;Max&lt;/b&gt; | <mark>356</mark>912"
+	if string(got) != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+}
@@ -0,0 +1,415 @@
+package upload
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/adrg/frontmatter"
+	"gopkg.in/yaml.v3"
+
+	"github.com/hmaier-dev/checklist-tool/internal/database"
+	"github.com/hmaier-dev/checklist-tool/internal/handlers"
+	"github.com/hmaier-dev/checklist-tool/internal/handlers/checklist"
+)
+
+// An update of a template happens in two steps:
+// the uploaded file is compared with the current template and the report is shown,
+// only after confirming it, the new version is applied.
+// Entries, which shouldn't get the new version, are kept at a copy of the old one
+// in 'template_versions'. The custom fields always change for all entries.
+
+// What an update of a template changes for its entries
+type UpdateReport struct {
+	Template     string
+	File         string
+	NewItems     []string
+	RemovedItems []string
+	// Items with 'renamed_from', their state is carried over
+	RenamedItems []Rename
+	NewFields    []string
+	// Fields of 'field_renames', their values are carried over
+	RenamedFields []Rename
+	// Values of these fields are hidden or deleted
+	RemovedFields []string
+	// Only entries, which lose something
+	Entries []EntryReport
+	Total   int
+	Open    int
+	// Entries, which are already kept at an older version
+	Frozen int
+}
+
+type Rename struct {
+	From string
+	To   string
+}
+
+type EntryReport struct {
+	Path    string
+	Label   string
+	Open    bool
+	Deleted bool
+	Frozen  bool
+	// Checked items, which aren't in the new version
+	LostChecks []string
+	// Text answers of items, which aren't in the new version or have no text field anymore.
+	// 'Desc' is the task.
+	LostTexts []handlers.DescValueView
+	// Values of the removed fields
+	OrphanedValues []handlers.DescValueView
+}
+
+func (e EntryReport) affected() bool {
+	return len(e.LostChecks) > 0 || len(e.LostTexts) > 0 || len(e.OrphanedValues) > 0
+}
+
+// Splits an uploaded checklist into the frontmatter and the checklist itself
+// and checks both
+func parseChecklist(contents string) (FrontMatter, string, error) {
+	var matter FrontMatter
+	rest, err := frontmatter.Parse(strings.NewReader(contents), &matter)
+	if err != nil {
+		return matter, "", fmt.Errorf("error while parsing frontmatter: %w", err)
+	}
+	if err := matter.validate(); err != nil {
+		return matter, "", err
+	}
+	if len(matter.Fields) != len(matter.Desc) {
+		return matter, "", fmt.Errorf("amount of fields and descriptions are uneven")
+	}
+	var items []*checklist.Item
+	if err := yaml.Unmarshal(rest, &items); err != nil {
+		return matter, "", fmt.Errorf("error while validating the yaml: %w", err)
+	}
+	return matter, string(rest), nil
+}
+
+// Tasks of a checklist, whether they have a text field
+// and the earlier tasks of renamed items.
+// Like everywhere else, only the first item with a task counts.
+type taskSet struct {
+	texts map[string]bool
+	order []string
+	// Earlier task to new task
+	renames map[string]string
+}
+
+func (t *taskSet) add(items []*checklist.Item) {
+	for _, item := range items {
+		if _, ok := t.texts[item.Task]; !ok {
+			t.texts[item.Task] = item.Text != nil
+			t.order = append(t.order, item.Task)
+			if item.RenamedFrom != "" && item.RenamedFrom != item.Task {
+				t.renames[item.RenamedFrom] = item.Task
+			}
+		}
+		t.add(item.Children)
+	}
+}
+
+func parseTasks(templateYaml string) (*taskSet, error) {
+	var items []*checklist.Item
+	if err := yaml.Unmarshal([]byte(templateYaml), &items); err != nil {
+		return nil, err
+	}
+	t := &taskSet{texts: make(map[string]bool), renames: make(map[string]string)}
+	t.add(items)
+	return t, nil
+}
+
+// Renames of the new version, which apply to tasks of the old one.
+// A task, which is still there, isn't renamed.
+func itemRenames(oldTasks, newTasks *taskSet) map[string]string {
+	result := make(map[string]string)
+	for from, to := range newTasks.renames {
+		_, fromOld := oldTasks.texts[from]
+		_, fromNew := newTasks.texts[from]
+		_, toOld := oldTasks.texts[to]
+		if fromOld && !fromNew && !toOld {
+			result[from] = to
+		}
+	}
+	return result
+}
+
+// Tasks, which are only in 'a' and weren't renamed.
+// 'renamed' holds the tasks on the side of 'a'.
+func missingTasks(a []string, b map[string]bool, renamed map[string]bool) []string {
+	var result []string
+	for _, t := range a {
+		if _, ok := b[t]; !ok && !renamed[t] {
+			result = append(result, t)
+		}
+	}
+	return result
+}
+
+// States of an entry, which are shown now, but not with the new version
+func lostStates(states []database.ItemState, oldTasks, newTasks *taskSet, renames map[string]string) ([]string, []handlers.DescValueView) {
+	var checks []string
+	var texts []handlers.DescValueView
+	for _, s := range states {
+		oldText, inOld := oldTasks.texts[s.Task]
+		if !inOld {
+			// Wasn't shown before either
+			continue
+		}
+		task := s.Task
+		if to, ok := renames[task]; ok {
+			task = to
+		}
+		newText, inNew := newTasks.texts[task]
+		if !inNew && s.Checked.Valid && s.Checked.Bool {
+			checks = append(checks, s.Task)
+		}
+		if oldText && !newText && s.Text.Valid && s.Text.String != "" {
+			texts = append(texts, handlers.DescValueView{Desc: s.Task, Value: s.Text.String})
+		}
+	}
+	return checks, texts
+}
+
+// Renames of 'field_renames', which apply to fields of the current version
+func fieldRenames(fields []database.CustomField, matter FrontMatter) map[string]string {
+	keys := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		keys[f.Key] = true
+	}
+	result := make(map[string]string)
+	for from, to := range matter.Field_renames {
+		if keys[from] && !keys[to] {
+			result[from] = to
+		}
+	}
+	return result
+}
+
+// Compares the uploaded version with the current one of the template
+func buildReport(ctx context.Context, q *database.Queries, template database.Template, matter FrontMatter, newYaml string) (UpdateReport, error) {
+	report := UpdateReport{Template: template.Name}
+	oldTasks, err := parseTasks(template.EmptyYaml.String)
+	if err != nil {
+		return report, fmt.Errorf("couldn't read the current checklist: %w", err)
+	}
+	newTasks, err := parseTasks(newYaml)
+	if err != nil {
+		return report, fmt.Errorf("couldn't read the new checklist: %w", err)
+	}
+	renames := itemRenames(oldTasks, newTasks)
+	renamedFrom := make(map[string]bool, len(renames))
+	renamedTo := make(map[string]bool, len(renames))
+	for _, from := range oldTasks.order {
+		if to, ok := renames[from]; ok {
+			report.RenamedItems = append(report.RenamedItems, Rename{From: from, To: to})
+			renamedFrom[from] = true
+			renamedTo[to] = true
+		}
+	}
+	report.NewItems = missingTasks(newTasks.order, oldTasks.texts, renamedTo)
+	report.RemovedItems = missingTasks(oldTasks.order, newTasks.texts, renamedFrom)
+
+	all, err := q.GetCustomFieldsByTemplateID(ctx, template.ID)
+	if err != nil {
+		return report, err
+	}
+	// Fields hidden by an earlier update don't show up in the report
+	var fields []database.CustomField
+	for _, f := range all {
+		if !f.RemovedAt.Valid {
+			fields = append(fields, f)
+		}
+	}
+	fRenames := fieldRenames(fields, matter)
+	removed := make(map[string]bool)
+	var keys []string
+	for _, f := range fields {
+		if to, ok := fRenames[f.Key]; ok {
+			report.RenamedFields = append(report.RenamedFields, Rename{From: f.Key, To: to})
+			keys = append(keys, to)
+			continue
+		}
+		keys = append(keys, f.Key)
+		if !slices.Contains(matter.Fields, f.Key) {
+			report.RemovedFields = append(report.RemovedFields, f.Key)
+			removed[f.Key] = true
+		}
+	}
+	for _, key := range matter.Fields {
+		if !slices.Contains(keys, key) {
+			report.NewFields = append(report.NewFields, key)
+		}
+	}
+
+	entries, err := q.GetEntriesByTemplateIDWithDeleted(ctx, template.ID)
+	if err != nil {
+		return report, err
+	}
+	values, err := handlers.LoadValuesForEntries(ctx, q, entries)
+	if err != nil {
+		return report, err
+	}
+	schema, err := q.GetTabDescriptionsByTemplateID(ctx, template.ID)
+	if err != nil {
+		return report, err
+	}
+	for _, e := range entries {
+		report.Total++
+		er := EntryReport{
+			Path:    e.Path,
+			Label:   handlers.BuildTabDescription(schema, handlers.ValueMap(values[e.ID])),
+			Open:    e.Status != "done",
+			Deleted: e.DeletedAt.Valid,
+			Frozen:  e.TemplateVersionID.Valid,
+		}
+		if er.Label == "" {
+			er.Label = e.Path
+		}
+		if er.Open {
+			report.Open++
+		}
+		if er.Frozen {
+			report.Frozen++
+		} else {
+			states, err := q.GetItemStatesByEntryID(ctx, e.ID)
+			if err != nil {
+				return report, err
+			}
+			er.LostChecks, er.LostTexts = lostStates(states, oldTasks, newTasks, renames)
+		}
+		for _, v := range values[e.ID] {
+			if removed[v.Key] && v.Value != "" {
+				er.OrphanedValues = append(er.OrphanedValues, v)
+			}
+		}
+		if er.affected() {
+			report.Entries = append(report.Entries, er)
+		}
+	}
+	return report, nil
+}
+
+// Choices made on the report
+type applyOptions struct {
+	// Finished entries are kept at the current version
+	OpenOnly bool
+	// Values of removed fields are deleted instead of hidden
+	RemoveValues bool
+	Now          int64
+}
+
+// Applies the new version of the template
+func applyUpdate(ctx context.Context, qtx *database.Queries, template database.Template, matter FrontMatter, newYaml string, file string, opts applyOptions) error {
+	oldTasks, err := parseTasks(template.EmptyYaml.String)
+	if err != nil {
+		return fmt.Errorf("couldn't read the current checklist: %w", err)
+	}
+	newTasks, err := parseTasks(newYaml)
+	if err != nil {
+		return fmt.Errorf("couldn't read the new checklist: %w", err)
+	}
+	renames := itemRenames(oldTasks, newTasks)
+	entries, err := qtx.GetEntriesByTemplateIDWithDeleted(ctx, template.ID)
+	if err != nil {
+		return fmt.Errorf("couldn't return entries for template '%s': %w", template.Name, err)
+	}
+	var version sql.NullInt64
+	var updated []database.Entry
+	for _, e := range entries {
+		if e.TemplateVersionID.Valid {
+			continue
+		}
+		if !opts.OpenOnly || e.Status != "done" {
+			updated = append(updated, e)
+			continue
+		}
+		// All finished entries share one copy of the current version
+		if !version.Valid {
+			id, err := qtx.InsertTemplateVersion(ctx, database.InsertTemplateVersionParams{
+				TemplateID: template.ID,
+				EmptyYaml:  template.EmptyYaml.String,
+				Date:       opts.Now,
+			})
+			if err != nil {
+				return fmt.Errorf("couldn't keep the current version: %w", err)
+			}
+			version = sql.NullInt64{Int64: id, Valid: true}
+		}
+		err := qtx.SetTemplateVersionByID(ctx, database.SetTemplateVersionByIDParams{
+			TemplateVersionID: version,
+			ID:                e.ID,
+		})
+		if err != nil {
+			return fmt.Errorf("couldn't keep '%s' at the current version: %w", e.Path, err)
+		}
+	}
+
+	if err := updateCustomFields(ctx, qtx, template.ID, matter, opts.RemoveValues, opts.Now); err != nil {
+		return fmt.Errorf("error while updating 'custom_fields' with the frontmatter values: %w", err)
+	}
+	// Updating the tab-desc-schema by deleting and inserting
+	if err := qtx.DeleteTabDescSchemaByTemplateID(ctx, template.ID); err != nil {
+		return err
+	}
+	for _, t := range matter.Tab_desc_schema {
+		err := qtx.InsertTabDescSchema(ctx, database.InsertTabDescSchemaParams{TemplateID: template.ID, Value: t})
+		if err != nil {
+			return fmt.Errorf("error while inserting frontmatter values into 'tab_desc_schema': %w", err)
+		}
+	}
+	// Updating the pdf-schema by deleting and inserting
+	if err := qtx.DeletePdfNameSchemaByTemplateID(ctx, template.ID); err != nil {
+		return err
+	}
+	for _, p := range matter.Pdf_name_schema {
+		err := qtx.InsertPdfNameSchema(ctx, database.InsertPdfNameSchemaParams{TemplateID: template.ID, Value: p})
+		if err != nil {
+			return fmt.Errorf("error while inserting frontmatter values into 'pdf_name_schema': %w", err)
+		}
+	}
+	err = qtx.UpdateTemplateById(ctx, database.UpdateTemplateByIdParams{
+		EmptyYaml: sql.NullString{String: newYaml, Valid: true},
+		File:      sql.NullString{String: file, Valid: true},
+		DueIn:     nullString(matter.Due_in),
+		DueField:  nullString(matter.Due_field),
+		CaseField: nullString(matter.Case_field),
+		ID:        template.ID,
+	})
+	if err != nil {
+		return err
+	}
+
+	// The state of the entries is stored per task, so checked items and text answers
+	// of tasks, which are still in the checklist, are kept.
+	// The state and the attachments of renamed items move to their new task.
+	// Only the progress and the answers have to be counted again.
+	// Entries in the trash are updated as well, so they are up to date when restored.
+	for _, e := range updated {
+		for from, to := range renames {
+			err := qtx.RenameItemState(ctx, database.RenameItemStateParams{NewTask: to, EntryID: e.ID, OldTask: from})
+			if err == nil {
+				err = qtx.RenameAttachmentTask(ctx, database.RenameAttachmentTaskParams{
+					NewTask: sql.NullString{Valid: true, String: to},
+					EntryID: e.ID,
+					OldTask: sql.NullString{Valid: true, String: from},
+				})
+			}
+			if err != nil {
+				return fmt.Errorf("couldn't rename '%s' in entry '%s': %w", from, e.Path, err)
+			}
+		}
+		items, err := checklist.LoadItemsFor(ctx, qtx, e.ID, newYaml)
+		if err != nil {
+			return fmt.Errorf("couldn't load the checklist of entry '%s': %w", e.Path, err)
+		}
+		if err := checklist.UpdateProgress(ctx, qtx, e.ID, items); err != nil {
+			return err
+		}
+		if err := checklist.UpdateAnswers(ctx, qtx, e.ID, items); err != nil {
+			return err
+		}
+	}
+	return nil
+}
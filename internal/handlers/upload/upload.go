@@ -2,11 +2,12 @@ package upload
 
 import (
 	"bytes"
-	"encoding/json"
+	"context"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -19,7 +20,6 @@ import (
 	"github.com/hmaier-dev/checklist-tool/internal/database"
 	"github.com/hmaier-dev/checklist-tool/internal/handlers"
 	"github.com/hmaier-dev/checklist-tool/internal/server"
-	"github.com/hmaier-dev/checklist-tool/internal/handlers/checklist"
 )
 
 type UploadHandler struct{
@@ -41,6 +41,20 @@ type TemplatesView struct {
 	Description string
 	Tab_Schema  string
 	PDF_Schema  string
+	Due         string
+	Case        string
+}
+
+// Describes the rule for the due date of new entries
+func FormatDueRule(t database.Template) string {
+	var rules []string
+	if t.DueField.Valid {
+		rules = append(rules, "field: "+t.DueField.String)
+	}
+	if t.DueIn.Valid {
+		rules = append(rules, "in: "+t.DueIn.String)
+	}
+	return strings.Join(rules, ", ")
 }
 
 // Sets /upload and all its subroutes
@@ -51,6 +65,7 @@ func (h *UploadHandler) Routes() {
 	h.Router.HandleFunc("/upload/delete", h.Delete).Methods("POST")
 	sub.HandleFunc(`/download/{id:\d*}`, h.Download).Methods("GET")
 	sub.HandleFunc("/update", h.Update).Methods("POST")
+	sub.HandleFunc("/update/apply", h.Apply).Methods("POST")
 }
 
 // Uses CustomField.Key
@@ -138,6 +153,8 @@ func (h *UploadHandler) Display(w http.ResponseWriter, r *http.Request) {
 			Description: FormatWithDescriptionWithCommas(cols),
 			Tab_Schema:  FormatToTabSchema(tab),
 			PDF_Schema:  FormatToPDFSchema(pdf),
+			Due:         FormatDueRule(t),
+			Case:        t.CaseField.String,
 		}
 	}
 	tmpl := handlers.LoadTemplates(templates)
@@ -157,6 +174,44 @@ type FrontMatter struct{
 	Desc []string 						`yaml:"desc"`
 	Tab_desc_schema []string 	`yaml:"tab_desc_schema"`
 	Pdf_name_schema []string 	`yaml:"pdf_name_schema"`
+	Due_in string 						`yaml:"due_in"`
+	Due_field string 					`yaml:"due_field"`
+	Case_field string 				`yaml:"case_field"`
+	// Old key to new key. Renamed fields keep the values of the existing entries.
+	Field_renames map[string]string	`yaml:"field_renames"`
+}
+
+// Checks the optional keys, which refer to other keys
+func (m FrontMatter) validate() error{
+	if m.Case_field != "" && !slices.Contains(m.Fields, m.Case_field){
+		return fmt.Errorf("'case_field' has to be one of 'fields', but is '%s'", m.Case_field)
+	}
+	if m.Due_in != ""{
+		if _, err := handlers.ParseDueIn(m.Due_in); err != nil{
+			return fmt.Errorf("invalid 'due_in': %w", err)
+		}
+	}
+	if m.Due_field != "" && !slices.Contains(m.Fields, m.Due_field){
+		return fmt.Errorf("'due_field' has to be one of 'fields', but is '%s'", m.Due_field)
+	}
+	renamed := make(map[string]bool, len(m.Field_renames))
+	for from, to := range m.Field_renames{
+		if !slices.Contains(m.Fields, to){
+			return fmt.Errorf("'field_renames' has to rename '%s' to one of 'fields', but renames it to '%s'", from, to)
+		}
+		if slices.Contains(m.Fields, from){
+			return fmt.Errorf("'field_renames' renames '%s', which is still one of 'fields'", from)
+		}
+		if renamed[to]{
+			return fmt.Errorf("'field_renames' renames more than one field to '%s'", to)
+		}
+		renamed[to] = true
+	}
+	return nil
+}
+
+func nullString(s string) sql.NullString{
+	return sql.NullString{String: s, Valid: s != ""}
 }
 
 // Runs when submit-button is pressed
@@ -179,6 +234,10 @@ func (h *UploadHandler) Execute(w http.ResponseWriter, r *http.Request) {
 		log.Printf("Error while parsing frontmatter.\n %q\n", err)
 		return
 	}
+	if err := matter.validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	var result any
 	err = yaml.Unmarshal([]byte(rest), &result)
 	if err != nil {
@@ -206,6 +265,9 @@ func (h *UploadHandler) Execute(w http.ResponseWriter, r *http.Request) {
 		Name: matter.Name,
 		EmptyYaml: sql.NullString{String: string(rest), Valid: true},
 		File: sql.NullString{String: fileContents, Valid: true},
+		DueIn: nullString(matter.Due_in),
+		DueField: nullString(matter.Due_field),
+		CaseField: nullString(matter.Case_field),
 	})
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
@@ -217,6 +279,7 @@ func (h *UploadHandler) Execute(w http.ResponseWriter, r *http.Request) {
 			TemplateID: id,
 			Key: matter.Fields[i],
 			Desc: matter.Desc[i],
+			Position: int64(i),
 		}
 		err := qtx.InsertCustomField(ctx, arg)
 		if err != nil{
@@ -228,7 +291,7 @@ func (h *UploadHandler) Execute(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// These column names tell the application later which,
-	// which value from database.Entry.Data (json-slice) should be displayed
+	// which value from 'entry_values' should be displayed
 
 	// Add column names for browser tab description.
 	for _, t := range matter.Tab_desc_schema{
@@ -342,215 +405,180 @@ func (h *UploadHandler) Download(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// First step of an update: shows what the uploaded version changes for the entries
 func (h *UploadHandler) Update(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	r.ParseMultipartForm(1 << 20)
-	file, header, err := r.FormFile("yaml")
+	file, _, err := r.FormFile("yaml")
 	if err != nil {
-		panic(err)
+		http.Error(w, "No file uploaded.", http.StatusBadRequest)
+		return
 	}
+	defer file.Close()
 	var buf bytes.Buffer
 	io.Copy(&buf, file)
 	fileContents := buf.String()
-	var matter FrontMatter
-	// splits the file into the yaml frontmatter and the rest of the file
-	rest, err := frontmatter.Parse(strings.NewReader(fileContents), &matter)
+	matter, rest, err := parseChecklist(fileContents)
 	if err != nil {
-		http.Error(w, "Error while parsing frontmatter", http.StatusBadRequest)
-		log.Printf("Error while parsing frontmatter.\n %q\n", err)
+		log.Printf("Couldn't read the uploaded checklist.\n Error: %v\n", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	var result any
-	err = yaml.Unmarshal([]byte(rest), &result)
+	q := database.New(h.DB)
+	// The templateName gets declared in the frontmatter
+	template, err := templateByName(ctx, q, matter.Name)
+	if err == sql.ErrNoRows {
+		msg := fmt.Sprintf("No template with the name '%s' exists. I can't get updated.", matter.Name)
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		msg := "Couldn't load the template."
+		log.Printf("%s\n Error: %v\n", msg, err)
+		http.Error(w, msg, http.StatusInternalServerError)
+		return
+	}
+	report, err := buildReport(ctx, q, template, matter, rest)
 	if err != nil {
-		log.Printf("Error while validating the yaml in %s: %q\n", header.Filename, err)
+		msg := fmt.Sprintf("Couldn't compare the upload with the template '%s'.", matter.Name)
+		log.Printf("%s\n Error: %v\n", msg, err)
+		http.Error(w, msg, http.StatusInternalServerError)
+		return
+	}
+	report.File = fileContents
+	tmpl := handlers.LoadTemplates([]string{
+		"upload/templates/preview.html",
+		"header.html",
+		"nav.html",
+	})
+	if err := tmpl.Execute(w, report); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// Second step of an update: applies the confirmed version to the entries
+func (h *UploadHandler) Apply(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	fileContents := r.FormValue("file")
+	opts := applyOptions{
+		OpenOnly:     r.FormValue("scope") == "open",
+		RemoveValues: r.FormValue("remove_values") == "on",
+		Now:          time.Now().Unix(),
+	}
+	matter, rest, err := parseChecklist(fileContents)
+	if err != nil {
+		log.Printf("Couldn't read the confirmed checklist.\n Error: %v\n", err)
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	tx, err := h.DB.Begin()
-	if err != nil{
-		http.Error(w,"Database error.",http.StatusInternalServerError)
+	tx, err := h.DB.BeginTx(ctx, nil)
+	if err != nil {
+		http.Error(w, "Database error.", http.StatusInternalServerError)
+		return
 	}
-	defer func(){
-		if err != nil{
-			rbErr := tx.Rollback()
-			if rbErr != nil{
-				log.Printf("Rollback error: %v", rbErr)	
-			}
-		}
-	}()
+	defer tx.Rollback()
 	qtx := database.New(h.DB).WithTx(tx)
-
-	// The templateName gets declared in the frontmatter
-	id, err := qtx.GetTemplateIdByName(ctx, matter.Name)
-	if err == sql.ErrNoRows{
+	template, err := templateByName(ctx, qtx, matter.Name)
+	if err == sql.ErrNoRows {
 		msg := fmt.Sprintf("No template with the name '%s' exists. I can't get updated.", matter.Name)
 		http.Error(w, msg, http.StatusBadRequest)
+		return
 	}
-	fl := len(matter.Fields)
-	dl := len(matter.Desc)
-	if fl != dl{
-		msg := "Amount of fields and descriptions are uneven."
-		http.Error(w, msg, http.StatusBadRequest)
-	}
-
-	// The following code should be as functions, but am very lazy...
-
-	qtx.DeleteCustomFieldsByTemplateID(ctx, id)
-	for i := range matter.Fields{
-		arg := database.InsertCustomFieldParams{
-			TemplateID: id,
-			Key: matter.Fields[i],
-			Desc: matter.Desc[i],
-		}
-		err := qtx.InsertCustomField(ctx, arg)
-		if err != nil{
-			msg := fmt.Sprintf("Error while inserting frontmatter values into 'custom_fields'. \n Error: %v\n", err)
-			log.Println(msg)
-			http.Error(w,msg,http.StatusInternalServerError)
-			return
-		}
-	}
-
-	// Updating the tab-desc-schema by deleting and inserting
-	qtx.DeleteTabDescSchemaByTemplateID(ctx, id)
-	for _, t := range matter.Tab_desc_schema{
-		arg := database.InsertTabDescSchemaParams{
-			TemplateID: id,
-			Value: t,
-		}
-		err := qtx.InsertTabDescSchema(ctx, arg)
-		if err != nil{
-			msg := fmt.Sprintf("Error while inserting frontmatter values into 'tab_desc_schema'. \n Error: %v\n", err)
-			log.Println(msg)
-			http.Error(w,msg,http.StatusInternalServerError)
-			return
-		}
+	if err == nil {
+		err = applyUpdate(ctx, qtx, template, matter, rest, fileContents, opts)
 	}
-	
-	// Updating the pdf-schema by deleting and inserting
-	qtx.DeletePdfNameSchemaByTemplateID(ctx, id)
-	for _, p := range matter.Pdf_name_schema{
-		arg := database.InsertPdfNameSchemaParams{
-			TemplateID: id,
-			Value: p,
-		}
-		err := qtx.InsertPdfNameSchema(ctx, arg)
-		if err != nil{
-			msg := fmt.Sprintf("Error while inserting frontmatter values into 'tab_desc_schema'. \n Error: %v\n", err)
-			log.Println(msg)
-			http.Error(w,msg,http.StatusInternalServerError)
-			return
-		}
+	if err == nil {
+		err = tx.Commit()
 	}
-	
-	arg := database.UpdateTemplateByIdParams{
-		EmptyYaml: sql.NullString{String: string(rest), Valid: true},
-		File: sql.NullString{String: fileContents, Valid: true},
-		ID: id,
+	if err != nil {
+		msg := fmt.Sprintf("Couldn't update the template '%s'.", matter.Name)
+		log.Printf("%s\n Error: %v\n", msg, err)
+		http.Error(w, msg, http.StatusInternalServerError)
+		return
 	}
-	qtx.UpdateTemplateById(ctx, arg)
+	http.Redirect(w, r, "/upload", http.StatusSeeOther)
+}
 
-	// After updating the checklist-template itself and all concerning meta-data tables,
-	// now we update the already existing entries for this template
-	entries, err := qtx.GetEntriesByTemplateName(ctx, matter.Name)
+func templateByName(ctx context.Context, q *database.Queries, name string) (database.Template, error) {
+	id, err := q.GetTemplateIdByName(ctx, name)
+	if err != nil {
+		return database.Template{}, err
+	}
+	return q.GetTemplateById(ctx, id)
+}
 
+// Fields, which are still in the frontmatter, keep their id,
+// so the values of the existing entries stay attached to them.
+// The same goes for fields renamed with 'field_renames'.
+// New fields start empty. Removed fields are hidden and keep their values,
+// unless 'removeValues' is set, then their values are deleted.
+func updateCustomFields(ctx context.Context, qtx *database.Queries, templateID int64, matter FrontMatter, removeValues bool, now int64) error{
+	existing, err := qtx.GetCustomFieldsByTemplateID(ctx, templateID)
 	if err != nil{
-		msg := fmt.Sprintf("Couldn't return entries for template: '%s'.\n Error: %v\n", matter.Name, err)
-		log.Println(msg)
-		http.Error(w,msg,http.StatusInternalServerError)
-		return
-	}
-	// Update dataMap ('entry.Data' json-arry) for all concerning entries
-	for _, e := range entries {
-		var dataMap map[string]string
-		err := json.Unmarshal([]byte(e.Data), &dataMap)
-		if err != nil {
-			log.Fatal("Error while unmarshaling json into map[string]string.")
-		}
-		for _, c := range matter.Fields {
-			if _, exists := dataMap[c]; !exists {
-				dataMap[c] = ""
+		return err
+	}
+	ids := make(map[string]int64, len(existing))
+	removed := make(map[int64]bool)
+	for _, f := range existing{
+		ids[f.Key] = f.ID
+		removed[f.ID] = f.RemovedAt.Valid
+	}
+	for from, to := range matter.Field_renames{
+		if fieldID, ok := ids[from]; ok{
+			if old, ok := ids[to]; ok{
+				// Both are fields of the current version, nothing to rename
+				if !removed[old]{
+					continue
+				}
+				// A hidden field with the new key is replaced
+				if err := qtx.DeleteCustomFieldByID(ctx, old); err != nil{
+					return err
+				}
 			}
+			ids[to] = fieldID
+			delete(ids, from)
 		}
-		j, err := json.Marshal(dataMap)
-		arg := database.UpdateDataByIdParams{
-			Data: string(j),
-			ID: e.ID,
-		}
-		qtx.UpdateDataById(ctx, arg)
-
 	}
-	
-	// Update the checklist for all relevant entries
-	// but save the state of the check-points
-	for _, e := range entries {
-		var oldCheck []*checklist.Item
-		var blankCheck []*checklist.Item
-		var y string
-		if e.Yaml.Valid{
-			y = e.Yaml.String
+	for i, key := range matter.Fields{
+		if fieldID, ok := ids[key]; ok{
+			err = qtx.UpdateCustomFieldByID(ctx, database.UpdateCustomFieldByIDParams{
+				Key: key,
+				Desc: matter.Desc[i],
+				Position: int64(i),
+				ID: fieldID,
+			})
+			delete(ids, key)
 		}else{
-			log.Println("'yaml'-field in database was NULL.")
-			return
+			err = qtx.InsertCustomField(ctx, database.InsertCustomFieldParams{
+				TemplateID: templateID,
+				Key: key,
+				Desc: matter.Desc[i],
+				Position: int64(i),
+			})
 		}
-		yaml.Unmarshal([]byte(y), &oldCheck)
-		yaml.Unmarshal(rest, &blankCheck)
-
-		var itemsMap = make(map[string]*checklist.Item)
-		//
-		fillHashMap(itemsMap, oldCheck)
-		adoptState(itemsMap, blankCheck)
-
-		var newCheck []byte
-		newCheck, err = yaml.Marshal(blankCheck)
 		if err != nil{
-			log.Fatalf("Marshaling yaml wen't wrong.")
+			return err
 		}
-		arg := database.UpdateYamlByIdParams{
-			Yaml: sql.NullString{Valid: true, String: string(newCheck)},
-			ID: e.ID,
-		}
-		qtx.UpdateYamlById(ctx, arg)
 	}
-
-	tx.Commit()
-	// Special header for htmx
-	w.Header().Set("HX-Redirect", "/upload")
-	w.WriteHeader(http.StatusNoContent)
-}
-
-// dissolves the multi-level checklist-struct in a one-dimensional hashmap.
-// Useful when searching for a key:value
-func fillHashMap(itemMap map[string]*checklist.Item, items []*checklist.Item) {
-	for _, item := range items {
-		val := checklist.Item{
-			Checked: item.Checked,
-			Text: item.Text,
-		}
-		itemMap[item.Task] = &val
-		if len(item.Children) > 0 {
-			fillHashMap(itemMap, item.Children)
+	for _, fieldID := range ids{
+		// Fields hidden by an earlier update stay hidden
+		if removed[fieldID]{
+			continue
 		}
-	}
-}
-
-// adopts checklist.Item.Checked if map-key fits checklist.Item.Task
-func adoptState(itemMap map[string]*checklist.Item, checklist []*checklist.Item) {
-	for _, item := range checklist {
-		if value, ok := itemMap[item.Task]; ok {
-			item.Checked = value.Checked
-			// Reversed logic
-			// When the new value is not nil, but the old value was nil
-			// Otherwise, don't overwritte the existing value
-			if value.Text != nil && item.Text == nil{
-				item.Text = value.Text
-			}
+		if removeValues{
+			err = qtx.DeleteCustomFieldByID(ctx, fieldID)
+		}else{
+			err = qtx.RemoveCustomFieldByID(ctx, database.RemoveCustomFieldByIDParams{
+				RemovedAt: sql.NullInt64{Int64: now, Valid: true},
+				ID: fieldID,
+			})
 		}
-		if len(item.Children) > 0 {
-			adoptState(itemMap, item.Children)
+		if err != nil{
+			return err
 		}
 	}
+	return nil
 }
 
 func init() {
@@ -1,75 +1,129 @@
 package upload
 
 import (
+	"database/sql"
+	"slices"
 	"testing"
 
-	"gopkg.in/yaml.v3"
+	"github.com/hmaier-dev/checklist-tool/internal/database"
 )
 
-func TestUpdateChecklistYaml(t *testing.T) {
-	old := `
-	- task: "Auf Arbeit kommen."
-  checked: true
+const oldChecklist = `
+- task: "Auf Arbeit kommen."
+  checked: false
   children:
-		- task: "Kaffee trinken."
-			checked: false
-		- task: "Müsli essen."
-			checked: true
-	- task: "Tickets bearbeiten."
-		checked: false
-		children:
-			- task: "Kommentare schreiben."
-				checked: true
-	`
-	blank := `
-	- task: "Auf Arbeit kommen."
-		checked: false
-		children:
-			- task: "Kaffee trinken."
-				checked: false
-			- task: "Müsli essen."
-				checked: false
-			- task: "Rauchen gehen."
-				checked: false
-	- task: "Tickets bearbeiten."
-		checked: false
-		children:
-			- task: "Kommentare schreiben."
-				checked: false
-			- task: "Emails schreiben."
-				checked: false
-	`
-	expected := `
-	- task: "Auf Arbeit kommen."
-		checked: true
-		children:
-			- task: "Kaffee trinken."
-				checked: false
-			- task: "Müsli essen."
-				checked: true
-			- task: "Rauchen gehen."
-				checked: false
-	- task: "Tickets bearbeiten."
-		checked: false
-		children:
-			- task: "Kommentare schreiben."
-				checked: true
-			- task: "Emails schreiben."
-				checked: false
-	`
-	yaml.Unmarshal()
+    - task: "Kaffee trinken."
+      checked: false
+    - task: "Müsli essen."
+      checked: false
+      text: ""
+- task: "Tickets bearbeiten."
+  checked: false
+  children:
+    - task: "Kommentare schreiben."
+      checked: false
+      text: ""
+`
+
+const newChecklist = `
+- task: "Auf Arbeit kommen."
+  checked: false
+  children:
+    - task: "Kaffee trinken."
+      checked: false
+    - task: "Müsli essen."
+      checked: false
+    - task: "Rauchen gehen."
+      checked: false
+- task: "Tickets bearbeiten."
+  checked: false
+  children:
+    - task: "Emails schreiben."
+      checked: false
+    - task: "Kommentare verfassen."
+      renamed_from: "Kommentare schreiben."
+      checked: false
+      text: ""
+    - task: "Pause machen."
+      renamed_from: "Kaffee trinken."
+      checked: false
+`
 
-	t.Run("Update YAML", func(t *testing.T) {
-							UpdateChecklistYaml(old, blank)
-							// compare tt.newYaml with tt.expected
-							for i, item := range tt.newYaml {
-									if item.Checked != tt.expected[i].Checked {
-											t.Errorf("item %d (%s): expected Checked=%v, got %v",
-													i, item.Text, tt.expected[i].Checked, item.Checked)
-									}
-							}
-					})
+func state(task string, checked bool, text string) database.ItemState {
+	return database.ItemState{
+		Task:    task,
+		Checked: sql.NullBool{Bool: checked, Valid: true},
+		Text:    sql.NullString{String: text, Valid: text != ""},
+	}
+}
 
+func TestUpdateReport(t *testing.T) {
+	oldTasks, err := parseTasks(oldChecklist)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newTasks, err := parseTasks(newChecklist)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 'Kaffee trinken.' is still there, so it isn't renamed
+	renames := itemRenames(oldTasks, newTasks)
+	if len(renames) != 1 || renames["Kommentare schreiben."] != "Kommentare verfassen." {
+		t.Errorf("unexpected renames %v", renames)
+	}
+	renamedTo := map[string]bool{"Kommentare verfassen.": true}
+	added := missingTasks(newTasks.order, oldTasks.texts, renamedTo)
+	if !slices.Equal(added, []string{"Rauchen gehen.", "Emails schreiben.", "Pause machen."}) {
+		t.Errorf("unexpected new items %v", added)
+	}
+	renamedFrom := map[string]bool{"Kommentare schreiben.": true}
+	if removed := missingTasks(oldTasks.order, newTasks.texts, renamedFrom); len(removed) != 0 {
+		t.Errorf("unexpected removed items %v", removed)
+	}
 
+	states := []database.ItemState{
+		state("Auf Arbeit kommen.", true, ""),
+		// Still there, but without a text field
+		state("Müsli essen.", true, "Mit Milch"),
+		// Renamed with its text field
+		state("Kommentare schreiben.", true, "Ticket 42"),
+		// Wasn't shown before the update either
+		state("Mittag essen.", true, ""),
+		state("Kaffee trinken.", false, ""),
+	}
+	checks, texts := lostStates(states, oldTasks, newTasks, renames)
+	if len(checks) != 0 {
+		t.Errorf("unexpected lost checks %v", checks)
+	}
+	if len(texts) != 1 || texts[0].Desc != "Müsli essen." || texts[0].Value != "Mit Milch" {
+		t.Errorf("unexpected lost texts %+v", texts)
+	}
+	// Without the rename, the state of the item gets lost
+	checks, texts = lostStates(states, oldTasks, newTasks, nil)
+	if !slices.Equal(checks, []string{"Kommentare schreiben."}) || len(texts) != 2 {
+		t.Errorf("unexpected lost states %v %+v", checks, texts)
+	}
+}
 
+func TestParseChecklist(t *testing.T) {
+	valid := "---\nname: test\nfields: [name]\ndesc: [Name]\n---\n" + newChecklist
+	matter, rest, err := parseChecklist(valid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if matter.Name != "test" || rest == "" {
+		t.Errorf("unexpected result %+v %q", matter, rest)
+	}
+	renamed := "---\nname: test\nfields: [name]\ndesc: [Name]\nfield_renames: {fullname: name}\n---\n" + newChecklist
+	if _, _, err := parseChecklist(renamed); err != nil {
+		t.Errorf("unexpected error for a renamed field: %v", err)
+	}
+	unknown := "---\nname: test\nfields: [name]\ndesc: [Name]\nfield_renames: {fullname: surname}\n---\n" + newChecklist
+	if _, _, err := parseChecklist(unknown); err == nil {
+		t.Errorf("expected an error for a rename to a key, which isn't one of the fields")
+	}
+	uneven := "---\nname: test\nfields: [name, date]\ndesc: [Name]\n---\n" + newChecklist
+	if _, _, err := parseChecklist(uneven); err == nil {
+		t.Errorf("expected an error for uneven fields and descriptions")
+	}
 }
@@ -0,0 +1,152 @@
+package views
+
+import (
+	"database/sql"
+	"html/template"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"github.com/hmaier-dev/checklist-tool/internal/database"
+	"github.com/hmaier-dev/checklist-tool/internal/handlers"
+	"github.com/hmaier-dev/checklist-tool/internal/server"
+)
+
+// Named settings of the entries-browser on /all, e.g. "my open setups this week"
+type ViewsHandler struct {
+	Router *mux.Router
+	DB     *sql.DB
+}
+
+var _ handlers.DisplayHandler = (*ViewsHandler)(nil)
+
+func (h *ViewsHandler) New(srv *server.Server) {
+	h.Router = srv.Router
+	h.DB = srv.DB
+}
+
+// Sets /views and all subroutes
+func (h *ViewsHandler) Routes() {
+	sub := h.Router.PathPrefix("/views").Subrouter()
+	sub.HandleFunc("", h.Display).Methods("GET")
+	sub.HandleFunc("", h.Save).Methods("POST")
+	sub.HandleFunc("/delete", h.Delete).Methods("POST")
+	sub.HandleFunc("/nav", h.Nav).Methods("GET")
+}
+
+// A saved view with the link to open it
+type View struct {
+	ID   int64
+	Name string
+	URL  string
+}
+
+func (h *ViewsHandler) views(r *http.Request) ([]View, error) {
+	saved, err := database.New(h.DB).GetAllSavedViews(r.Context())
+	if err != nil {
+		return nil, err
+	}
+	var result []View
+	for _, v := range saved {
+		url := "/all"
+		if v.Query != "" {
+			url += "?" + v.Query
+		}
+		result = append(result, View{ID: v.ID, Name: v.Name, URL: url})
+	}
+	return result, nil
+}
+
+// Return rendered html for GET to /views
+func (h *ViewsHandler) Display(w http.ResponseWriter, r *http.Request) {
+	var templates = []string{
+		"views/templates/views.html",
+		"nav.html",
+		"header.html",
+	}
+	tmpl := handlers.LoadTemplates(templates)
+	views, err := h.views(r)
+	if err != nil {
+		msg := "Couldn't load the saved views."
+		log.Printf("%s\n Error: %v\n", msg, err)
+		http.Error(w, msg, http.StatusInternalServerError)
+		return
+	}
+	err = tmpl.Execute(w, map[string]any{
+		"Views": views,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// Returns the menu of saved views for nav.html
+func (h *ViewsHandler) Nav(w http.ResponseWriter, r *http.Request) {
+	tmpl := handlers.LoadTemplates([]string{"views/templates/menu.html"})
+	views, err := h.views(r)
+	if err != nil {
+		log.Printf("Couldn't load the saved views.\n Error: %v\n", err)
+	}
+	err = tmpl.Execute(w, map[string]any{
+		"Views": views,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// Saves the filters of the entries-browser under a name.
+// The form values are the same as in the query string of /all.
+// A view with the same name is replaced.
+func (h *ViewsHandler) Save(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Couldn't parse the form.", http.StatusBadRequest)
+		return
+	}
+	name := strings.TrimSpace(r.FormValue("name"))
+	if name == "" {
+		http.Error(w, "The view needs a name.", http.StatusBadRequest)
+		return
+	}
+	// Always start on the first page
+	query := handlers.ParseBrowserQuery(r.Form)
+	query.Page = 1
+	err := database.New(h.DB).SaveView(r.Context(), database.SaveViewParams{
+		Name:  name,
+		Query: query.Encode(),
+	})
+	if err != nil {
+		msg := "Couldn't save the view."
+		log.Printf("%s\n Error: %v\n", msg, err)
+		http.Error(w, msg, http.StatusInternalServerError)
+		return
+	}
+	// Reloads the menu in nav.html
+	w.Header().Set("HX-Trigger", "viewsChanged")
+	w.Write([]byte("Ansicht „" + template.HTMLEscapeString(name) + "“ gespeichert."))
+}
+
+func (h *ViewsHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.FormValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "'id' is weird.", http.StatusBadRequest)
+		return
+	}
+	err = database.New(h.DB).DeleteSavedViewByID(r.Context(), id)
+	if err != nil {
+		msg := "Couldn't delete the view."
+		log.Printf("%s\n Error: %v\n", msg, err)
+		http.Error(w, msg, http.StatusInternalServerError)
+		return
+	}
+	// Special header for htmx
+	w.Header().Set("HX-Redirect", "/views")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func init() {
+	handlers.RegisterHandler(&ViewsHandler{})
+}
@@ -0,0 +1,166 @@
+package live
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Single message on an event stream.
+// With the sse extension of htmx, 'Name' selects the element
+// that is swapped with 'Data' (sse-swap) or that is triggered (hx-trigger="sse:<Name>").
+type Event struct {
+	Name string
+	Data string
+}
+
+// Keeps proxies from closing an idle stream
+const pingInterval = 25 * time.Second
+
+// Events waiting for a slow browser.
+// When the buffer is full, further events are dropped for it.
+const buffer = 32
+
+// Hub keeps the open event streams per key (e.g. the path of an entry)
+// and sends published events to all of them.
+type Hub struct {
+	mu      sync.Mutex
+	streams map[string]map[chan Event]bool
+	closed  bool
+}
+
+func NewHub() *Hub {
+	return &Hub{streams: make(map[string]map[chan Event]bool)}
+}
+
+// Opens a stream for 'key'.
+// The channel is closed by the returned function or when the hub closes.
+// Returns false when the hub is already closed.
+func (h *Hub) Subscribe(key string) (<-chan Event, func(), bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return nil, nil, false
+	}
+	ch := make(chan Event, buffer)
+	if h.streams[key] == nil {
+		h.streams[key] = make(map[chan Event]bool)
+	}
+	h.streams[key][ch] = true
+	cancel := func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		// Already closed by Close
+		if !h.streams[key][ch] {
+			return
+		}
+		delete(h.streams[key], ch)
+		if len(h.streams[key]) == 0 {
+			delete(h.streams, key)
+		}
+		close(ch)
+	}
+	return ch, cancel, true
+}
+
+// Number of open streams for 'key'
+func (h *Hub) Count(key string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.streams[key])
+}
+
+// Sends the event to all streams of 'key' without waiting for them
+func (h *Hub) Publish(key string, e Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.streams[key] {
+		select {
+		case ch <- e:
+		default:
+			log.Printf("Dropped the event '%s' for a slow stream of '%s'.\n", e.Name, key)
+		}
+	}
+}
+
+// Ends all streams, so their requests return and the server can shut down.
+// Meant for http.Server.RegisterOnShutdown.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for key, streams := range h.streams {
+		for ch := range streams {
+			close(ch)
+		}
+		delete(h.streams, key)
+	}
+}
+
+// Streams the events of 'key' to the browser until it leaves or the hub closes.
+// 'changed' is called after the stream opened and after it closed,
+// e.g. to tell the others how many are watching.
+func (h *Hub) Serve(w http.ResponseWriter, r *http.Request, key string, changed func()) {
+	rc := http.NewResponseController(w)
+	// The stream lives longer than the WriteTimeout of the server
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		log.Printf("Couldn't lift the write deadline of the stream.\n Error: %v\n", err)
+	}
+	events, cancel, ok := h.Subscribe(key)
+	if !ok {
+		http.Error(w, "Server is shutting down.", http.StatusServiceUnavailable)
+		return
+	}
+	defer func() {
+		cancel()
+		changed()
+	}()
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	// Disables the buffering of nginx
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	rc.Flush()
+	changed()
+
+	ping := time.NewTicker(pingInterval)
+	defer ping.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case e, ok := <-events:
+			// Closed by Close when the server shuts down
+			if !ok {
+				return
+			}
+			if err := Write(w, e); err != nil {
+				return
+			}
+		case <-ping.C:
+			if _, err := io.WriteString(w, ": ping\n\n"); err != nil {
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// Writes the event in the format of text/event-stream.
+// Every line of the data gets its own 'data:' field.
+func Write(w io.Writer, e Event) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "event: %s\n", e.Name)
+	for _, line := range strings.Split(e.Data, "\n") {
+		fmt.Fprintf(&b, "data: %s\n", strings.TrimRight(line, "\r"))
+	}
+	b.WriteString("\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,99 @@
+package live
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPublish(t *testing.T) {
+	h := NewHub()
+	a, cancelA, _ := h.Subscribe("a")
+	b, _, _ := h.Subscribe("a")
+	other, _, _ := h.Subscribe("b")
+	if n := h.Count("a"); n != 2 {
+		t.Fatalf("expected 2 streams, got %d", n)
+	}
+	h.Publish("a", Event{Name: "status", Data: "done"})
+	for _, ch := range []<-chan Event{a, b} {
+		if e := <-ch; e.Name != "status" || e.Data != "done" {
+			t.Errorf("unexpected event %+v", e)
+		}
+	}
+	select {
+	case e := <-other:
+		t.Errorf("expected nothing on another key, got %+v", e)
+	default:
+	}
+	cancelA()
+	if _, ok := <-a; ok {
+		t.Errorf("expected the stream to be closed")
+	}
+	if n := h.Count("a"); n != 1 {
+		t.Errorf("expected 1 stream, got %d", n)
+	}
+	// A slow stream doesn't block the others
+	for i := 0; i < buffer+5; i++ {
+		h.Publish("b", Event{Name: "progress"})
+	}
+	h.Close()
+	// Cancelling after Close is fine
+	cancelA()
+	if _, _, ok := h.Subscribe("a"); ok {
+		t.Errorf("expected no new streams after Close")
+	}
+}
+
+func TestWrite(t *testing.T) {
+	var b strings.Builder
+	if err := Write(&b, Event{Name: "item-1", Data: "<span>\r\n  x\n</span>"}); err != nil {
+		t.Fatal(err)
+	}
+	want := "event: item-1\ndata: <span>\ndata:   x\ndata: </span>\n\n"
+	if b.String() != want {
+		t.Errorf("expected %q, got %q", want, b.String())
+	}
+}
+
+// An open stream outlives the WriteTimeout of the server
+// and doesn't keep Shutdown from returning.
+func TestShutdown(t *testing.T) {
+	h := NewHub()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h.Serve(w, r, "entry", func() {})
+		}),
+		WriteTimeout: 100 * time.Millisecond,
+	}
+	srv.RegisterOnShutdown(h.Close)
+	go srv.Serve(ln)
+
+	resp, err := http.Get("http://" + ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("unexpected content type '%s'", ct)
+	}
+	time.Sleep(200 * time.Millisecond)
+	h.Publish("entry", Event{Name: "progress"})
+	line, err := bufio.NewReader(resp.Body).ReadString('\n')
+	if err != nil || line != "event: progress\n" {
+		t.Fatalf("expected the event after the WriteTimeout, got %q (%v)", line, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		t.Fatalf("shutdown failed: %v", err)
+	}
+}
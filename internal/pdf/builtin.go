@@ -0,0 +1,416 @@
+package pdf
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"strings"
+)
+
+// Draws the checklist directly into a pdf, so no gotenberg container is needed.
+// It uses the standard fonts of the pdf viewers, which only know the characters of WinAnsiEncoding.
+// Attached pdfs can't be appended without parsing them,
+// so they are embedded as file attachments instead.
+type Builtin struct{}
+
+var _ Renderer = (*Builtin)(nil)
+
+var ErrMergeUnsupported = errors.New("merging pdfs needs the gotenberg backend")
+
+// A4 in points
+const (
+	pageWidth  = 595.28
+	pageHeight = 841.89
+	margin     = 40.0
+	// Space for the page number
+	footer = 20.0
+	// Indentation of the children of an item
+	indent = 16.0
+	// Images are scaled to fit into a square of this size
+	maxImage = 160.0
+	// Larger images are scaled down before they are embedded
+	maxPixels = 800
+)
+
+// Colors as pdf operators
+const (
+	black    = "0 0 0"
+	gray     = "0.294 0.333 0.388"
+	border   = "0.820 0.835 0.859"
+	headerBg = "0.953 0.957 0.965"
+	blue     = "0.231 0.510 0.965"
+)
+
+type page struct {
+	content bytes.Buffer
+}
+
+type pdfImage struct {
+	width  int
+	height int
+	rgb    []byte
+}
+
+// Places the content on the pages from top to bottom.
+// 'y' is the top of the next line.
+type layout struct {
+	pages    []*page
+	y        float64
+	xobjects []pdfImage
+}
+
+func (b *Builtin) Render(ctx context.Context, doc Document) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	l := &layout{}
+	l.newPage()
+
+	l.table(doc.Fields, doc.Progress)
+	l.y -= 10
+	l.paragraph(margin, pageWidth-2*margin, 10, false, gray, "Exportiert am "+doc.Date)
+	l.y -= 10
+	l.items(doc.Items, 0)
+
+	if len(doc.Images) > 0 {
+		l.heading("Anhänge")
+		l.images(margin, doc.Images)
+	}
+	if len(doc.Attachments) > 0 {
+		l.heading("Angehängte PDFs")
+		for _, a := range doc.Attachments {
+			l.paragraph(margin, pageWidth-2*margin, 10, false, black, a.Filename)
+		}
+		l.paragraph(margin, pageWidth-2*margin, 8, false, gray, "Die Dateien sind als Anhang in dieses PDF eingebettet.")
+	}
+	if len(doc.Comments) > 0 {
+		l.heading("Kommentare")
+		for _, c := range doc.Comments {
+			l.need(30)
+			l.paragraph(margin, pageWidth-2*margin, 8, true, gray, c.Author+" am "+c.Date)
+			l.paragraph(margin, pageWidth-2*margin, 10, false, black, c.Body)
+			l.y -= 4
+			l.line(margin, l.y, pageWidth-margin, l.y, border)
+			l.y -= 8
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return l.write(doc)
+}
+
+func (b *Builtin) Merge(ctx context.Context, pdfs [][]byte) ([]byte, error) {
+	return nil, ErrMergeUnsupported
+}
+
+func (l *layout) newPage() {
+	l.pages = append(l.pages, &page{})
+	l.y = pageHeight - margin
+}
+
+func (l *layout) page() *page {
+	return l.pages[len(l.pages)-1]
+}
+
+// Starts a new page, when 'h' doesn't fit on the current one anymore
+func (l *layout) need(h float64) {
+	if l.y-h < margin+footer {
+		l.newPage()
+	}
+}
+
+func leading(size float64) float64 {
+	return size * 1.35
+}
+
+func font(bold bool) string {
+	if bold {
+		return "F2"
+	}
+	return "F1"
+}
+
+func (l *layout) text(x, baseline, size float64, bold bool, color string, text []byte) {
+	fmt.Fprintf(&l.page().content, "BT %s rg /%s %.2f Tf %.2f %.2f Td %s Tj ET\n",
+		color, font(bold), size, x, baseline, literal(text))
+}
+
+func (l *layout) line(x1, y1, x2, y2 float64, color string) {
+	fmt.Fprintf(&l.page().content, "%s RG 0.5 w %.2f %.2f m %.2f %.2f l S\n", color, x1, y1, x2, y2)
+}
+
+func (l *layout) rect(x, y, w, h float64, stroke string, fill string) {
+	c := &l.page().content
+	if fill != "" {
+		fmt.Fprintf(c, "%s rg %.2f %.2f %.2f %.2f re f\n", fill, x, y, w, h)
+	}
+	if stroke != "" {
+		fmt.Fprintf(c, "%s RG 0.5 w %.2f %.2f %.2f %.2f re S\n", stroke, x, y, w, h)
+	}
+}
+
+// Breaks the text into lines, which fit into 'width'.
+// Words longer than a line are broken anywhere.
+func wrap(s string, size float64, bold bool, width float64) [][]byte {
+	var lines [][]byte
+	for _, para := range strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n") {
+		var cur []byte
+		for _, word := range strings.Fields(para) {
+			w := encode(word)
+			candidate := w
+			if len(cur) > 0 {
+				candidate = append(append(append([]byte{}, cur...), ' '), w...)
+			}
+			if textWidth(candidate, size, bold) <= width {
+				cur = candidate
+				continue
+			}
+			if len(cur) > 0 {
+				lines = append(lines, cur)
+			}
+			// Breaks a single long word, e.g. a url
+			for textWidth(w, size, bold) > width {
+				n := 1
+				for n < len(w) && textWidth(w[:n+1], size, bold) <= width {
+					n++
+				}
+				lines = append(lines, w[:n])
+				w = w[n:]
+			}
+			cur = w
+		}
+		lines = append(lines, cur)
+	}
+	return lines
+}
+
+// Draws wrapped text and moves below it.
+// Long texts continue on the next page.
+func (l *layout) paragraph(x, width, size float64, bold bool, color string, s string) {
+	for _, line := range wrap(s, size, bold, width) {
+		l.need(leading(size))
+		l.text(x, l.y-size, size, bold, color, line)
+		l.y -= leading(size)
+	}
+}
+
+func (l *layout) heading(s string) {
+	l.y -= 12
+	l.need(40)
+	l.paragraph(margin, pageWidth-2*margin, 13, true, black, s)
+	l.y -= 4
+}
+
+// The values of the entry as two columns: description and value
+func (l *layout) table(fields []Field, progress string) {
+	const size = 10.0
+	const pad = 5.0
+	descWidth := 160.0
+	valueWidth := pageWidth - 2*margin - descWidth
+	rows := append(append([]Field{}, fields...), Field{Desc: "Fortschritt", Value: progress})
+	for _, f := range rows {
+		desc := wrap(f.Desc, size, true, descWidth-2*pad)
+		value := wrap(f.Value, size, false, valueWidth-2*pad)
+		n := max(len(desc), len(value))
+		h := float64(n)*leading(size) + 2*pad
+		l.need(h)
+		top := l.y
+		l.rect(margin, top-h, descWidth, h, "", headerBg)
+		l.rect(margin, top-h, descWidth, h, border, "")
+		l.rect(margin+descWidth, top-h, valueWidth, h, border, "")
+		for i, line := range desc {
+			l.text(margin+pad, top-pad-size-float64(i)*leading(size), size, true, gray, line)
+		}
+		for i, line := range value {
+			l.text(margin+descWidth+pad, top-pad-size-float64(i)*leading(size), size, false, black, line)
+		}
+		l.y = top - h
+	}
+}
+
+func (l *layout) items(items []Item, level int) {
+	const size = 10.0
+	const box = 9.0
+	for _, item := range items {
+		x := margin + float64(level)*indent
+		textX := x + box + 6
+		width := pageWidth - margin - textX
+		l.need(leading(size))
+		baseline := l.y - size
+		if item.Checked {
+			l.rect(x, baseline-1, box, box, blue, "")
+			fmt.Fprintf(&l.page().content, "%s RG 1.2 w %.2f %.2f m %.2f %.2f l %.2f %.2f l S\n", blue,
+				x+2, baseline+3.5, x+4, baseline+1.3, x+7.5, baseline+6.5)
+		} else {
+			l.rect(x, baseline-1, box, box, black, "")
+		}
+		l.paragraph(textX, width, size, false, black, item.Task)
+		if item.Text != nil {
+			l.answer(textX, min(275, width), *item.Text)
+		}
+		if len(item.Images) > 0 {
+			l.images(textX, item.Images)
+		}
+		l.y -= 3
+		l.items(item.Children, level+1)
+	}
+}
+
+// Text answer in a box like the disabled input of print.html
+func (l *layout) answer(x, width float64, s string) {
+	const size = 9.0
+	const pad = 3.0
+	lines := wrap(s, size, false, width-2*pad)
+	h := float64(len(lines))*leading(size) + 2*pad
+	// Very long answers are split over the pages without a box
+	if h > pageHeight-2*margin-footer {
+		l.paragraph(x, width, size, false, black, s)
+		return
+	}
+	l.y -= 2
+	l.need(h)
+	top := l.y
+	l.rect(x, top-h, width, h, black, "")
+	for i, line := range lines {
+		l.text(x+pad, top-pad-size-float64(i)*leading(size), size, false, black, line)
+	}
+	l.y = top - h - 2
+}
+
+// Draws the images in rows, scaled down to 'maxImage'.
+// Files, which can't be decoded (e.g. WebP), are listed by their name.
+func (l *layout) images(x float64, images []Image) {
+	const gap = 4.0
+	var failed []string
+	cx, rowHeight := x, 0.0
+	for _, img := range images {
+		decoded, err := decodeImage(img.Data)
+		if err != nil {
+			failed = append(failed, img.Filename)
+			continue
+		}
+		scale := min(maxImage/float64(decoded.width), maxImage/float64(decoded.height), 1)
+		w := float64(decoded.width) * scale
+		h := float64(decoded.height) * scale
+		// Next row
+		if cx+w > pageWidth-margin && cx > x {
+			l.y -= rowHeight + gap
+			cx, rowHeight = x, 0
+		}
+		if l.y-h < margin+footer {
+			l.newPage()
+			cx, rowHeight = x, 0
+		}
+		l.xobjects = append(l.xobjects, decoded)
+		fmt.Fprintf(&l.page().content, "q %.2f 0 0 %.2f %.2f %.2f cm /Im%d Do Q\n", w, h, cx, l.y-h, len(l.xobjects)-1)
+		l.rect(cx, l.y-h, w, h, border, "")
+		cx += w + gap
+		rowHeight = max(rowHeight, h)
+	}
+	if rowHeight > 0 {
+		l.y -= rowHeight + gap
+	}
+	for _, name := range failed {
+		l.paragraph(x, pageWidth-margin-x, 9, false, gray, "[Bild: "+name+"]")
+	}
+}
+
+// Decodes png, jpeg and gif into RGB on a white background,
+// scaled down to 'maxPixels' on the longer side
+func decodeImage(data []byte) (pdfImage, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return pdfImage{}, err
+	}
+	b := img.Bounds()
+	step := 1
+	for max(b.Dx(), b.Dy())/step > maxPixels {
+		step++
+	}
+	w, h := b.Dx()/step, b.Dy()/step
+	if w == 0 || h == 0 {
+		return pdfImage{}, fmt.Errorf("image is empty")
+	}
+	rgb := make([]byte, 0, w*h*3)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			// Average of the pixels, which become one
+			var r, g, bl, n uint32
+			for dy := 0; dy < step; dy++ {
+				for dx := 0; dx < step; dx++ {
+					cr, cg, cb, ca := img.At(b.Min.X+x*step+dx, b.Min.Y+y*step+dy).RGBA()
+					// The colors are premultiplied, the rest is white
+					r += cr + 0xffff - ca
+					g += cg + 0xffff - ca
+					bl += cb + 0xffff - ca
+					n++
+				}
+			}
+			rgb = append(rgb, byte(r/n>>8), byte(g/n>>8), byte(bl/n>>8))
+		}
+	}
+	return pdfImage{width: w, height: h, rgb: rgb}, nil
+}
+
+// Writes the laid out pages, the fonts, the images and the attachments into the file
+func (l *layout) write(doc Document) ([]byte, error) {
+	w := newWriter()
+	catalog := w.reserve()
+	pages := w.reserve()
+	resources := w.reserve()
+	info := w.reserve()
+
+	regular := w.reserve()
+	w.object(regular, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	bold := w.reserve()
+	w.object(bold, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+
+	var xobjects strings.Builder
+	for i, img := range l.xobjects {
+		id := w.reserve()
+		w.stream(id, fmt.Sprintf("/Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceRGB /BitsPerComponent 8",
+			img.width, img.height), img.rgb)
+		fmt.Fprintf(&xobjects, "/Im%d %d 0 R ", i, id)
+	}
+	w.object(resources, fmt.Sprintf("<< /Font << /F1 %d 0 R /F2 %d 0 R >> /XObject << %s>> >>", regular, bold, xobjects.String()))
+
+	var kids strings.Builder
+	for i, p := range l.pages {
+		// Page numbers are known only after the layout
+		number := encode(fmt.Sprintf("Seite %d von %d", i+1, len(l.pages)))
+		fmt.Fprintf(&p.content, "BT %s rg /F1 8 Tf %.2f %.2f Td %s Tj ET\n", gray,
+			pageWidth-margin-textWidth(number, 8, false), margin, literal(number))
+		content := w.reserve()
+		w.stream(content, "", p.content.Bytes())
+		id := w.reserve()
+		w.object(id, fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %.2f %.2f] /Resources %d 0 R /Contents %d 0 R >>",
+			pages, pageWidth, pageHeight, resources, content))
+		fmt.Fprintf(&kids, "%d 0 R ", id)
+	}
+	w.object(pages, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", kids.String(), len(l.pages)))
+
+	var names strings.Builder
+	for i, a := range doc.Attachments {
+		file := w.reserve()
+		w.stream(file, fmt.Sprintf("/Type /EmbeddedFile /Subtype /application#2Fpdf /Params << /Size %d >>", len(a.Data)), a.Data)
+		spec := w.reserve()
+		w.object(spec, fmt.Sprintf("<< /Type /Filespec /F %s /UF %s /EF << /F %d 0 R >> >>",
+			literal(encode(a.Filename)), textString(a.Filename), file))
+		// The keys of the name tree have to be sorted
+		fmt.Fprintf(&names, "(%04d) %d 0 R ", i, spec)
+	}
+	if names.Len() > 0 {
+		w.object(catalog, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R /Names << /EmbeddedFiles << /Names [%s] >> >> /PageMode /UseAttachments >>",
+			pages, names.String()))
+	} else {
+		w.object(catalog, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pages))
+	}
+	w.object(info, fmt.Sprintf("<< /Title %s /Producer (checklist-tool) >>", textString(strings.TrimSuffix(doc.Name, ".pdf"))))
+	return w.finish(catalog, info)
+}
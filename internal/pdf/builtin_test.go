@@ -0,0 +1,121 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func testPNG(t *testing.T) []byte {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, 20, 10))
+	for x := 0; x < 20; x++ {
+		img.Set(x, 5, color.NRGBA{R: 255, A: 255})
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// Text of all content streams, decompressed
+func contents(t *testing.T, file []byte) string {
+	t.Helper()
+	var all strings.Builder
+	re := regexp.MustCompile(`(?s)<< /Filter /FlateDecode /Length (\d+) >>\nstream\n`)
+	for _, m := range re.FindAllSubmatchIndex(file, -1) {
+		n, _ := strconv.Atoi(string(file[m[2]:m[3]]))
+		zr, err := zlib.NewReader(bytes.NewReader(file[m[1] : m[1]+n]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(zr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		all.Write(data)
+	}
+	return all.String()
+}
+
+func TestBuiltinRender(t *testing.T) {
+	answer := "Ticket (42) für Müller"
+	var items []Item
+	for i := 0; i < 60; i++ {
+		items = append(items, Item{Task: "Punkt " + strconv.Itoa(i), Checked: i%2 == 0})
+	}
+	items[0].Text = &answer
+	items[1].Images = []Image{{Filename: "foto.png", Data: testPNG(t)}, {Filename: "foto.webp", Data: []byte("RIFF")}}
+	items[2].Children = []Item{{Task: "Unterpunkt", Checked: true}}
+	doc := Document{
+		Name:        "20250101_Max.pdf",
+		Fields:      []Field{{Desc: "Name", Value: "Max"}, {Desc: "Erstellungsdatum", Value: "01.01.2025 10:00:00"}},
+		Progress:    "50% (30/61)",
+		Date:        "01.01.2025, 12:00:00",
+		Items:       items,
+		Comments:    []Comment{{Author: "Anna", Date: "01.01.2025", Body: "Gerät übergeben.\n**Fertig**"}},
+		Attachments: []Attachment{{Filename: "rechnung.pdf", Data: []byte("%PDF-1.4 test")}},
+	}
+	file, err := (&Builtin{}).Render(context.Background(), doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(file, []byte("%PDF-1.7")) || !bytes.HasSuffix(file, []byte("%%EOF\n")) {
+		t.Fatalf("not a pdf file")
+	}
+
+	// Every entry of the cross-reference table points to its object
+	start := bytes.LastIndex(file, []byte("\nxref\n")) + 1
+	m := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(file)
+	if m == nil || string(m[1]) != strconv.Itoa(start) {
+		t.Fatalf("startxref doesn't point to the xref table")
+	}
+	lines := strings.Split(string(file[start:]), "\n")
+	count, _ := strconv.Atoi(strings.Fields(lines[1])[1])
+	for id := 1; id < count; id++ {
+		offset, _ := strconv.Atoi(lines[2+id][:10])
+		if want := strconv.Itoa(id) + " 0 obj"; !bytes.HasPrefix(file[offset:], []byte(want)) {
+			t.Errorf("object %d isn't at offset %d", id, offset)
+		}
+	}
+
+	text := contents(t, file)
+	for _, want := range []string{
+		"(Max)", "(Exportiert am 01.01.2025, 12:00:00)", "(Unterpunkt)",
+		// WinAnsiEncoding and escaped parentheses
+		"(Ticket \\(42\\) f\xfcr M\xfcller)",
+		"/Im0 Do", "([Bild: foto.webp])", "(Kommentare)",
+		"(Seite 1 von 2)", "(Seite 2 von 2)",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("expected %q in the content", want)
+		}
+	}
+	if !bytes.Contains(file, []byte("/EmbeddedFiles")) || !bytes.Contains(file, []byte("%PDF-1.4 test")) {
+		t.Errorf("expected the attached pdf to be embedded")
+	}
+	if n, err := PageCount(file); err != nil || n != 2 {
+		t.Errorf("expected 2 pages, got %d (%v)", n, err)
+	}
+}
+
+func TestWrap(t *testing.T) {
+	lines := wrap("eins zwei drei\n\nvier "+strings.Repeat("x", 100), 10, false, 60)
+	for _, l := range lines {
+		if textWidth(l, 10, false) > 60 {
+			t.Errorf("line %q is too long", l)
+		}
+	}
+	if string(lines[0]) != "eins zwei" || string(lines[1]) != "drei" || len(lines[2]) != 0 || string(lines[3]) != "vier" {
+		t.Errorf("unexpected lines %q", lines)
+	}
+}
@@ -0,0 +1,109 @@
+package pdf
+
+import "unicode/utf8"
+
+// Widths of the characters 32 to 255 of the standard fonts Helvetica and Helvetica-Bold
+// in WinAnsiEncoding, in thousandths of the font size.
+// Every pdf viewer has these fonts, so they don't need to be embedded.
+var helveticaWidths = [224]uint16{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584, 350,
+	556, 350, 222, 556, 333, 1000, 556, 556, 333, 1000, 667, 333, 1000, 350, 611, 350,
+	350, 222, 222, 333, 333, 350, 556, 1000, 333, 1000, 500, 333, 944, 350, 500, 667,
+	278, 333, 556, 556, 556, 556, 260, 556, 333, 737, 370, 556, 584, 333, 737, 333,
+	400, 584, 333, 333, 333, 556, 537, 278, 333, 333, 365, 556, 834, 834, 834, 611,
+	667, 667, 667, 667, 667, 667, 1000, 722, 667, 667, 667, 667, 278, 278, 278, 278,
+	722, 722, 778, 778, 778, 778, 778, 584, 778, 722, 722, 722, 722, 667, 667, 611,
+	556, 556, 556, 556, 556, 556, 889, 500, 556, 556, 556, 556, 278, 278, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 584, 611, 556, 556, 556, 556, 500, 556, 500,
+}
+
+var helveticaBoldWidths = [224]uint16{
+	278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+	975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+	333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+	611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584, 350,
+	556, 350, 278, 556, 500, 1000, 556, 556, 333, 1000, 667, 333, 1000, 350, 611, 350,
+	350, 278, 278, 500, 500, 350, 556, 1000, 333, 1000, 556, 333, 944, 350, 500, 667,
+	278, 333, 556, 556, 556, 556, 280, 556, 333, 737, 370, 556, 584, 333, 737, 333,
+	400, 584, 333, 333, 333, 611, 556, 278, 333, 333, 365, 556, 834, 834, 834, 611,
+	722, 722, 722, 722, 722, 722, 1000, 722, 667, 667, 667, 667, 278, 278, 278, 278,
+	722, 722, 778, 778, 778, 778, 778, 584, 778, 722, 722, 722, 722, 667, 667, 611,
+	556, 556, 556, 556, 556, 556, 889, 556, 556, 556, 556, 556, 278, 278, 278, 278,
+	611, 611, 611, 611, 611, 611, 611, 584, 611, 611, 611, 611, 611, 556, 611, 556,
+}
+
+// Characters of WinAnsiEncoding (Windows-1252), which differ from Latin-1
+var winAnsi = map[rune]byte{
+	0x20AC: 0x80, // €
+	0x201A: 0x82, // ‚
+	0x0192: 0x83, // ƒ
+	0x201E: 0x84, // „
+	0x2026: 0x85, // …
+	0x2020: 0x86, // †
+	0x2021: 0x87, // ‡
+	0x02C6: 0x88, // ˆ
+	0x2030: 0x89, // ‰
+	0x0160: 0x8A, // Š
+	0x2039: 0x8B, // ‹
+	0x0152: 0x8C, // Œ
+	0x017D: 0x8E, // Ž
+	0x2018: 0x91, // ‘
+	0x2019: 0x92, // ’
+	0x201C: 0x93, // “
+	0x201D: 0x94, // ”
+	0x2022: 0x95, // •
+	0x2013: 0x96, // –
+	0x2014: 0x97, // —
+	0x02DC: 0x98, // ˜
+	0x2122: 0x99, // ™
+	0x0161: 0x9A, // š
+	0x203A: 0x9B, // ›
+	0x0153: 0x9C, // œ
+	0x017E: 0x9E, // ž
+	0x0178: 0x9F, // Ÿ
+}
+
+// Converts text to WinAnsiEncoding.
+// Characters, which the standard fonts don't have, become '?'.
+func encode(s string) []byte {
+	out := make([]byte, 0, len(s))
+	for _, r := range s {
+		switch {
+		case r == '\t':
+			out = append(out, ' ')
+		case r >= 32 && r < 127, r >= 160 && r <= 255:
+			out = append(out, byte(r))
+		case r == utf8.RuneError:
+			out = append(out, '?')
+		default:
+			if b, ok := winAnsi[r]; ok {
+				out = append(out, b)
+			} else if r >= 32 {
+				out = append(out, '?')
+			}
+		}
+	}
+	return out
+}
+
+// Width of the encoded text in points
+func textWidth(text []byte, size float64, bold bool) float64 {
+	widths := &helveticaWidths
+	if bold {
+		widths = &helveticaBoldWidths
+	}
+	var w float64
+	for _, c := range text {
+		if c >= 32 {
+			w += float64(widths[c-32])
+		}
+	}
+	return w * size / 1000
+}
@@ -0,0 +1,169 @@
+package pdf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/starwalkn/gotenberg-go-client/v8"
+	"github.com/starwalkn/gotenberg-go-client/v8/document"
+)
+
+// Returned when gotenberg can't be reached or fails to create the pdf,
+// even after all retries.
+var ErrUnavailable = errors.New("gotenberg is not available")
+
+// Prints the html of the checklist with Chromium in a gotenberg container
+// and appends the attached pdfs to it.
+type Gotenberg struct {
+	URL string
+	// Limit for a single request, 0 disables it
+	Timeout time.Duration
+	// How often a request is repeated, when gotenberg can't be reached or answers with 5xx
+	Retries int
+	// Used for all requests, http.DefaultClient when nil
+	Client *http.Client
+}
+
+var _ Renderer = (*Gotenberg)(nil)
+
+// Waiting time before the first retry, doubled with each further one
+var retryDelay = 500 * time.Millisecond
+
+func (g *Gotenberg) Render(ctx context.Context, doc Document) ([]byte, error) {
+	html, err := document.FromBytes(doc.Name, doc.HTML)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create the HTML request.
+	req := gotenberg.NewHTMLRequest(html)
+
+	// Set the document parameters to request (optional).
+	req.Margins(gotenberg.NoMargins)
+	req.Scale(0.90)
+	req.PaperSize(gotenberg.A4)
+
+	// Skips the IDLE events for faster PDF conversion.
+	req.SkipNetworkIdleEvent(true)
+	pdfBytes, err := g.send(ctx, func(ctx context.Context, c *gotenberg.Client) (*http.Response, error) {
+		return c.Send(ctx, req)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("couldn't print '%s': %w", doc.Name, err)
+	}
+
+	// Attached pdfs are appended to the checklist
+	if len(doc.Attachments) == 0 {
+		return pdfBytes, nil
+	}
+	pdfs := [][]byte{pdfBytes}
+	for _, a := range doc.Attachments {
+		pdfs = append(pdfs, a.Data)
+	}
+	merged, err := g.Merge(ctx, pdfs)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't append the attachments to the pdf: %w", err)
+	}
+	return merged, nil
+}
+
+// Longest time a Render can take: printing the html and appending the attachments,
+// each with all its attempts and the delays between them. 0 when g.Timeout is off.
+func (g *Gotenberg) MaxRenderTime() time.Duration {
+	if g.Timeout <= 0 {
+		return 0
+	}
+	request := g.Timeout * time.Duration(g.Retries+1)
+	delay := retryDelay
+	for range g.Retries {
+		request += delay
+		delay *= 2
+	}
+	return 2 * request
+}
+
+func (g *Gotenberg) Merge(ctx context.Context, pdfs [][]byte) ([]byte, error) {
+	var docs []document.Document
+	for i, p := range pdfs {
+		// gotenberg merges the files in alphabetical order of their names
+		doc, err := document.FromBytes(fmt.Sprintf("%04d.pdf", i), p)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, doc)
+	}
+	req := gotenberg.NewMergeRequest(docs...)
+	merged, err := g.send(ctx, func(ctx context.Context, c *gotenberg.Client) (*http.Response, error) {
+		return c.Send(ctx, req)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("couldn't merge %d pdfs: %w", len(pdfs), err)
+	}
+	return merged, nil
+}
+
+// Answer of gotenberg other than 200 OK
+type StatusError struct {
+	Code int
+	// Start of the body, gotenberg explains the error there
+	Message string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("gotenberg answered with %d: %s", e.Code, e.Message)
+}
+
+// Sends the request built by 'send' and returns the body of the answer.
+// Requests failing on the network or with 5xx are repeated up to g.Retries times.
+// Errors caused by the request itself (4xx) and a canceled 'ctx' end it right away.
+func (g *Gotenberg) send(ctx context.Context, send func(context.Context, *gotenberg.Client) (*http.Response, error)) ([]byte, error) {
+	httpClient := g.Client
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	client, err := gotenberg.NewClient(g.URL, httpClient)
+	if err != nil {
+		return nil, err
+	}
+	delay := retryDelay
+	for attempt := 0; ; attempt++ {
+		body, err := g.attempt(ctx, client, send)
+		var status *StatusError
+		retry := err != nil && ctx.Err() == nil &&
+			(!errors.As(err, &status) || status.Code >= http.StatusInternalServerError)
+		if !retry {
+			return body, err
+		}
+		if attempt >= g.Retries {
+			return nil, fmt.Errorf("%w after %d attempts: %w", ErrUnavailable, attempt+1, err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+func (g *Gotenberg) attempt(ctx context.Context, client *gotenberg.Client, send func(context.Context, *gotenberg.Client) (*http.Response, error)) ([]byte, error) {
+	if g.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, g.Timeout)
+		defer cancel()
+	}
+	resp, err := send(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, &StatusError{Code: resp.StatusCode, Message: string(msg)}
+	}
+	return io.ReadAll(resp.Body)
+}
@@ -0,0 +1,123 @@
+package pdf
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hmaier-dev/checklist-tool/internal/pdf/gotenbergtest"
+)
+
+func init() {
+	// Tests don't wait for the retries
+	retryDelay = time.Millisecond
+}
+
+func TestGotenbergRender(t *testing.T) {
+	fake := gotenbergtest.NewServer(t)
+	g := &Gotenberg{URL: fake.URL}
+	doc := Document{
+		Name:        "test.pdf",
+		HTML:        []byte("<p>Punkt 1</p>"),
+		Attachments: []Attachment{{Filename: "a.pdf", Data: []byte("-A-")}, {Filename: "b.pdf", Data: []byte("-B-")}},
+	}
+	got, err := g.Render(context.Background(), doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The attachments are appended in their order
+	if want := "%PDF-fake\n<p>Punkt 1</p>-A--B-"; string(got) != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+	requests := fake.Requests()
+	if len(requests) != 2 || requests[0].Route != gotenbergtest.RouteHTML || requests[1].Route != gotenbergtest.RouteMerge {
+		t.Errorf("expected a print and a merge, got %+v", requests)
+	}
+}
+
+func TestGotenbergRetries(t *testing.T) {
+	fake := gotenbergtest.NewServer(t)
+	g := &Gotenberg{URL: fake.URL, Retries: 2}
+	doc := Document{Name: "test.pdf", HTML: []byte("<p></p>")}
+
+	// Overloaded gotenberg recovers
+	fake.Fail(http.StatusServiceUnavailable, http.StatusServiceUnavailable)
+	if _, err := g.Render(context.Background(), doc); err != nil {
+		t.Fatalf("expected the third attempt to succeed, got %v", err)
+	}
+	if n := len(fake.Requests()); n != 3 {
+		t.Errorf("expected 3 requests, got %d", n)
+	}
+
+	// No more retries left
+	fake.Fail(http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError)
+	_, err := g.Render(context.Background(), doc)
+	if !errors.Is(err, ErrUnavailable) {
+		t.Errorf("expected ErrUnavailable, got %v", err)
+	}
+
+	// A bad request doesn't get better by repeating it
+	before := len(fake.Requests())
+	fake.Fail(http.StatusBadRequest)
+	_, err = g.Render(context.Background(), doc)
+	var status *StatusError
+	if !errors.As(err, &status) || status.Code != http.StatusBadRequest || errors.Is(err, ErrUnavailable) {
+		t.Errorf("expected a StatusError with 400, got %v", err)
+	}
+	if n := len(fake.Requests()) - before; n != 1 {
+		t.Errorf("expected 1 request, got %d", n)
+	}
+}
+
+func TestGotenbergUnreachable(t *testing.T) {
+	g := &Gotenberg{URL: "http://127.0.0.1:1", Retries: 1}
+	_, err := g.Render(context.Background(), Document{Name: "test.pdf", HTML: []byte("<p></p>")})
+	if !errors.Is(err, ErrUnavailable) || !strings.Contains(err.Error(), "2 attempts") {
+		t.Errorf("expected ErrUnavailable after 2 attempts, got %v", err)
+	}
+}
+
+func TestGotenbergTimeout(t *testing.T) {
+	fake := gotenbergtest.NewServer(t)
+	fake.Delay(time.Second)
+	g := &Gotenberg{URL: fake.URL, Timeout: 50 * time.Millisecond}
+	start := time.Now()
+	_, err := g.Render(context.Background(), Document{Name: "test.pdf", HTML: []byte("<p></p>")})
+	if !errors.Is(err, context.DeadlineExceeded) || !errors.Is(err, ErrUnavailable) {
+		t.Errorf("expected a timeout, got %v", err)
+	}
+	if time.Since(start) > 500*time.Millisecond {
+		t.Errorf("expected the request to be stopped after the timeout")
+	}
+}
+
+func TestGotenbergMaxRenderTime(t *testing.T) {
+	g := &Gotenberg{Timeout: 10 * time.Second, Retries: 2}
+	// Two requests with three attempts each, waiting 1ms and 2ms in between
+	if got, want := g.MaxRenderTime(), 2*(30*time.Second+3*time.Millisecond); got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+	g.Timeout = 0
+	if got := g.MaxRenderTime(); got != 0 {
+		t.Errorf("expected no limit, got %s", got)
+	}
+}
+
+func TestGotenbergCanceled(t *testing.T) {
+	fake := gotenbergtest.NewServer(t)
+	fake.Delay(time.Second)
+	g := &Gotenberg{URL: fake.URL, Retries: 3}
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	_, err := g.Render(ctx, Document{Name: "test.pdf", HTML: []byte("<p></p>")})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the request to be canceled, got %v", err)
+	}
+	// Canceled requests aren't repeated
+	if n := len(fake.Requests()); n != 1 {
+		t.Errorf("expected 1 request, got %d", n)
+	}
+}
@@ -0,0 +1,137 @@
+// Fake gotenberg running inside the test process,
+// so the pdf export can be tested without the container.
+package gotenbergtest
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	RouteHTML  = "/forms/chromium/convert/html"
+	RouteMerge = "/forms/pdfengines/merge"
+)
+
+// Request received by the fake
+type Request struct {
+	Route string
+	// Uploaded files by their name
+	Files map[string][]byte
+}
+
+// Answers like gotenberg, but without creating real pdfs:
+// RouteHTML returns "%PDF-fake\n" followed by the uploaded index.html,
+// RouteMerge returns the uploaded files joined in the order of their names.
+type Server struct {
+	URL string
+
+	mu       sync.Mutex
+	requests []Request
+	failures []int
+	delay    time.Duration
+}
+
+// Starts the fake and stops it, when the test ends
+func NewServer(tb testing.TB) *Server {
+	tb.Helper()
+	s := &Server{}
+	ts := httptest.NewServer(http.HandlerFunc(s.serve))
+	tb.Cleanup(ts.Close)
+	s.URL = ts.URL
+	return s
+}
+
+// The next requests are answered with these status codes, one per request
+func (s *Server) Fail(status ...int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = append(s.failures, status...)
+}
+
+// Every answer is delayed by 'd', e.g. to run into a timeout
+func (s *Server) Delay(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.delay = d
+}
+
+// All requests received so far, including the failed ones
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request{}, s.requests...)
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	req := Request{Route: r.URL.Path, Files: make(map[string][]byte)}
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	for _, header := range r.MultipartForm.File["files"] {
+		f, err := header.Open()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		data, err := io.ReadAll(f)
+		f.Close()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		req.Files[header.Filename] = data
+	}
+
+	s.mu.Lock()
+	s.requests = append(s.requests, req)
+	status := http.StatusOK
+	if len(s.failures) > 0 {
+		status = s.failures[0]
+		s.failures = s.failures[1:]
+	}
+	delay := s.delay
+	s.mu.Unlock()
+
+	select {
+	case <-time.After(delay):
+	case <-r.Context().Done():
+		return
+	}
+	if status != http.StatusOK {
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+
+	var body bytes.Buffer
+	switch req.Route {
+	case RouteHTML:
+		html, ok := req.Files["index.html"]
+		if !ok {
+			http.Error(w, "index.html is missing", http.StatusBadRequest)
+			return
+		}
+		body.WriteString("%PDF-fake\n")
+		body.Write(html)
+	case RouteMerge:
+		var names []string
+		for name := range req.Files {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			body.Write(req.Files[name])
+		}
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Write(body.Bytes())
+}
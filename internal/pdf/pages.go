@@ -0,0 +1,40 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+)
+
+// Limits for PageCount. Merged exports of a few hundred entries stay far below.
+const (
+	maxDecodeBytes = 64 << 20
+	maxObjects     = 1_000_000
+)
+
+func init() {
+	// Without this, pdfcpu writes its configuration to the home directory on first use
+	// and exits the process, when it can't.
+	api.DisableConfigDir()
+}
+
+// Reads the number of pages of a pdf with pdfcpu, the engine gotenberg merges with.
+// The pdfs include uploaded attachments, so a broken file has to return an error.
+func PageCount(data []byte) (n int, err error) {
+	// pdfcpu panics on some malformed files, one upload mustn't take down the server
+	defer func() {
+		if r := recover(); r != nil {
+			n, err = 0, fmt.Errorf("couldn't read the pdf: %v", r)
+		}
+	}()
+	conf := model.NewDefaultConfiguration()
+	conf.ValidationMode = model.ValidationRelaxed
+	// A stream can't be longer than the file, the defaults allow 512 MB per stream
+	conf.Limits.MaxStreamBytes = int64(len(data))
+	conf.Limits.MaxDecodeBytes = maxDecodeBytes
+	conf.Limits.MaxObjectCount = maxObjects
+	conf.Limits.MaxXRefEntries = maxObjects
+	return api.PageCount(bytes.NewReader(data), conf)
+}
@@ -0,0 +1,101 @@
+package pdf
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestPageCount(t *testing.T) {
+	// Three entries printed by the built-in renderer, merged by pdfcpu like gotenberg does.
+	// The page tree is compressed into an object stream, found through a cross-reference stream.
+	data, err := os.ReadFile("testdata/merged.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := PageCount(data); err != nil || n != 3 {
+		t.Errorf("expected 3 pages, got %d (%v)", n, err)
+	}
+	// The fake of gotenberg returns html instead of a pdf
+	if _, err := PageCount([]byte("%PDF-fake\n<p>Punkt 1</p>")); err == nil {
+		t.Errorf("expected an error for a file, which isn't a pdf")
+	}
+}
+
+// Builds a pdf with a cross-reference table from the bodies of objects 1…n.
+// 'offset' may replace the offset of an object in the table.
+func testPDF(objects []string, offset func(nr, actual int) int) []byte {
+	var b strings.Builder
+	b.WriteString("%PDF-1.7\n")
+	offsets := make([]int, len(objects))
+	for i, o := range objects {
+		offsets[i] = b.Len()
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, o)
+	}
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for i, o := range offsets {
+		if offset != nil {
+			o = offset(i+1, o)
+		}
+		fmt.Fprintf(&b, "%010d 00000 n \n", o)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return []byte(b.String())
+}
+
+func TestPageCountMalformed(t *testing.T) {
+	page := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 595 842] >>",
+	}
+	if n, err := PageCount(testPDF(page, nil)); err != nil || n != 1 {
+		t.Fatalf("expected the valid pdf to have 1 page, got %d (%v)", n, err)
+	}
+	tests := map[string][]byte{
+		"offset past the end": testPDF(page, func(nr, actual int) int {
+			if nr == 2 {
+				return 99999
+			}
+			return actual
+		}),
+		"length refers to itself": testPDF(append(page[:2:2],
+			"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 595 842] /Contents 4 0 R >>",
+			"<< /Length 4 0 R >>\nstream\nBT ET\nendstream",
+		), nil),
+		"object stream inside itself": testPDF(append(page[:1:1],
+			"<< /Type /ObjStm /N 1 /First 4 /Length 4 0 R >>\nstream\n2 0 \nendstream",
+			"<< /Type /Page /Parent 2 0 R >>",
+			"<< /Length 2 0 R >>",
+		), nil),
+		"negative first": testPDF(append(page[:1:1],
+			"<< /Type /ObjStm /N 1 /First -5 /Length 4 >>\nstream\n2 0 \nendstream",
+		), nil),
+		"truncated": testPDF(page, nil)[:60],
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			// Only the absence of a panic or endless recursion matters, pdfcpu repairs some of these
+			PageCount(data)
+		})
+	}
+}
+
+func FuzzPageCount(f *testing.F) {
+	if data, err := os.ReadFile("testdata/merged.pdf"); err == nil {
+		f.Add(data)
+	}
+	f.Add(testPDF([]string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 595 842] >>",
+	}, nil))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		n, err := PageCount(data)
+		if err == nil && n < 0 {
+			t.Errorf("negative page count %d", n)
+		}
+	})
+}
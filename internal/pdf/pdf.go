@@ -1,35 +1,98 @@
 package pdf
 
 import (
-    "context"
-    "net/http"
-		"log"
-    
-    "github.com/starwalkn/gotenberg-go-client/v8"
-    "github.com/starwalkn/gotenberg-go-client/v8/document"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
 )
 
-func Generate(r *http.Request, pdfName string, bodyBytes []byte)(*http.Response, error){
-	client, err := gotenberg.NewClient("http://gotenberg:3000", http.DefaultClient)
-		if err != nil {
-			log.Fatalf("Couln't connect to gotenberg container. \nErr: %q \n", err)
-		}
-		doc, err := document.FromBytes(pdfName, bodyBytes)
-
-    // Create the HTML request.
-    req := gotenberg.NewHTMLRequest(doc)
-
-    // Set the document parameters to request (optional).
-    req.Margins(gotenberg.NoMargins)
-    req.Scale(0.90)
-    req.PaperSize(gotenberg.A4)
-
-    // Skips the IDLE events for faster PDF conversion.
-    req.SkipNetworkIdleEvent(true)
-    // If you wish to redirect the response directly to the browser, you may also use:
-    resp, err := client.Send(context.Background(), req)
-		if err != nil{
-			log.Fatalln(err)
-		}
-		return resp, nil
+// Checklist to export, prepared by the checklist handler.
+// Gotenberg prints 'HTML', the built-in renderer draws the other fields.
+type Document struct {
+	// File name of the pdf, also used as title
+	Name string
+	// The rendered print.html
+	HTML []byte
+	// Values of the entry, including the creation date
+	Fields   []Field
+	Progress string
+	// Date of the export
+	Date  string
+	Items []Item
+	// Images attached to the entry itself
+	Images   []Image
+	Comments []Comment
+	// Attached pdfs, appended to the exported pdf
+	Attachments []Attachment
+}
+
+type Field struct {
+	Desc  string
+	Value string
+}
+
+type Item struct {
+	Task    string
+	Checked bool
+	// nil, when the item has no text field
+	Text     *string
+	Images   []Image
+	Children []Item
+}
+
+type Image struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+type Comment struct {
+	Author string
+	Date   string
+	// Markdown
+	Body string
+}
+
+type Attachment struct {
+	Filename string
+	Data     []byte
+}
+
+// SHA-256 of the content of the document: fields, progress, items, images, comments and attachments.
+// 'Name', 'HTML' and 'Date' are left out, they contain the date of the export.
+// Two exports with the same hash show the same content, even when the revision of the entry
+// didn't change in between, e.g. after a new comment or attachment.
+func (doc Document) ContentHash() string {
+	doc.Name, doc.HTML, doc.Date = "", nil, ""
+	// Only slices, strings and bools, which always encode
+	data, _ := json.Marshal(doc)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Turns a checklist into a pdf
+type Renderer interface {
+	Render(ctx context.Context, doc Document) ([]byte, error)
+	// Merges several pdfs into one, in the order they are passed
+	Merge(ctx context.Context, pdfs [][]byte) ([]byte, error)
+}
+
+// Backends for the flag -pdf-backend
+const (
+	BackendGotenberg = "gotenberg"
+	BackendBuiltin   = "builtin"
+)
+
+// Returns the renderer for 'backend'. 'g' is the configured gotenberg client,
+// it is only used with BackendGotenberg.
+func NewRenderer(backend string, g *Gotenberg) (Renderer, error) {
+	switch backend {
+	case BackendGotenberg:
+		return g, nil
+	case BackendBuiltin:
+		return &Builtin{}, nil
+	}
+	return nil, fmt.Errorf("unknown pdf backend '%s', use '%s' or '%s'", backend, BackendGotenberg, BackendBuiltin)
 }
@@ -0,0 +1,28 @@
+package pdf
+
+import "testing"
+
+func TestContentHash(t *testing.T) {
+	doc := Document{
+		Name:     "Max_20240101.pdf",
+		HTML:     []byte("<p>01.01.2024</p>"),
+		Date:     "01.01.2024",
+		Fields:   []Field{{Desc: "Name", Value: "Max"}},
+		Progress: "50% (1/2)",
+	}
+	later := doc
+	later.Name, later.HTML, later.Date = "Max_20240102.pdf", []byte("<p>02.01.2024</p>"), "02.01.2024"
+	if doc.ContentHash() != later.ContentHash() {
+		t.Errorf("expected the date of the export not to change the hash")
+	}
+	commented := doc
+	commented.Comments = []Comment{{Author: "Anna", Body: "Display gesprungen"}}
+	if doc.ContentHash() == commented.ContentHash() {
+		t.Errorf("expected a comment to change the hash")
+	}
+	attached := doc
+	attached.Attachments = []Attachment{{Filename: "rechnung.pdf", Data: []byte("%PDF-1")}}
+	if doc.ContentHash() == attached.ContentHash() {
+		t.Errorf("expected an attachment to change the hash")
+	}
+}
@@ -0,0 +1,96 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"unicode/utf16"
+)
+
+// Writes the objects of a pdf file and the cross-reference table pointing to them.
+// Objects are numbered from 1 and can be reserved before they are written,
+// so they can refer to each other.
+type writer struct {
+	buf     bytes.Buffer
+	offsets []int
+}
+
+func newWriter() *writer {
+	w := &writer{}
+	// The binary comment tells tools, that the file isn't plain text
+	w.buf.WriteString("%PDF-1.7\n%\xe2\xe3\xcf\xd3\n")
+	return w
+}
+
+// Returns the number of a new object, which has to be written later
+func (w *writer) reserve() int {
+	w.offsets = append(w.offsets, -1)
+	return len(w.offsets)
+}
+
+func (w *writer) object(id int, body string) {
+	w.offsets[id-1] = w.buf.Len()
+	fmt.Fprintf(&w.buf, "%d 0 obj\n%s\nendobj\n", id, body)
+}
+
+// Writes a stream compressed with FlateDecode.
+// 'dict' are the entries of its dictionary without /Length and /Filter.
+func (w *writer) stream(id int, dict string, data []byte) {
+	var z bytes.Buffer
+	zw := zlib.NewWriter(&z)
+	zw.Write(data)
+	zw.Close()
+	if dict != "" {
+		dict += " "
+	}
+	w.offsets[id-1] = w.buf.Len()
+	fmt.Fprintf(&w.buf, "%d 0 obj\n<< %s/Filter /FlateDecode /Length %d >>\nstream\n", id, dict, z.Len())
+	w.buf.Write(z.Bytes())
+	w.buf.WriteString("\nendstream\nendobj\n")
+}
+
+// Writes the cross-reference table and the trailer and returns the whole file
+func (w *writer) finish(root int, info int) ([]byte, error) {
+	for i, o := range w.offsets {
+		if o < 0 {
+			return nil, fmt.Errorf("object %d was reserved, but never written", i+1)
+		}
+	}
+	start := w.buf.Len()
+	fmt.Fprintf(&w.buf, "xref\n0 %d\n0000000000 65535 f \n", len(w.offsets)+1)
+	for _, o := range w.offsets {
+		fmt.Fprintf(&w.buf, "%010d 00000 n \n", o)
+	}
+	fmt.Fprintf(&w.buf, "trailer\n<< /Size %d /Root %d 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n",
+		len(w.offsets)+1, root, info, start)
+	return w.buf.Bytes(), nil
+}
+
+// Literal string in WinAnsiEncoding for content streams
+func literal(text []byte) string {
+	var b bytes.Buffer
+	b.WriteByte('(')
+	for _, c := range text {
+		switch c {
+		case '(', ')', '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte(')')
+	return b.String()
+}
+
+// Text string for metadata like the title or file names.
+// UTF-16 keeps every character, not only the ones of WinAnsiEncoding.
+func textString(s string) string {
+	var b bytes.Buffer
+	b.WriteString("<FEFF")
+	for _, u := range utf16.Encode([]rune(s)) {
+		fmt.Fprintf(&b, "%04X", u)
+	}
+	b.WriteByte('>')
+	return b.String()
+}
@@ -7,18 +7,40 @@ import (
 
 	"github.com/gorilla/mux"
 
+	"github.com/hmaier-dev/checklist-tool/internal/archive"
+	"github.com/hmaier-dev/checklist-tool/internal/live"
+	"github.com/hmaier-dev/checklist-tool/internal/pdf"
 )
 
 type Server struct {
 	Router *mux.Router
 	DB *sql.DB
+	Config Config
+	// Open event streams of the checklists, see live.Hub
+	Live *live.Hub
+	// Backend exporting checklists as pdf, chosen by -pdf-backend
+	PDF pdf.Renderer
+	// Keeps every generated pdf, chosen by -archive-backend. nil turns archiving off.
+	Archive *archive.Archive
 }
 
-func NewServer(db *sql.DB) *Server {
+// Settings passed by flags to main.
+// Handlers can read them from the server in New()
+type Config struct {
+	// Days an entry stays in the trash before it gets purged.
+	// 0 disables purging.
+	PurgeAfterDays int
+	// Upper limit for a single attachment in megabytes
+	MaxAttachmentMB int64
+}
+
+func NewServer(db *sql.DB, cfg Config) *Server {
 	router := mux.NewRouter()
 	srv := &Server{
 		Router: router,
 		DB: db,
+		Config: cfg,
+		Live: live.NewHub(),
 	}
   router.PathPrefix("/static/").Handler(http.StripPrefix("/static/", http.FileServer(http.Dir("./static/"))))
 	return srv
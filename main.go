@@ -4,35 +4,61 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"io/fs"
 	"log"
 	"net/http"
 	"os"
-	_ "embed"
+	"embed"
 	"os/signal"
 	"syscall"
 	"time"
 	"database/sql"
 	_ "github.com/mattn/go-sqlite3"
 
+	"github.com/hmaier-dev/checklist-tool/internal/archive"
+	"github.com/hmaier-dev/checklist-tool/internal/database"
+	"github.com/hmaier-dev/checklist-tool/internal/pdf"
 	"github.com/hmaier-dev/checklist-tool/internal/server"
 	"github.com/hmaier-dev/checklist-tool/internal/handlers"
+	"github.com/hmaier-dev/checklist-tool/internal/handlers/checklist"
 
 	// blank import for handlers. They initalize theirself by init()
 	_ "github.com/hmaier-dev/checklist-tool/internal/handlers/all"
-	_ "github.com/hmaier-dev/checklist-tool/internal/handlers/checklist"
+	_ "github.com/hmaier-dev/checklist-tool/internal/handlers/backup"
+	_ "github.com/hmaier-dev/checklist-tool/internal/handlers/bulk"
+	_ "github.com/hmaier-dev/checklist-tool/internal/handlers/cases"
 	_ "github.com/hmaier-dev/checklist-tool/internal/handlers/delete"
 	_ "github.com/hmaier-dev/checklist-tool/internal/handlers/new"
+	_ "github.com/hmaier-dev/checklist-tool/internal/handlers/people"
+	_ "github.com/hmaier-dev/checklist-tool/internal/handlers/search"
 	_ "github.com/hmaier-dev/checklist-tool/internal/handlers/upload"
+	_ "github.com/hmaier-dev/checklist-tool/internal/handlers/views"
 	_ "github.com/hmaier-dev/checklist-tool/internal/handlers/history"
 )
 
 //go:embed schema.sql
 var ddl string
 
+//go:embed migrations/*.sql
+var migrations embed.FS
+
 func main() {
 	log.SetFlags(log.LstdFlags | log.Llongfile)
   dbArg := flag.String("db", "", "Path to sqlite database")
   port := flag.String("port", "8080", "Port handling http requests")
+  purgeAfter := flag.Int("purge-after", 30, "Days until deleted entries get purged from the trash (0 disables it)")
+  maxAttachment := flag.Int64("max-attachment-size", 10, "Maximum size of a single attachment in megabytes")
+  pdfBackend := flag.String("pdf-backend", pdf.BackendGotenberg, "Backend exporting pdfs, 'gotenberg' or 'builtin'")
+  gotenbergURL := flag.String("gotenberg-url", "http://gotenberg:3000", "Address of the gotenberg container")
+  gotenbergTimeout := flag.Duration("gotenberg-timeout", 60*time.Second, "Limit for a single request to gotenberg (0 disables it)")
+  gotenbergRetries := flag.Int("gotenberg-retries", 2, "How often a failed request to gotenberg is repeated")
+  archiveBackend := flag.String("archive-backend", archive.BackendNone, "Keeps every generated pdf in 'none', a local 'dir' or an 's3' bucket")
+  archiveDir := flag.String("archive-dir", "pdf-archive", "Directory of the archive with -archive-backend=dir")
+  archiveS3Endpoint := flag.String("archive-s3-endpoint", "http://minio:9000", "Address of the S3-compatible storage with -archive-backend=s3")
+  archiveS3Bucket := flag.String("archive-s3-bucket", "", "Bucket of the archive with -archive-backend=s3")
+  archiveS3Prefix := flag.String("archive-s3-prefix", "", "Put in front of every key in the bucket, e.g. 'checklists/'")
+  archiveS3Region := flag.String("archive-s3-region", "us-east-1", "Region of the bucket")
+  userHeader := flag.String("user-header", "", "Header with the name of the logged in user, set by an authenticating proxy (e.g. X-Forwarded-User)")
   flag.Parse()
   if *dbArg == "" {
     flag.Usage()
@@ -44,11 +70,68 @@ func main() {
 		log.Fatal(err)
 	}
 	// Server should hold the router and the db-handler
-  srv := server.NewServer(db)
+  srv := server.NewServer(db, server.Config{
+		PurgeAfterDays: *purgeAfter,
+		MaxAttachmentMB: *maxAttachment,
+	})
+	srv.PDF, err = pdf.NewRenderer(*pdfBackend, &pdf.Gotenberg{
+		URL: *gotenbergURL,
+		Timeout: *gotenbergTimeout,
+		Retries: *gotenbergRetries,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	// The keys of the bucket are read from the environment, so they don't show up in the process list
+	store, err := archive.NewStore(*archiveBackend, &archive.Dir{Path: *archiveDir}, &archive.S3{
+		Endpoint: *archiveS3Endpoint,
+		Bucket: *archiveS3Bucket,
+		Prefix: *archiveS3Prefix,
+		Region: *archiveS3Region,
+		AccessKey: os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	if store != nil {
+		srv.Archive = &archive.Archive{Store: store, UserHeader: *userHeader}
+	}
 	// create tables if not exist
 	if _, err := srv.DB.ExecContext(ctx, ddl); err != nil {
 		log.Fatal(err)
 	}
+	// the search index needs FTS5, fts5.go stops builds without '-tags sqlite_fts5'.
+	// A system sqlite (-tags libsqlite3) can still miss it.
+	if ok, err := database.HasFTS5(ctx, srv.DB); err != nil || !ok {
+		log.Fatalf("sqlite was built without FTS5 (%v)", err)
+	}
+	// bring existing databases up to date
+	sub, err := fs.Sub(migrations, "migrations")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := database.Migrate(ctx, srv.DB, sub); err != nil {
+		log.Fatal(err)
+	}
+	// entries created before the checklist state was stored per task
+	if err := checklist.ConvertYaml(ctx, srv.DB); err != nil {
+		log.Fatal(err)
+	}
+	// entries created before the progress was stored
+	if err := checklist.BackfillProgress(ctx, srv.DB); err != nil {
+		log.Fatal(err)
+	}
+	if err := checklist.BackfillAnswers(ctx, srv.DB); err != nil {
+		log.Fatal(err)
+	}
+	// 'backup' and 'restore' run on the migrated database instead of the server
+	if flag.NArg() > 0 {
+		if err := runCommand(ctx, srv.DB, flag.Args()); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
 	
 	// Call all registered handlers
 	// The handlers register theirself by init(), which is called by blank import
@@ -66,12 +149,20 @@ func main() {
 		Handler: srv.Router,
 		// TODO: test these!
 		ReadTimeout: 10 * time.Second,
+		// Printing and exporting pdfs extend it per request
 		WriteTimeout: 10 * time.Second,
 		IdleTimeout: 10 * time.Second,
 	}
 
+	// Open event streams of the checklists would keep Shutdown waiting
+	httpServer.RegisterOnShutdown(srv.Live.Close)
+
 	srv.LogRoutes()
 
+	ctxPurge, stopPurge := context.WithCancel(context.Background())
+	defer stopPurge()
+	go purgeTrash(ctxPurge, srv.DB, *purgeAfter)
+
 	go func() {
 		log.Printf("Starting tool on %s \n", addr)
 		// http.ErrServerClosed is returned form httpServer.Shutdown
@@ -97,3 +188,28 @@ func main() {
 	}
 	log.Println("server stopped")
 }
+
+// Removes entries from the trash, which have been deleted more than 'days' ago.
+// Runs once at startup and then every hour until ctx is done.
+func purgeTrash(ctx context.Context, db *sql.DB, days int) {
+	if days <= 0 {
+		return
+	}
+	q := database.New(db)
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for {
+		cutoff := time.Now().AddDate(0, 0, -days).Unix()
+		n, err := q.PurgeDeletedEntriesBefore(ctx, sql.NullInt64{Valid: true, Int64: cutoff})
+		if err != nil {
+			log.Printf("Couldn't purge the trash.\n Error: %v\n", err)
+		} else if n > 0 {
+			log.Printf("Purged %d entries from the trash.\n", n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,3 @@
+-- Entries are moved to the trash instead of being deleted right away.
+-- A NULL value means the entry is active.
+ALTER TABLE entries ADD COLUMN deleted_at INT;
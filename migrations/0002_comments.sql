@@ -0,0 +1,17 @@
+-- Comment thread below each checklist.
+-- 'body' is written in Markdown.
+CREATE TABLE IF NOT EXISTS comments (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  entry_id INTEGER NOT NULL,
+  author TEXT NOT NULL,
+  body TEXT NOT NULL,
+  date INT NOT NULL,
+  FOREIGN KEY (entry_id)
+    REFERENCES entries (id)
+);
+-- Foreign keys aren't enforced, so comments of purged entries are removed here.
+CREATE TRIGGER IF NOT EXISTS delete_comments_of_entry
+AFTER DELETE ON entries
+BEGIN
+  DELETE FROM comments WHERE entry_id = OLD.id;
+END;
@@ -0,0 +1,19 @@
+-- Photos and documents attached to an entry or to a single item of the checklist.
+-- 'task' is NULL when the file belongs to the entry itself.
+CREATE TABLE IF NOT EXISTS attachments (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  entry_id INTEGER NOT NULL,
+  task TEXT,
+  filename TEXT NOT NULL,
+  content_type TEXT NOT NULL,
+  size INT NOT NULL,
+  data BLOB NOT NULL,
+  date INT NOT NULL,
+  FOREIGN KEY (entry_id)
+    REFERENCES entries (id)
+);
+CREATE TRIGGER IF NOT EXISTS delete_attachments_of_entry
+AFTER DELETE ON entries
+BEGIN
+  DELETE FROM attachments WHERE entry_id = OLD.id;
+END;
@@ -0,0 +1,2 @@
+-- Workflow state of an entry: 'open', 'in_progress' or 'done'
+ALTER TABLE entries ADD COLUMN status TEXT NOT NULL DEFAULT 'open';
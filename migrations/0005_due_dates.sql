@@ -0,0 +1,5 @@
+-- Point in time (unix) until the checklist of an entry should be done
+ALTER TABLE entries ADD COLUMN due INT;
+-- Rules from the frontmatter to compute 'due' when an entry is created
+ALTER TABLE templates ADD COLUMN due_in TEXT;
+ALTER TABLE templates ADD COLUMN due_field TEXT;
@@ -0,0 +1,21 @@
+-- Team members, which can be assigned to entries
+CREATE TABLE IF NOT EXISTS people (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  name TEXT NOT NULL UNIQUE
+);
+ALTER TABLE entries ADD COLUMN assignee_id INT REFERENCES people (id);
+-- Log of changes to an entry, e.g. a new assignee
+CREATE TABLE IF NOT EXISTS entry_events (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  entry_id INTEGER NOT NULL,
+  kind TEXT NOT NULL,
+  message TEXT NOT NULL,
+  date INT NOT NULL,
+  FOREIGN KEY (entry_id)
+    REFERENCES entries (id)
+);
+CREATE TRIGGER IF NOT EXISTS delete_events_of_entry
+AFTER DELETE ON entries
+BEGIN
+  DELETE FROM entry_events WHERE entry_id = OLD.id;
+END;
@@ -0,0 +1,3 @@
+-- Key of 'custom_fields', whose value groups entries of different templates into a case,
+-- e.g. the ticket number shared by a device setup and an account setup.
+ALTER TABLE templates ADD COLUMN case_field TEXT;
@@ -0,0 +1,6 @@
+-- Counts of checked items, computed from 'yaml' whenever it changes.
+-- Existing entries are filled in on startup.
+ALTER TABLE entries ADD COLUMN progress_checked INT NOT NULL DEFAULT 0;
+ALTER TABLE entries ADD COLUMN progress_total INT NOT NULL DEFAULT 0;
+ALTER TABLE entries ADD COLUMN required_checked INT NOT NULL DEFAULT 0;
+ALTER TABLE entries ADD COLUMN required_total INT NOT NULL DEFAULT 0;
@@ -0,0 +1,50 @@
+-- Filled in text answers of the checklist, kept up to date by the application.
+-- The triggers below can't read them from the yaml.
+ALTER TABLE entries ADD COLUMN answers TEXT;
+-- Full-text index over the values in 'data', the template name and the answers.
+-- The rowid is the id of the entry. Needs sqlite built with FTS5 (-tags sqlite_fts5).
+CREATE VIRTUAL TABLE IF NOT EXISTS entries_search USING fts5(
+  data,
+  template,
+  answers,
+  tokenize = 'unicode61 remove_diacritics 2'
+);
+INSERT INTO entries_search (rowid, data, template, answers)
+SELECT e.id,
+  (SELECT group_concat(value, ' | ') FROM json_each(e.data)),
+  t.name,
+  e.answers
+FROM entries e
+JOIN templates t ON t.id = e.template_id;
+CREATE TRIGGER IF NOT EXISTS index_new_entry
+AFTER INSERT ON entries
+BEGIN
+  INSERT INTO entries_search (rowid, data, template, answers)
+  VALUES (
+    NEW.id,
+    (SELECT group_concat(value, ' | ') FROM json_each(NEW.data)),
+    (SELECT name FROM templates WHERE id = NEW.template_id),
+    NEW.answers
+  );
+END;
+CREATE TRIGGER IF NOT EXISTS index_updated_entry
+AFTER UPDATE OF data, answers, template_id ON entries
+BEGIN
+  UPDATE entries_search
+  SET data = (SELECT group_concat(value, ' | ') FROM json_each(NEW.data)),
+    template = (SELECT name FROM templates WHERE id = NEW.template_id),
+    answers = NEW.answers
+  WHERE rowid = NEW.id;
+END;
+CREATE TRIGGER IF NOT EXISTS unindex_deleted_entry
+AFTER DELETE ON entries
+BEGIN
+  DELETE FROM entries_search WHERE rowid = OLD.id;
+END;
+CREATE TRIGGER IF NOT EXISTS index_renamed_template
+AFTER UPDATE OF name ON templates
+BEGIN
+  UPDATE entries_search
+  SET template = NEW.name
+  WHERE rowid IN (SELECT id FROM entries WHERE template_id = NEW.id);
+END;
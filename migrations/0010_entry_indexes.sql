@@ -0,0 +1,3 @@
+-- Used by the entries-browser to filter and sort large lists
+CREATE INDEX IF NOT EXISTS entries_by_date ON entries (deleted_at, date);
+CREATE INDEX IF NOT EXISTS entries_by_template ON entries (template_id);
@@ -0,0 +1,7 @@
+-- Named filter, sort and column settings of the entries-browser.
+-- 'query' is the query string of /all, e.g. 'assignee=2&open=true&sort=due'.
+CREATE TABLE IF NOT EXISTS saved_views (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  name TEXT NOT NULL UNIQUE,
+  query TEXT NOT NULL
+);
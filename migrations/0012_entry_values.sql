@@ -0,0 +1,109 @@
+-- The values of the custom fields move from the json in 'entries.data'
+-- into their own table, so they can be filtered and sorted by in SQL.
+-- A field without a row has an empty value.
+CREATE TABLE IF NOT EXISTS entry_values (
+  entry_id INTEGER NOT NULL,
+  field_id INTEGER NOT NULL,
+  value TEXT NOT NULL,
+  PRIMARY KEY (entry_id, field_id),
+  FOREIGN KEY (entry_id)
+    REFERENCES entries (id),
+  FOREIGN KEY (field_id)
+    REFERENCES custom_fields (id)
+);
+CREATE INDEX IF NOT EXISTS entry_values_by_field ON entry_values (field_id, value);
+INSERT INTO entry_values (entry_id, field_id, value)
+SELECT e.id, cf.id, CAST(j.value AS TEXT)
+FROM entries e
+JOIN json_each(e.data) j
+JOIN custom_fields cf ON cf.template_id = e.template_id AND cf.key = j.key;
+-- Order of the fields in the frontmatter.
+-- Updating a template keeps the ids of its fields, so the values stay attached.
+ALTER TABLE custom_fields ADD COLUMN position INTEGER NOT NULL DEFAULT 0;
+UPDATE custom_fields
+SET position = (
+  SELECT COUNT(*) FROM custom_fields cf
+  WHERE cf.template_id = custom_fields.template_id AND cf.id < custom_fields.id
+);
+CREATE TRIGGER IF NOT EXISTS delete_entry_values
+AFTER DELETE ON entries
+BEGIN
+  DELETE FROM entry_values WHERE entry_id = OLD.id;
+END;
+CREATE TRIGGER IF NOT EXISTS delete_field_values
+AFTER DELETE ON custom_fields
+BEGIN
+  DELETE FROM entry_values WHERE field_id = OLD.id;
+END;
+-- The search index reads the values from the new table, see 0009_search.sql.
+-- The subqueries keep the order of the fields, sqlc can't parse group_concat(… ORDER BY …).
+DROP TRIGGER IF EXISTS index_new_entry;
+DROP TRIGGER IF EXISTS index_updated_entry;
+CREATE TRIGGER IF NOT EXISTS index_new_entry
+AFTER INSERT ON entries
+BEGIN
+  INSERT INTO entries_search (rowid, data, template, answers)
+  VALUES (
+    NEW.id,
+    '',
+    (SELECT name FROM templates WHERE id = NEW.template_id),
+    NEW.answers
+  );
+END;
+CREATE TRIGGER IF NOT EXISTS index_updated_entry
+AFTER UPDATE OF answers, template_id ON entries
+BEGIN
+  UPDATE entries_search
+  SET template = (SELECT name FROM templates WHERE id = NEW.template_id),
+    answers = NEW.answers
+  WHERE rowid = NEW.id;
+END;
+CREATE TRIGGER IF NOT EXISTS index_new_value
+AFTER INSERT ON entry_values
+BEGIN
+  UPDATE entries_search
+  SET data = (
+    SELECT group_concat(value, ' | ')
+    FROM (
+      SELECT ev.value
+      FROM entry_values ev
+      JOIN custom_fields cf ON cf.id = ev.field_id
+      WHERE ev.entry_id = NEW.entry_id
+      ORDER BY cf.position, cf.id
+    )
+  )
+  WHERE rowid = NEW.entry_id;
+END;
+CREATE TRIGGER IF NOT EXISTS index_updated_value
+AFTER UPDATE OF value ON entry_values
+BEGIN
+  UPDATE entries_search
+  SET data = (
+    SELECT group_concat(value, ' | ')
+    FROM (
+      SELECT ev.value
+      FROM entry_values ev
+      JOIN custom_fields cf ON cf.id = ev.field_id
+      WHERE ev.entry_id = NEW.entry_id
+      ORDER BY cf.position, cf.id
+    )
+  )
+  WHERE rowid = NEW.entry_id;
+END;
+CREATE TRIGGER IF NOT EXISTS index_deleted_value
+AFTER DELETE ON entry_values
+BEGIN
+  UPDATE entries_search
+  SET data = (
+    SELECT group_concat(value, ' | ')
+    FROM (
+      SELECT ev.value
+      FROM entry_values ev
+      JOIN custom_fields cf ON cf.id = ev.field_id
+      WHERE ev.entry_id = OLD.entry_id
+      ORDER BY cf.position, cf.id
+    )
+  )
+  WHERE rowid = OLD.entry_id;
+END;
+ALTER TABLE entries DROP COLUMN data;
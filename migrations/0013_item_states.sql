@@ -0,0 +1,18 @@
+-- The structure of a checklist is only stored once in 'templates.empty_yaml'.
+-- What is checked or filled in per entry lives here, one row per task.
+-- NULL means the default of the template.
+-- 'entries.yaml' is converted into rows at startup (see checklist.ConvertYaml) and cleared afterwards.
+CREATE TABLE IF NOT EXISTS item_states (
+  entry_id INTEGER NOT NULL,
+  task TEXT NOT NULL,
+  checked BOOLEAN,
+  text TEXT,
+  PRIMARY KEY (entry_id, task),
+  FOREIGN KEY (entry_id)
+    REFERENCES entries (id)
+);
+CREATE TRIGGER IF NOT EXISTS delete_item_states
+AFTER DELETE ON entries
+BEGIN
+  DELETE FROM item_states WHERE entry_id = OLD.id;
+END;
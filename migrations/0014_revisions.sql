@@ -0,0 +1,8 @@
+-- Every change to the checklist of an entry increases 'entries.revision'.
+-- 'checked_revision' and 'text_revision' are the revision of the entry,
+-- when the checkbox or the text field of an item was changed last.
+-- A write sends the revision the client has seen, so a change made in the meantime
+-- by someone else doesn't get overwritten unnoticed.
+ALTER TABLE entries ADD COLUMN revision INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE item_states ADD COLUMN checked_revision INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE item_states ADD COLUMN text_revision INTEGER NOT NULL DEFAULT 0;
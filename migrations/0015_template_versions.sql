@@ -0,0 +1,17 @@
+-- Earlier checklists of a template, kept for entries which were not updated with it,
+-- e.g. finished entries when an update was applied to open entries only.
+CREATE TABLE IF NOT EXISTS template_versions (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  template_id INTEGER NOT NULL,
+  empty_yaml TEXT NOT NULL,
+  date INTEGER NOT NULL,
+  FOREIGN KEY (template_id)
+    REFERENCES templates (id)
+);
+-- NULL follows the current checklist of the template
+ALTER TABLE entries ADD COLUMN template_version_id INTEGER REFERENCES template_versions (id);
+CREATE TRIGGER IF NOT EXISTS delete_template_versions
+AFTER DELETE ON templates
+BEGIN
+  DELETE FROM template_versions WHERE template_id = OLD.id;
+END;
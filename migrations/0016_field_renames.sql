@@ -0,0 +1,4 @@
+-- Fields removed from the frontmatter can keep the values of the existing entries.
+-- They are hidden until a later version of the template brings their key back.
+-- NULL is a field of the current version.
+ALTER TABLE custom_fields ADD COLUMN removed_at INTEGER;
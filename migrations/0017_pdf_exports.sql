@@ -0,0 +1,21 @@
+-- Every generated pdf is kept in the archive storage (-archive-backend).
+-- A row records who got which pdf, built from which revision of the entry.
+-- Comments and attachments don't change the revision, 'content_sha256' is a hash
+-- of everything printed, so exports with the same content can be told apart from changed ones.
+-- 'storage_key' names the file inside the storage.
+-- The rows stay when their entry is purged from the trash or overwritten by a restore,
+-- that's why 'entry_id' has no foreign key and 'entry_path' keeps the entry they belong to.
+CREATE TABLE IF NOT EXISTS pdf_exports (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  entry_id INTEGER NOT NULL,
+  entry_path TEXT NOT NULL,
+  revision INTEGER NOT NULL,
+  content_sha256 TEXT NOT NULL,
+  filename TEXT NOT NULL,
+  storage_key TEXT NOT NULL,
+  sha256 TEXT NOT NULL,
+  size INT NOT NULL,
+  requested_by TEXT NOT NULL,
+  date INT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS pdf_exports_entry_path ON pdf_exports (entry_path);
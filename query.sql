@@ -1,11 +1,33 @@
 -- name: InsertNewChecklistTemplate :one
-INSERT INTO templates (name, empty_yaml, file)
-VALUES (?, ?, ?)
+INSERT INTO templates (name, empty_yaml, file, due_in, due_field, case_field)
+VALUES (?, ?, ?, ?, ?, ?)
 RETURNING id;
 
 -- name: InsertCustomField :exec
-INSERT INTO custom_fields (template_id, key, desc)
-VALUES (?, ?, ?);
+INSERT INTO custom_fields (template_id, key, desc, position)
+VALUES (?, ?, ?, ?);
+
+-- name: GetCustomFieldsByTemplateID :many
+SELECT id, template_id, key, desc, position, removed_at
+FROM custom_fields
+WHERE template_id = ?
+ORDER BY position, id;
+
+-- name: UpdateCustomFieldByID :exec
+UPDATE custom_fields
+SET key = ?, desc = ?, position = ?, removed_at = NULL
+WHERE id = ?;
+
+-- name: RemoveCustomFieldByID :exec
+-- Hides the field, but keeps its values
+UPDATE custom_fields
+SET removed_at = ?
+WHERE id = ? AND removed_at IS NULL;
+
+-- name: DeleteCustomFieldByID :exec
+-- The values of the field are deleted by a trigger
+DELETE FROM custom_fields
+WHERE id = ?;
 
 -- name: InsertTabDescSchema :exec
 INSERT INTO tab_desc_schema (template_id, value)
@@ -16,30 +38,31 @@ INSERT INTO pdf_name_schema (template_id, value)
 VALUES (?, ?);
 
 -- name: GetTemplateByName :one
-SELECT id, name, empty_yaml, file
+SELECT id, name, empty_yaml, file, due_in, due_field, case_field
 FROM templates
 WHERE name = ?;
 
 -- name: GetTemplateById :one
-SELECT id, name, empty_yaml, file
+SELECT id, name, empty_yaml, file, due_in, due_field, case_field
 FROM templates
 WHERE id = ?;
 
 -- name: GetAllTemplates :many
-SELECT id, name, empty_yaml, file
+SELECT id, name, empty_yaml, file, due_in, due_field, case_field
 FROM templates;
 
 -- name: GetTemplateIdByName :one
 SELECT id FROM templates where name = ?;
 
 -- name: UpdateTemplateById :exec
-UPDATE templates SET empty_yaml = ?, file = ? WHERE id = ?;
+UPDATE templates SET empty_yaml = ?, file = ?, due_in = ?, due_field = ?, case_field = ? WHERE id = ?;
 
 -- name: GetCustomFieldsByTemplateName :many
-SELECT cf.id, cf.template_id, cf.key, cf.desc
+SELECT cf.id, cf.template_id, cf.key, cf.desc, cf.position, cf.removed_at
 FROM custom_fields cf
 JOIN templates t ON cf.template_id = t.id
-WHERE t.name = ?;
+WHERE t.name = ? AND cf.removed_at IS NULL
+ORDER BY cf.position, cf.id;
 
 -- name: GetTabDescriptionsByTemplateID :many
 SELECT id, template_id, value
@@ -52,64 +75,122 @@ FROM pdf_name_schema
 WHERE template_id = ?;
 
 -- name: InsertEntry :exec
-INSERT INTO entries (template_id, data, path, yaml, date)
+INSERT INTO entries (template_id, path, date, due, assignee_id)
 VALUES (?, ?, ?, ?, ?);
 
 -- name: GetEntryByPath :one
-SELECT id, template_id, data, path, yaml, date
+SELECT id, template_id, path, yaml, date, deleted_at, status, due, assignee_id, progress_checked, progress_total, required_checked, required_total, answers, revision, template_version_id
 FROM entries
-WHERE path = ?;
+WHERE path = ? AND deleted_at IS NULL;
+
+-- name: GetDeletedEntryByPath :one
+SELECT id, template_id, path, yaml, date, deleted_at, status, due, assignee_id, progress_checked, progress_total, required_checked, required_total, answers, revision, template_version_id
+FROM entries
+WHERE path = ? AND deleted_at IS NOT NULL;
 
 -- name: GetAllEntries :many
-SELECT id, template_id, data, path, yaml, date
-FROM entries;
+SELECT id, template_id, path, yaml, date, deleted_at, status, due, assignee_id, progress_checked, progress_total, required_checked, required_total, answers, revision, template_version_id
+FROM entries
+WHERE deleted_at IS NULL;
 
 -- name: GetEntriesByTemplateName :many
 SELECT
     entries.id,
     entries.template_id,
-    entries.data,
     entries.path,
     entries.yaml,
-    entries.date
+    entries.date,
+    entries.deleted_at,
+    entries.status,
+    entries.due,
+    entries.assignee_id,
+    entries.progress_checked,
+    entries.progress_total,
+    entries.required_checked,
+    entries.required_total,
+    entries.answers,
+    entries.revision,
+    entries.template_version_id
 FROM entries
 JOIN templates ON entries.template_id = templates.id
-WHERE templates.name = ?
+WHERE templates.name = ? AND entries.deleted_at IS NULL
 ORDER BY entries.date DESC;
 
+-- name: GetEntriesByTemplateIDWithDeleted :many
+-- Also returns the entries from the trash,
+-- so they stay in sync with their template when restored.
+SELECT id, template_id, path, yaml, date, deleted_at, status, due, assignee_id, progress_checked, progress_total, required_checked, required_total, answers, revision, template_version_id
+FROM entries
+WHERE template_id = ?;
+
 -- name: GetAllEntriesPlusTemplateName :many
 SELECT
     entries.id,
-    entries.data,
     entries.path,
     entries.yaml,
     entries.date,
+    entries.status,
+    entries.due,
+    entries.assignee_id,
+    entries.progress_checked,
+    entries.progress_total,
+    entries.required_checked,
+    entries.required_total,
+    entries.answers,
     templates.name AS template_name
 FROM entries
 JOIN templates ON entries.template_id = templates.id
+WHERE entries.deleted_at IS NULL
 ORDER BY entries.date DESC;
 
+-- name: GetDeletedEntriesPlusTemplateName :many
+SELECT
+    entries.id,
+    entries.path,
+    entries.yaml,
+    entries.date,
+    entries.deleted_at,
+    entries.status,
+    entries.due,
+    entries.assignee_id,
+    entries.progress_checked,
+    entries.progress_total,
+    entries.required_checked,
+    entries.required_total,
+    entries.answers,
+    templates.name AS template_name
+FROM entries
+JOIN templates ON entries.template_id = templates.id
+WHERE entries.deleted_at IS NOT NULL
+ORDER BY entries.deleted_at DESC;
+
 -- name: GetTemplateNameById :one
 SELECT name FROM templates WHERE id = ?;
 
--- name: UpdateYamlByPath :exec
+-- name: ClearYamlByID :exec
 UPDATE entries
-SET yaml = ?
-WHERE path = ?;
+SET yaml = NULL
+WHERE id = ?;
 
--- name: UpdateDataById :exec
+-- name: SoftDeleteEntryByPath :exec
+-- Moves the entry into the trash
 UPDATE entries
-SET data = ?
-WHERE id = ?;
+SET deleted_at = ?
+WHERE path = ? AND deleted_at IS NULL;
 
--- name: UpdateYamlById :exec
+-- name: RestoreEntryByPath :exec
 UPDATE entries
-SET yaml = ?
-WHERE id = ?;
+SET deleted_at = NULL
+WHERE path = ?;
 
--- name: DeleteEntryByPath :exec
+-- name: PurgeEntryByPath :exec
+-- Only entries in the trash can be purged
 DELETE FROM entries
-WHERE path = ?;
+WHERE path = ? AND deleted_at IS NOT NULL;
+
+-- name: PurgeDeletedEntriesBefore :execrows
+DELETE FROM entries
+WHERE deleted_at IS NOT NULL AND deleted_at < ?;
 
 -- name: DeleteCustomFieldsByTemplateID :exec
 DELETE FROM custom_fields
@@ -134,4 +215,407 @@ WHERE id = ?;
 -- name: DoesPathExist :one
 SELECT path
 FROM entries
+WHERE path = ? AND deleted_at IS NULL;
+
+-- name: InsertComment :exec
+INSERT INTO comments (entry_id, author, body, date)
+VALUES (?, ?, ?, ?);
+
+-- name: GetCommentsByEntryID :many
+SELECT id, entry_id, author, body, date
+FROM comments
+WHERE entry_id = ?
+ORDER BY date ASC, id ASC;
+
+-- name: InsertAttachment :exec
+INSERT INTO attachments (entry_id, task, filename, content_type, size, data, date)
+VALUES (?, ?, ?, ?, ?, ?, ?);
+
+-- name: GetAttachmentsByEntryID :many
+-- Leaves out the file itself, because it is just needed for the listing
+SELECT id, entry_id, task, filename, content_type, size, date
+FROM attachments
+WHERE entry_id = ?
+ORDER BY date ASC, id ASC;
+
+-- name: GetAttachmentsWithDataByEntryID :many
+SELECT id, entry_id, task, filename, content_type, size, data, date
+FROM attachments
+WHERE entry_id = ?
+ORDER BY date ASC, id ASC;
+
+-- name: GetAttachmentByID :one
+SELECT id, entry_id, task, filename, content_type, size, data, date
+FROM attachments
+WHERE id = ?;
+
+-- name: DeleteAttachmentByIDAndEntryID :execrows
+-- The entry is part of the condition, so attachments can only be deleted from the entry they belong to
+DELETE FROM attachments
+WHERE id = ? AND entry_id = ?;
+
+-- name: UpdateDueByPath :exec
+UPDATE entries
+SET due = ?
+WHERE path = ? AND deleted_at IS NULL;
+
+-- name: UpdateStatusByPath :exec
+UPDATE entries
+SET status = ?
+WHERE path = ? AND deleted_at IS NULL;
+
+-- name: InsertPerson :exec
+INSERT INTO people (name)
+VALUES (?);
+
+-- name: GetAllPeople :many
+SELECT id, name
+FROM people
+ORDER BY name;
+
+-- name: GetPersonByID :one
+SELECT id, name
+FROM people
+WHERE id = ?;
+
+-- name: DeletePersonByID :exec
+DELETE FROM people
+WHERE id = ?;
+
+-- name: UpdateAssigneeByPath :exec
+UPDATE entries
+SET assignee_id = ?
+WHERE path = ? AND deleted_at IS NULL;
+
+-- name: ClearAssigneeByPersonID :exec
+UPDATE entries
+SET assignee_id = NULL
+WHERE assignee_id = ?;
+
+-- name: InsertEntryEvent :exec
+INSERT INTO entry_events (entry_id, kind, message, date)
+VALUES (?, ?, ?, ?);
+
+-- name: InsertEntryEventsByAssignee :exec
+INSERT INTO entry_events (entry_id, kind, message, date)
+SELECT id, 'assignee', ?, ?
+FROM entries
+WHERE assignee_id = ?;
+
+-- name: GetEntryEventsByEntryID :many
+SELECT id, entry_id, kind, message, date
+FROM entry_events
+WHERE entry_id = ?
+ORDER BY date, id;
+
+-- name: GetEntriesByCaseKey :many
+SELECT
+    entries.id,
+    entries.template_id,
+    entries.path,
+    entries.yaml,
+    entries.date,
+    entries.status,
+    entries.progress_checked,
+    entries.progress_total,
+    entries.required_checked,
+    entries.required_total,
+    entries.answers,
+    templates.name AS template_name
+FROM entries
+JOIN templates ON entries.template_id = templates.id
+WHERE templates.case_field IS NOT NULL
+  AND EXISTS (
+    SELECT 1 FROM entry_values
+    JOIN custom_fields ON custom_fields.id = entry_values.field_id
+    WHERE entry_values.entry_id = entries.id
+      AND custom_fields.key = templates.case_field
+      AND entry_values.value = sqlc.arg(case_key))
+  AND entries.deleted_at IS NULL
+ORDER BY entries.date;
+
+-- name: UpdateProgressByID :exec
+UPDATE entries
+SET progress_checked = ?, progress_total = ?, required_checked = ?, required_total = ?
+WHERE id = ?;
+
+-- name: GetEntriesWithoutProgress :many
+SELECT id, template_id
+FROM entries
+WHERE progress_total = 0;
+
+-- name: UpdateAnswersByID :exec
+UPDATE entries
+SET answers = ?
+WHERE id = ?;
+
+-- name: GetEntriesWithoutAnswers :many
+SELECT id, template_id
+FROM entries
+WHERE answers IS NULL;
+
+-- Paginated list of the entries-browser, see handlers/browser.go.
+-- sqlc can't build dynamic ORDER BY clauses, so the column is chosen by 'sort'.
+-- sqlc only replaces the arguments of ORDER BY in a subquery, they are joined as the one row of 'params'.
+-- The casts give the arguments their types, the LIKE is in parentheses, otherwise sqlc fails to parse its ESCAPE.
+-- name: BrowseEntries :many
+SELECT
+    entries.id,
+    entries.path,
+    entries.yaml,
+    entries.date,
+    entries.status,
+    entries.due,
+    entries.assignee_id,
+    entries.progress_checked,
+    entries.progress_total,
+    entries.required_checked,
+    entries.required_total,
+    entries.answers,
+    templates.name AS template_name
+FROM entries
+JOIN templates ON entries.template_id = templates.id
+JOIN (
+  SELECT
+    CAST(sqlc.arg(sort) AS TEXT) AS sort,
+    CAST(sqlc.arg(field) AS TEXT) AS field,
+    CAST(sqlc.arg(descending) AS INTEGER) AS descending
+) AS params
+WHERE entries.deleted_at IS NULL
+  AND (CAST(sqlc.arg(template) AS TEXT) = '' OR templates.name = sqlc.arg(template))
+  AND (CAST(sqlc.arg(date_from) AS INTEGER) = 0 OR entries.date >= sqlc.arg(date_from))
+  AND (CAST(sqlc.arg(date_to) AS INTEGER) = 0 OR entries.date <= sqlc.arg(date_to))
+  AND (CAST(sqlc.arg(open) AS INTEGER) = 0 OR entries.status != 'done')
+  AND (CAST(sqlc.arg(assignee) AS TEXT) = ''
+    OR (sqlc.arg(assignee) = 'none' AND entries.assignee_id IS NULL)
+    OR CAST(entries.assignee_id AS TEXT) = sqlc.arg(assignee))
+  AND (CAST(sqlc.arg(due_filter) AS TEXT) = ''
+    OR (sqlc.arg(due_filter) = 'none' AND entries.due IS NULL)
+    OR (sqlc.arg(due_filter) = 'overdue' AND entries.status != 'done' AND entries.due < CAST(sqlc.arg(now) AS INTEGER))
+    OR (sqlc.arg(due_filter) = 'soon' AND entries.status != 'done' AND entries.due < CAST(sqlc.arg(soon) AS INTEGER)))
+  AND (CAST(sqlc.arg(filter_field) AS TEXT) = '' OR CAST(sqlc.arg(filter_value) AS TEXT) = '' OR EXISTS (
+    SELECT 1 FROM entry_values
+    JOIN custom_fields ON custom_fields.id = entry_values.field_id
+    WHERE entry_values.entry_id = entries.id
+      AND custom_fields.key = sqlc.arg(filter_field)
+      AND (entry_values.value LIKE '%' || sqlc.arg(filter_value) || '%' ESCAPE '\')))
+  AND (CAST(sqlc.arg(status) AS TEXT) = '' OR entries.status = sqlc.arg(status))
+ORDER BY
+  -- entries without due date come last in both directions
+  CASE WHEN params.sort = 'due' THEN entries.due IS NULL END,
+  CASE WHEN params.descending = 0 THEN CASE params.sort
+      WHEN 'template' THEN templates.name
+      WHEN 'field' THEN (SELECT entry_values.value FROM entry_values
+        JOIN custom_fields ON custom_fields.id = entry_values.field_id
+        WHERE entry_values.entry_id = entries.id AND custom_fields.key = params.field)
+      WHEN 'due' THEN entries.due
+      WHEN 'progress' THEN entries.progress_checked * 1.0 / MAX(entries.progress_total, 1)
+      ELSE entries.date
+    END END ASC,
+  CASE WHEN params.descending = 1 THEN CASE params.sort
+      WHEN 'template' THEN templates.name
+      WHEN 'field' THEN (SELECT entry_values.value FROM entry_values
+        JOIN custom_fields ON custom_fields.id = entry_values.field_id
+        WHERE entry_values.entry_id = entries.id AND custom_fields.key = params.field)
+      WHEN 'due' THEN entries.due
+      WHEN 'progress' THEN entries.progress_checked * 1.0 / MAX(entries.progress_total, 1)
+      ELSE entries.date
+    END END DESC,
+  entries.id DESC
+LIMIT sqlc.arg(limit) OFFSET sqlc.arg(offset);
+
+-- Has to use the same WHERE as BrowseEntries
+-- name: CountBrowseEntries :one
+SELECT COUNT(*)
+FROM entries
+JOIN templates ON entries.template_id = templates.id
+WHERE entries.deleted_at IS NULL
+  AND (CAST(sqlc.arg(template) AS TEXT) = '' OR templates.name = sqlc.arg(template))
+  AND (CAST(sqlc.arg(date_from) AS INTEGER) = 0 OR entries.date >= sqlc.arg(date_from))
+  AND (CAST(sqlc.arg(date_to) AS INTEGER) = 0 OR entries.date <= sqlc.arg(date_to))
+  AND (CAST(sqlc.arg(open) AS INTEGER) = 0 OR entries.status != 'done')
+  AND (CAST(sqlc.arg(assignee) AS TEXT) = ''
+    OR (sqlc.arg(assignee) = 'none' AND entries.assignee_id IS NULL)
+    OR CAST(entries.assignee_id AS TEXT) = sqlc.arg(assignee))
+  AND (CAST(sqlc.arg(due_filter) AS TEXT) = ''
+    OR (sqlc.arg(due_filter) = 'none' AND entries.due IS NULL)
+    OR (sqlc.arg(due_filter) = 'overdue' AND entries.status != 'done' AND entries.due < CAST(sqlc.arg(now) AS INTEGER))
+    OR (sqlc.arg(due_filter) = 'soon' AND entries.status != 'done' AND entries.due < CAST(sqlc.arg(soon) AS INTEGER)))
+  AND (CAST(sqlc.arg(filter_field) AS TEXT) = '' OR CAST(sqlc.arg(filter_value) AS TEXT) = '' OR EXISTS (
+    SELECT 1 FROM entry_values
+    JOIN custom_fields ON custom_fields.id = entry_values.field_id
+    WHERE entry_values.entry_id = entries.id
+      AND custom_fields.key = sqlc.arg(filter_field)
+      AND (entry_values.value LIKE '%' || sqlc.arg(filter_value) || '%' ESCAPE '\')))
+  AND (CAST(sqlc.arg(status) AS TEXT) = '' OR entries.status = sqlc.arg(status));
+
+-- name: GetAllCustomFields :many
+SELECT id, template_id, key, desc, position, removed_at
+FROM custom_fields
+WHERE removed_at IS NULL
+ORDER BY template_id, position, id;
+
+-- name: SetEntryValue :exec
+INSERT INTO entry_values (entry_id, field_id, value)
+VALUES (?, ?, ?)
+ON CONFLICT (entry_id, field_id) DO UPDATE SET value = excluded.value;
+
+-- Every field of the template is returned,
+-- fields without a stored value have an empty one.
+-- name: GetEntryValuesByEntryIDs :many
+SELECT
+    entries.id AS entry_id,
+    custom_fields.id AS field_id,
+    custom_fields.key,
+    custom_fields.desc,
+    COALESCE(entry_values.value, '') AS value
+FROM entries
+JOIN custom_fields ON custom_fields.template_id = entries.template_id
+  AND custom_fields.removed_at IS NULL
+LEFT JOIN entry_values ON entry_values.entry_id = entries.id
+  AND entry_values.field_id = custom_fields.id
+WHERE entries.id IN (sqlc.slice(ids))
+ORDER BY entries.id, custom_fields.position, custom_fields.id;
+
+-- Saving a view under an existing name replaces it
+-- name: SaveView :exec
+INSERT INTO saved_views (name, query)
+VALUES (?, ?)
+ON CONFLICT (name) DO UPDATE SET query = excluded.query;
+
+-- name: GetAllSavedViews :many
+SELECT id, name, query
+FROM saved_views
+ORDER BY name;
+
+-- name: DeleteSavedViewByID :exec
+DELETE FROM saved_views WHERE id = ?;
+
+-- Entries created before 'item_states', see migrations/0013_item_states.sql
+-- name: GetEntriesWithYaml :many
+SELECT id, yaml
+FROM entries
+WHERE yaml IS NOT NULL;
+
+-- name: GetItemStatesByEntryID :many
+SELECT entry_id, task, checked, text, checked_revision, text_revision
+FROM item_states
+WHERE entry_id = ?;
+
+-- name: SetItemChecked :exec
+INSERT INTO item_states (entry_id, task, checked, checked_revision)
+VALUES (?, ?, ?, ?)
+ON CONFLICT (entry_id, task) DO UPDATE SET checked = excluded.checked, checked_revision = excluded.checked_revision;
+
+-- name: SetItemText :exec
+INSERT INTO item_states (entry_id, task, text, text_revision)
+VALUES (?, ?, ?, ?)
+ON CONFLICT (entry_id, task) DO UPDATE SET text = excluded.text, text_revision = excluded.text_revision;
+
+-- A task, which already has a state, keeps it
+-- name: RenameItemState :exec
+UPDATE OR IGNORE item_states
+SET task = sqlc.arg(new_task)
+WHERE entry_id = sqlc.arg(entry_id) AND task = sqlc.arg(old_task);
+
+-- name: RenameAttachmentTask :exec
+UPDATE attachments
+SET task = sqlc.arg(new_task)
+WHERE entry_id = sqlc.arg(entry_id) AND task = sqlc.arg(old_task);
+
+-- Checklist of an entry: the version it was kept at or the current one of its template
+-- name: GetChecklistYamlByEntryID :one
+SELECT COALESCE(v.empty_yaml, t.empty_yaml, '') AS empty_yaml
+FROM entries e
+JOIN templates t ON t.id = e.template_id
+LEFT JOIN template_versions v ON v.id = e.template_version_id
+WHERE e.id = ?;
+
+-- name: InsertTemplateVersion :one
+INSERT INTO template_versions (template_id, empty_yaml, date)
+VALUES (?, ?, ?)
+RETURNING id;
+
+-- name: SetTemplateVersionByID :exec
+UPDATE entries SET template_version_id = ? WHERE id = ?;
+
+-- name: NextRevisionByID :one
+UPDATE entries SET revision = revision + 1
+WHERE id = ?
+RETURNING revision;
+
+-- name: GetTemplateVersionsByTemplateID :many
+SELECT id, template_id, empty_yaml, date
+FROM template_versions
+WHERE template_id = ?
+ORDER BY id;
+
+-- Values of all fields of an entry, also of removed ones
+-- name: GetAllEntryValuesByEntryID :many
+SELECT custom_fields.key, entry_values.value
+FROM entry_values
+JOIN custom_fields ON custom_fields.id = entry_values.field_id
+WHERE entry_values.entry_id = ?
+ORDER BY custom_fields.position, custom_fields.id;
+
+-- name: RestoreCustomField :one
+INSERT INTO custom_fields (template_id, key, desc, position, removed_at)
+VALUES (?, ?, ?, ?, ?)
+RETURNING id;
+
+-- Inserts an entry from a backup with all of its columns
+-- name: RestoreEntry :one
+INSERT INTO entries (
+  template_id, path, date, deleted_at, status, due, assignee_id,
+  progress_checked, progress_total, required_checked, required_total,
+  answers, revision, template_version_id
+)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+RETURNING id;
+
+-- name: RestoreItemState :exec
+INSERT INTO item_states (entry_id, task, checked, text, checked_revision, text_revision)
+VALUES (?, ?, ?, ?, ?, ?);
+
+-- name: CountEntriesByPath :one
+-- Also counts the entries in the trash
+SELECT COUNT(*)
+FROM entries
+WHERE path = ?;
+
+-- name: DeleteEntryByPath :exec
+-- Also deletes an entry in the trash, e.g. when a backup overwrites it
+DELETE FROM entries
+WHERE path = ?;
+
+-- name: InsertPdfExport :one
+INSERT INTO pdf_exports (entry_id, entry_path, revision, content_sha256, filename, storage_key, sha256, size, requested_by, date)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+RETURNING id, entry_id, entry_path, revision, content_sha256, filename, storage_key, sha256, size, requested_by, date;
+
+-- name: GetPdfExportsByEntryPath :many
+SELECT id, entry_id, entry_path, revision, content_sha256, filename, storage_key, sha256, size, requested_by, date
+FROM pdf_exports
+WHERE entry_path = ?
+ORDER BY date DESC, id DESC;
+
+-- name: GetPdfExportByID :one
+SELECT id, entry_id, entry_path, revision, content_sha256, filename, storage_key, sha256, size, requested_by, date
+FROM pdf_exports
+WHERE id = ?;
+
+-- name: GetAllPdfExports :many
+SELECT id, entry_id, entry_path, revision, content_sha256, filename, storage_key, sha256, size, requested_by, date
+FROM pdf_exports
+ORDER BY id;
+
+-- name: CountPdfExportsByStorageKey :one
+SELECT COUNT(*)
+FROM pdf_exports
+WHERE storage_key = ?;
+
+-- name: GetEntryIDByPath :one
+-- Also finds the entries in the trash
+SELECT id
+FROM entries
 WHERE path = ?;